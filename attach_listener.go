@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"jvm/native"
+	"jvm/rtda"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 和HotSpot一样 本地工具通过临时目录下的.java_pid<pid>连接虚拟机
+func attachSocketPath(pid int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf(".java_pid%d", pid))
+}
+
+// 虚拟机内部的Attach Listener线程 一次处理一个连接
+// 请求是一行命令 回复的第一行是状态码 0表示成功 之后是命令的输出或者错误信息
+func (self *JVM) startAttachListener() {
+	listener, err := listenAttach(attachSocketPath(os.Getpid()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to start the attach listener: %v\n", err)
+		return
+	}
+
+	thread := rtda.NewThread()
+	thread.SetJThread(self.newJThread("Attach Listener", self.systemGroup, 9, true)) // Thread.MAX_PRIORITY - 1
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if err := checkAttachPeer(conn); err != nil {
+				conn.SetWriteDeadline(time.Now().Add(attachTimeout))
+				fmt.Fprintf(conn, "1\n%v\n", err)
+				conn.Close()
+				continue
+			}
+			self.serveAttach(thread, conn)
+		}
+	}()
+}
+
+// dumpheap可以写任意文件 socket只允许同一个用户连接
+// 先在只有自己能进的临时目录里创建 改成0600以后再移到正式的位置 这样别人不会在chmod之前连上
+// 被SIGKILL或者崩溃的虚拟机来不及删除socket文件 启动时顺便删掉进程已经不在的
+func listenAttach(path string) (*net.UnixListener, error) {
+	removeStaleAttachSockets()
+	os.Remove(path) // 同一个pid上次异常退出留下的
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".java_attach") // 权限是0700
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(dir)
+	tmpPath := filepath.Join(dir, filepath.Base(path))
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(tmpPath, 0600); err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	// Close只会删除绑定时的名字 正式的名字要自己删
+	listener.SetUnlinkOnClose(false)
+	native.AtExit(func() {
+		listener.Close()
+		os.Remove(path)
+	})
+	return listener, nil
+}
+
+func removeStaleAttachSockets() {
+	matches, _ := filepath.Glob(filepath.Join(os.TempDir(), ".java_pid*"))
+	for _, path := range matches {
+		pid, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), ".java_pid"))
+		if err == nil && pid != os.Getpid() && !processExists(pid) {
+			os.Remove(path) // 别的用户的文件删不掉 忽略错误
+		}
+	}
+}
+
+// 连接是一个一个处理的 客户端不发命令或者不读回复时 不能一直占着Attach Listener
+// 读命令和写回复分别有时限 执行命令本身不限时 转储很大的堆可能要很久
+var attachTimeout = 10 * time.Second
+
+func (self *JVM) serveAttach(thread *rtda.Thread, conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(attachTimeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+	command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	reply := new(bytes.Buffer)
+	switch command {
+	case "threaddump":
+		reply.WriteString("0\n")
+		reply.Write(threadDump(thread))
+	case "inspectheap":
+		thread.Acquire()
+		histogram := classHistogram(self.classLoader)
+		thread.Release()
+		reply.WriteString("0\n")
+		reply.Write(histogram)
+	case "dumpheap":
+		thread.Acquire()
+		msg, err := dumpHeap(self.classLoader, arg)
+		thread.Release()
+		if err != nil {
+			fmt.Fprintf(reply, "1\n%v\n", err)
+		} else {
+			fmt.Fprintf(reply, "0\nDumping heap to %s ...\n%s", arg, msg)
+		}
+	default:
+		fmt.Fprintf(reply, "1\nunknown command: %s\n", command)
+	}
+	conn.SetWriteDeadline(time.Now().Add(attachTimeout))
+	conn.Write(reply.Bytes())
+}
+
+// jvmgo jstack <pid>
+// 连接正在运行的虚拟机 打印它的线程转储
+func jstack(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s jstack <pid>\n", os.Args[0])
+		return 1
+	}
+	return attach(args[0], "threaddump")
+}
+
+// jvmgo jmap -histo[:live] <pid>
+// jvmgo jmap -dump:[live,][format=b,]file=<path> <pid>
+// 只支持二进制格式 统计和转储的本来就只有可达的对象 所以忽略live
+func jmap(args []string) int {
+	if len(args) != 2 {
+		return jmapUsage()
+	}
+	option, pid := args[0], args[1]
+	if option == "-histo" || option == "-histo:live" {
+		return attach(pid, "inspectheap")
+	}
+	if !strings.HasPrefix(option, "-dump:") {
+		return jmapUsage()
+	}
+	path := ""
+	for _, suboption := range strings.Split(strings.TrimPrefix(option, "-dump:"), ",") {
+		switch {
+		case strings.HasPrefix(suboption, "file="):
+			path = strings.TrimPrefix(suboption, "file=")
+		case suboption != "live" && suboption != "format=b":
+			fmt.Fprintf(os.Stderr, "Unknown dump option: %s\n", suboption)
+			return 1
+		}
+	}
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "No dump file specified")
+		return 1
+	}
+	path, err := filepath.Abs(path) // 虚拟机的当前目录可能不一样
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	return attach(pid, "dumpheap "+path)
+}
+
+func jmapUsage() int {
+	fmt.Fprintf(os.Stderr, "Usage: %s jmap -histo[:live] <pid>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "   or  %s jmap -dump:file=<path> <pid>\n", os.Args[0])
+	return 1
+}
+
+// 发送一行命令 把回复打印出来
+func attach(pidArg, command string) int {
+	pid, err := strconv.Atoi(pidArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: invalid pid\n", pidArg)
+		return 1
+	}
+	conn, err := net.Dial("unix", attachSocketPath(pid))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%d: unable to open socket file: %v\n", pid, err)
+		return 1
+	}
+	defer conn.Close()
+	io.WriteString(conn, command+"\n")
+
+	reply := bufio.NewReader(conn)
+	status, err := reply.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%d: no reply from the attach listener: %v\n", pid, err)
+		return 1
+	}
+	if strings.TrimSpace(status) != "0" {
+		io.Copy(os.Stderr, reply)
+		return 1
+	}
+	io.Copy(os.Stdout, reply)
+	return 0
+}
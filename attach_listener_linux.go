@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// 用SO_PEERCRED取得对方的有效uid和gid 必须和虚拟机的一样
+func checkAttachPeer(conn net.Conn) error {
+	raw, err := conn.(*net.UnixConn).SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *syscall.Ucred
+	err2 := raw.Control(func(fd uintptr) {
+		cred, err = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err2 != nil {
+		return err2
+	}
+	if err != nil {
+		return err
+	}
+	if int(cred.Uid) != os.Geteuid() || int(cred.Gid) != os.Getegid() {
+		return fmt.Errorf("file permission denied: peer uid %d, gid %d", cred.Uid, cred.Gid)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"jvm/rtda"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveAttach在限定的时间内返回 返回之后连接已经关闭
+func serveAttachWithin(t *testing.T, server net.Conn) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		(&JVM{}).serveAttach(rtda.NewThread(), server)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("serveAttach blocked the attach listener")
+	}
+}
+
+func setAttachTimeout(t *testing.T, timeout time.Duration) {
+	saved := attachTimeout
+	attachTimeout = timeout
+	t.Cleanup(func() { attachTimeout = saved })
+}
+
+// 客户端连上以后什么也不发
+func TestAttachIdleClient(t *testing.T) {
+	setAttachTimeout(t, 50*time.Millisecond)
+	server, client := net.Pipe()
+	defer client.Close()
+	serveAttachWithin(t, server)
+}
+
+// 客户端发了命令但不读回复 net.Pipe没有缓冲 写回复会一直阻塞
+func TestAttachClientNotReading(t *testing.T) {
+	setAttachTimeout(t, 50*time.Millisecond)
+	server, client := net.Pipe()
+	defer client.Close()
+	go io.WriteString(client, "nosuchcommand\n")
+	serveAttachWithin(t, server)
+}
+
+func TestAttachUnknownCommand(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	go (&JVM{}).serveAttach(rtda.NewThread(), server)
+	io.WriteString(client, "nosuchcommand\n")
+	reply := bufio.NewReader(client)
+	if status, _ := reply.ReadString('\n'); status != "1\n" {
+		t.Fatalf("status = %q, want 1", status)
+	}
+	if msg, _ := reply.ReadString('\n'); msg != "unknown command: nosuchcommand\n" {
+		t.Fatalf("message = %q", msg)
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import "syscall"
+
+const _STILL_ACTIVE = 259
+
+func processExists(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	return syscall.GetExitCodeProcess(h, &code) == nil && code == _STILL_ACTIVE
+}
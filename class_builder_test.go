@@ -0,0 +1,428 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 测试用的类文件生成器 没有JDK也能拼出简单的类 放进假的rt.jar里运行
+
+// 用到的操作码
+const (
+	op_aconst_null     = 0x01
+	op_iconst_m1       = 0x02
+	op_iconst_0        = 0x03
+	op_iconst_1        = 0x04
+	op_iconst_2        = 0x05
+	op_iconst_3        = 0x06
+	op_iconst_5        = 0x08
+	op_lconst_0        = 0x09
+	op_lconst_1        = 0x0a
+	op_fconst_1        = 0x0c
+	op_fconst_2        = 0x0d
+	op_dconst_1        = 0x0f
+	op_bipush          = 0x10
+	op_sipush          = 0x11
+	op_iload           = 0x15
+	op_aload           = 0x19
+	op_iload_0         = 0x1a
+	op_iload_1         = 0x1b
+	op_iload_2         = 0x1c
+	op_lload_0         = 0x1e
+	op_fload_3         = 0x25
+	op_dload_0         = 0x26
+	op_aload_0         = 0x2a
+	op_aload_1         = 0x2b
+	op_aload_2         = 0x2c
+	op_aload_3         = 0x2d
+	op_iaload          = 0x2e
+	op_laload          = 0x2f
+	op_baload          = 0x33
+	op_caload          = 0x34
+	op_istore          = 0x36
+	op_astore          = 0x3a
+	op_istore_1        = 0x3c
+	op_istore_2        = 0x3d
+	op_lstore_0        = 0x3f
+	op_fstore_3        = 0x46
+	op_dstore_0        = 0x47
+	op_astore_1        = 0x4c
+	op_astore_2        = 0x4d
+	op_astore_3        = 0x4e
+	op_iastore         = 0x4f
+	op_lastore         = 0x50
+	op_bastore         = 0x54
+	op_castore         = 0x55
+	op_pop             = 0x57
+	op_dup             = 0x59
+	op_dup2            = 0x5c
+	op_iadd            = 0x60
+	op_ladd            = 0x61
+	op_fadd            = 0x62
+	op_dadd            = 0x63
+	op_isub            = 0x64
+	op_lsub            = 0x65
+	op_dsub            = 0x67
+	op_imul            = 0x68
+	op_lmul            = 0x69
+	op_fmul            = 0x6a
+	op_dmul            = 0x6b
+	op_ldiv            = 0x6d
+	op_fdiv            = 0x6e
+	op_ddiv            = 0x6f
+	op_irem            = 0x70
+	op_lrem            = 0x71
+	op_drem            = 0x73
+	op_ineg            = 0x74
+	op_lneg            = 0x75
+	op_ishl            = 0x78
+	op_lshl            = 0x79
+	op_ishr            = 0x7a
+	op_lshr            = 0x7b
+	op_iushr           = 0x7c
+	op_lushr           = 0x7d
+	op_land            = 0x7f
+	op_ior             = 0x80
+	op_ixor            = 0x82
+	op_lxor            = 0x83
+	op_iinc            = 0x84
+	op_i2l             = 0x85
+	op_i2f             = 0x86
+	op_i2d             = 0x87
+	op_l2i             = 0x88
+	op_l2d             = 0x8a
+	op_f2i             = 0x8b
+	op_f2d             = 0x8d
+	op_d2l             = 0x8f
+	op_d2f             = 0x90
+	op_i2b             = 0x91
+	op_i2c             = 0x92
+	op_i2s             = 0x93
+	op_lcmp            = 0x94
+	op_dcmpl           = 0x97
+	op_ifeq            = 0x99
+	op_ifge            = 0x9c
+	op_ifle            = 0x9e
+	op_if_icmpge       = 0xa2
+	op_goto            = 0xa7
+	op_tableswitch     = 0xaa
+	op_lookupswitch    = 0xab
+	op_ireturn         = 0xac
+	op_lreturn         = 0xad
+	op_dreturn         = 0xaf
+	op_return          = 0xb1
+	op_getstatic       = 0xb2
+	op_putstatic       = 0xb3
+	op_getfield        = 0xb4
+	op_putfield        = 0xb5
+	op_invokevirtual   = 0xb6
+	op_invokespecial   = 0xb7
+	op_invokestatic    = 0xb8
+	op_invokeinterface = 0xb9
+	op_new             = 0xbb
+	op_newarray        = 0xbc
+	op_arraylength     = 0xbe
+	op_athrow          = 0xbf
+	op_checkcast       = 0xc0
+	op_instanceof      = 0xc1
+	op_monitorenter    = 0xc2
+	op_monitorexit     = 0xc3
+)
+
+// newarray的元素类型
+const (
+	t_char = 5
+	t_byte = 8
+	t_int  = 10
+	t_long = 11
+)
+
+// 常量池 同样的常量只放一次
+type testConstantPool struct {
+	buf   bytes.Buffer
+	count uint16
+	index map[string]uint16
+}
+
+func newTestConstantPool() *testConstantPool {
+	return &testConstantPool{index: map[string]uint16{}}
+}
+
+func (self *testConstantPool) add(key string, tag byte, write func(buf *bytes.Buffer)) uint16 {
+	if i, ok := self.index[key]; ok {
+		return i
+	}
+	self.buf.WriteByte(tag)
+	write(&self.buf)
+	self.count++
+	self.index[key] = self.count
+	return self.count
+}
+
+func (self *testConstantPool) utf8(s string) uint16 {
+	return self.add("utf8:"+s, 1, func(buf *bytes.Buffer) {
+		binary.Write(buf, binary.BigEndian, uint16(len(s)))
+		buf.WriteString(s)
+	})
+}
+
+func (self *testConstantPool) class(name string) uint16 {
+	nameIndex := self.utf8(name)
+	return self.add("class:"+name, 7, func(buf *bytes.Buffer) {
+		binary.Write(buf, binary.BigEndian, nameIndex)
+	})
+}
+
+func (self *testConstantPool) nameAndType(name, descriptor string) uint16 {
+	nameIndex, descIndex := self.utf8(name), self.utf8(descriptor)
+	return self.add("nat:"+name+":"+descriptor, 12, func(buf *bytes.Buffer) {
+		binary.Write(buf, binary.BigEndian, nameIndex)
+		binary.Write(buf, binary.BigEndian, descIndex)
+	})
+}
+
+func (self *testConstantPool) memberRef(tag byte, class, name, descriptor string) uint16 {
+	classIndex, natIndex := self.class(class), self.nameAndType(name, descriptor)
+	return self.add(fmt.Sprintf("%d:%s.%s:%s", tag, class, name, descriptor), tag, func(buf *bytes.Buffer) {
+		binary.Write(buf, binary.BigEndian, classIndex)
+		binary.Write(buf, binary.BigEndian, natIndex)
+	})
+}
+
+func (self *testConstantPool) fieldRef(class, name, descriptor string) uint16 {
+	return self.memberRef(9, class, name, descriptor)
+}
+
+func (self *testConstantPool) methodRef(class, name, descriptor string) uint16 {
+	return self.memberRef(10, class, name, descriptor)
+}
+
+func (self *testConstantPool) interfaceMethodRef(class, name, descriptor string) uint16 {
+	return self.memberRef(11, class, name, descriptor)
+}
+
+// 方法的字节码 跳转目标用标签表示 最后统一回填偏移量
+type bytecode struct {
+	code   []byte
+	labels map[string]int
+	jumps  []bytecodeJump
+}
+
+// at处的偏移量相对于pc处的指令 wide表示4字节偏移(tableswitch和lookupswitch)
+type bytecodeJump struct {
+	pc, at int
+	label  string
+	wide   bool
+}
+
+func newBytecode() *bytecode {
+	return &bytecode{labels: map[string]int{}}
+}
+
+func (self *bytecode) op(bytes ...int) *bytecode {
+	for _, b := range bytes {
+		self.code = append(self.code, byte(b))
+	}
+	return self
+}
+
+func (self *bytecode) u2(op int, index uint16) *bytecode {
+	return self.op(op, int(index>>8), int(index))
+}
+
+func (self *bytecode) mark(label string) *bytecode {
+	self.labels[label] = len(self.code)
+	return self
+}
+
+func (self *bytecode) jump(op int, label string) *bytecode {
+	self.jumps = append(self.jumps, bytecodeJump{pc: len(self.code), at: len(self.code) + 1, label: label})
+	return self.op(op, 0, 0)
+}
+
+func (self *bytecode) s4(v int32) {
+	self.code = binary.BigEndian.AppendUint32(self.code, uint32(v))
+}
+
+func (self *bytecode) switchTarget(pc int, label string) {
+	self.jumps = append(self.jumps, bytecodeJump{pc: pc, at: len(self.code), label: label, wide: true})
+	self.s4(0)
+}
+
+// 操作码之后补齐到4字节边界
+func (self *bytecode) switchHeader(op int) int {
+	pc := len(self.code)
+	self.op(op)
+	for len(self.code)%4 != 0 {
+		self.op(0)
+	}
+	return pc
+}
+
+func (self *bytecode) tableswitch(defaultLabel string, low int32, labels ...string) *bytecode {
+	pc := self.switchHeader(op_tableswitch)
+	self.switchTarget(pc, defaultLabel)
+	self.s4(low)
+	self.s4(low + int32(len(labels)) - 1)
+	for _, label := range labels {
+		self.switchTarget(pc, label)
+	}
+	return self
+}
+
+// keys要按升序排列
+func (self *bytecode) lookupswitch(defaultLabel string, keys []int32, labels ...string) *bytecode {
+	pc := self.switchHeader(op_lookupswitch)
+	self.switchTarget(pc, defaultLabel)
+	self.s4(int32(len(keys)))
+	for i, key := range keys {
+		self.s4(key)
+		self.switchTarget(pc, labels[i])
+	}
+	return self
+}
+
+func (self *bytecode) bytes(t testing.TB) []byte {
+	for _, jump := range self.jumps {
+		target, ok := self.labels[jump.label]
+		if !ok {
+			t.Fatalf("undefined label %s", jump.label)
+		}
+		offset := target - jump.pc
+		if jump.wide {
+			binary.BigEndian.PutUint32(self.code[jump.at:], uint32(int32(offset)))
+		} else {
+			binary.BigEndian.PutUint16(self.code[jump.at:], uint16(int16(offset)))
+		}
+	}
+	return self.code
+}
+
+type testField struct {
+	flags            uint16
+	name, descriptor string
+}
+
+type testMethod struct {
+	flags               uint16
+	name, descriptor    string
+	maxStack, maxLocals uint16
+	code                []byte
+}
+
+type testClass struct {
+	flags      uint16
+	name       string
+	superName  string // java/lang/Object没有超类
+	interfaces []string
+	cp         *testConstantPool // 字节码里用到的常量要和类共用一个常量池
+	fields     []testField
+	methods    []testMethod
+}
+
+func (self *testClass) bytes() []byte {
+	cp := self.cp
+	if cp == nil {
+		cp = newTestConstantPool()
+	}
+	thisIndex := cp.class(self.name)
+	superIndex := uint16(0)
+	if self.superName != "" {
+		superIndex = cp.class(self.superName)
+	}
+	var interfaceIndexes []uint16
+	for _, iface := range self.interfaces {
+		interfaceIndexes = append(interfaceIndexes, cp.class(iface))
+	}
+	codeIndex := cp.utf8("Code")
+	memberIndexes := func(name, descriptor string) [2]uint16 {
+		return [2]uint16{cp.utf8(name), cp.utf8(descriptor)}
+	}
+	var fieldIndexes, methodIndexes [][2]uint16
+	for _, field := range self.fields {
+		fieldIndexes = append(fieldIndexes, memberIndexes(field.name, field.descriptor))
+	}
+	for _, method := range self.methods {
+		methodIndexes = append(methodIndexes, memberIndexes(method.name, method.descriptor))
+	}
+
+	var out bytes.Buffer
+	w := func(v interface{}) { binary.Write(&out, binary.BigEndian, v) }
+	w(uint32(0xCAFEBABE))
+	w(uint16(0))  // minor_version
+	w(uint16(50)) // major_version 不需要StackMapTable
+	w(cp.count + 1)
+	out.Write(cp.buf.Bytes())
+	w(self.flags)
+	w(thisIndex)
+	w(superIndex)
+	w(uint16(len(interfaceIndexes)))
+	w(interfaceIndexes)
+	w(uint16(len(self.fields)))
+	for i, field := range self.fields {
+		w(field.flags)
+		w(fieldIndexes[i])
+		w(uint16(0)) // attributes_count
+	}
+	w(uint16(len(self.methods)))
+	for i, method := range self.methods {
+		w(method.flags)
+		w(methodIndexes[i])
+		if method.code == nil {
+			w(uint16(0)) // 抽象方法
+			continue
+		}
+		w(uint16(1))
+		w(codeIndex)
+		w(uint32(12 + len(method.code)))
+		w(method.maxStack)
+		w(method.maxLocals)
+		w(uint32(len(method.code)))
+		out.Write(method.code)
+		w(uint16(0)) // exception_table_length
+		w(uint16(0)) // attributes_count
+	}
+	w(uint16(0)) // attributes_count
+	return out.Bytes()
+}
+
+// 在临时目录里造一个jre 只有lib/rt.jar 里面是加载数组类需要的几个类和classes
+// 返回的目录既可以当-Xjre也可以当-cp
+func writeTestJre(t testing.TB, classes ...*testClass) string {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "lib", "rt.jar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	object := &testClass{flags: 0x21, name: "java/lang/Object", methods: []testMethod{
+		{0x1, "<init>", "()V", 0, 1, []byte{op_return}},
+	}}
+	bootClasses := []*testClass{
+		object,
+		{flags: 0x31, name: "java/lang/Class", superName: "java/lang/Object"},
+		{flags: 0x601, name: "java/lang/Cloneable", superName: "java/lang/Object"},
+		{flags: 0x601, name: "java/io/Serializable", superName: "java/lang/Object"},
+	}
+	zw := zip.NewWriter(f)
+	for _, class := range append(bootClasses, classes...) {
+		w, err := zw.Create(class.name + ".class")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(class.bytes())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
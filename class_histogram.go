@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"jvm/rtda/heap"
+)
+
+// 和jmap -histo的格式一样 每个类一行 按占用的字节数从大到小排列
+// 调用者必须持有全局解释器锁
+func classHistogram(loader *heap.ClassLoader) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("\n num     #instances         #bytes  class name\n")
+	buf.WriteString("----------------------------------------------\n")
+	var instances, size int64
+	for i, entry := range loader.Histogram(heapRoots()) {
+		fmt.Fprintf(buf, "%4d: %13d %14d  %s\n", i+1, entry.Instances, entry.Bytes, entry.Class.JavaName())
+		instances += entry.Instances
+		size += entry.Bytes
+	}
+	fmt.Fprintf(buf, "Total %13d %14d\n", instances, size)
+	return buf.Bytes()
+}
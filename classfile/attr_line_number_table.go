@@ -33,6 +33,18 @@ func (self *LineNumberTableAttribute) readInfo(reader *ClassReader) {
 
 }
 
+func (self *LineNumberTableAttribute) Entries() []*LineNumberTableEntry {
+	return self.lineNumberTable
+}
+
+func (self *LineNumberTableEntry) StartPc() uint16 {
+	return self.startPc
+}
+
+func (self *LineNumberTableEntry) LineNumber() uint16 {
+	return self.lineNumber
+}
+
 // TODO 给异常处理用的
 func (self *LineNumberTableAttribute) GetLineNumber(pc int) int {
 	for i := len(self.lineNumberTable) - 1;i >= 0;i-- {
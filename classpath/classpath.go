@@ -74,6 +74,16 @@ func (self *Classpath) ReadClass(className string) ([]byte, Entry, error) {
 	return self.userClasspath.readClass(className)
 }
 
+// 启动类路径下所有jar包的绝对路径 -Xshare用来校验共享归档是否过期
+func (self *Classpath) BootJars() []string {
+	entries := self.bootClasspath.(CompositeEntry)
+	jars := make([]string, len(entries))
+	for i, entry := range entries {
+		jars[i] = entry.String()
+	}
+	return jars
+}
+
 func (self *Classpath) String() string {
 	return self.userClasspath.String()
 }
@@ -4,6 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type Cmd struct {
@@ -13,6 +16,17 @@ type Cmd struct {
 	verboseInstFlag bool
 	cpOption string
 	XjreOption string
+	XssOption string
+	XmxOption string
+	XlinkOption string
+	XshareOption string
+	XintOption string
+	useJITFlag bool
+	printCompilationFlag bool
+	heapDumpOnOutOfMemoryFlag bool
+	heapDumpPath string
+	printClassHistogramFlag bool
+	sharedArchiveFile string
 	class string
 	args []string
 }
@@ -29,7 +43,18 @@ func parseCmd() *Cmd {
 	flag.StringVar(&cmd.cpOption, "classpath", "", "classpath")
 	flag.StringVar(&cmd.cpOption, "cp", "", "classpath")
 	flag.StringVar(&cmd.XjreOption, "Xjre", "", "path to jre")
-	flag.Parse()
+	flag.StringVar(&cmd.XssOption, "Xss", "", "thread stack size, in frames (2048) or bytes (512k)")
+	flag.StringVar(&cmd.XmxOption, "Xmx", "", "maximum heap size, in bytes (64m)")
+	flag.StringVar(&cmd.XlinkOption, "Xlink", "lazy", "resolve symbolic references lazily or eagerly at link time (lazy|eager)")
+	flag.StringVar(&cmd.XshareOption, "Xshare", "auto", "use the shared class data archive (auto|dump|off)")
+	flag.StringVar(&cmd.XintOption, "Xint", "", "interpreted mode only, with the default or fast interpreter (default|fast)")
+	flag.BoolVar(&cmd.useJITFlag, "XX:+UseJIT", false, "compile hot methods (mixed mode), ignored with -Xint")
+	flag.BoolVar(&cmd.printCompilationFlag, "XX:+PrintCompilation", false, "print methods compiled by the JIT")
+	flag.BoolVar(&cmd.heapDumpOnOutOfMemoryFlag, "XX:+HeapDumpOnOutOfMemoryError", false, "dump the heap when the first OutOfMemoryError is thrown")
+	flag.StringVar(&cmd.heapDumpPath, "XX:HeapDumpPath", "", "file or directory of the heap dump (java_pid<pid>.hprof)")
+	flag.BoolVar(&cmd.printClassHistogramFlag, "XX:+PrintClassHistogram", false, "print a class histogram after the thread dump on SIGQUIT")
+	flag.StringVar(&cmd.sharedArchiveFile, "XX:SharedArchiveFile", "", "path to the shared class data archive")
+	flag.CommandLine.Parse(normalizeArgs(os.Args[1:]))
 
 	args := flag.Args()
 	if (len(args) > 0) {
@@ -40,6 +65,106 @@ func parseCmd() *Cmd {
 	return cmd
 }
 
+// java习惯把值直接写在选项后面 比如-Xss512k 这里改写成flag包认识的-Xss=512k
+// -Xlink:eager -Xshare:dump这样用冒号分隔的也改写成-Xlink=eager -Xshare=dump
+// 单独的-Xint表示只用默认的解释器 不编译热点方法
+// 主类以及之后的参数原样保留
+func normalizeArgs(args []string) []string {
+	normalized := append([]string{}, args...)
+	for i := 0; i < len(normalized); i++ {
+		arg := normalized[i]
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		for _, name := range []string{"-Xss", "-Xmx"} {
+			if strings.HasPrefix(arg, name) && len(arg) > len(name) && arg[len(name)] != '=' {
+				normalized[i] = name + "=" + arg[len(name):]
+			}
+		}
+		if arg == "-Xint" {
+			normalized[i] = "-Xint=default"
+		}
+		for _, name := range []string{"-Xlink", "-Xshare", "-Xint"} {
+			if strings.HasPrefix(arg, name+":") {
+				normalized[i] = name + "=" + arg[len(name)+1:]
+			}
+		}
+		if !strings.Contains(normalized[i], "=") && takesValue(arg) {
+			i++ // 跳过选项的值
+		}
+	}
+	return normalized
+}
+
+func takesValue(arg string) bool {
+	f := flag.CommandLine.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
+// 不带单位的数字表示栈帧个数 带k/m/g单位的表示字节数
+func parseStackSize(option string) (frames, bytes uint, err error) {
+	if option == "" {
+		return 0, 0, nil
+	}
+	if unit := sizeUnit(option); unit == 1 {
+		n, err := strconv.ParseUint(option, 10, 32)
+		return uint(n), 0, err
+	}
+	n, err := parseMemorySize(option)
+	return 0, uint(n), err
+}
+
+// 64m 512k 1g 或者直接给出字节数
+func parseMemorySize(option string) (int64, error) {
+	if option == "" {
+		return 0, nil
+	}
+	unit := sizeUnit(option)
+	if unit > 1 {
+		option = option[:len(option)-1]
+	}
+	n, err := strconv.ParseInt(option, 10, 64)
+	return n * unit, err
+}
+
+func sizeUnit(option string) int64 {
+	switch option[len(option)-1] {
+	case 'k', 'K':
+		return 1 << 10
+	case 'm', 'M':
+		return 1 << 20
+	case 'g', 'G':
+		return 1 << 30
+	}
+	return 1
+}
+
+// 默认放在用户的缓存目录下 jre目录通常是不可写的
+func defaultSharedArchiveFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "jvmgo", "classes.jsa")
+}
+
+// 和java命令一样 选项有误时打印错误和用法 不创建虚拟机
+func usageError(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	printUsage()
+	os.Exit(1)
+}
+
 func printUsage() {
 	fmt.Printf("Usage: %s [-Options] class [args...]\n", os.Args[0])
+	fmt.Printf("   or  %s -Xshare:dump [-Options]\n", os.Args[0])
+	fmt.Printf("   or  %s jstack <pid>\n", os.Args[0])
+	fmt.Printf("   or  %s jmap -histo[:live] <pid>\n", os.Args[0])
+	fmt.Printf("   or  %s jmap -dump:file=<path> <pid>\n", os.Args[0])
 }
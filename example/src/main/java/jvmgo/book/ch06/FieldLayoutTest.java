@@ -0,0 +1,41 @@
+package jvmgo.book.ch06;
+
+// jvmgo -Xint jvmgo.book.ch06.FieldLayoutTest
+// jvmgo -Xint:fast jvmgo.book.ch06.FieldLayoutTest
+// jvmgo -XX:+UseJIT jvmgo.book.ch06.FieldLayoutTest
+public class FieldLayoutTest {
+
+    static class Point {
+        int a, b, c, d, e, f, g, h;
+        boolean flag;
+        char ch;
+        long count;
+        Object next;
+    }
+
+    public static void main(String[] args) {
+        Runtime rt = Runtime.getRuntime();
+        rt.gc();
+        long before = rt.totalMemory() - rt.freeMemory();
+        Point[] points = new Point[10000];
+        for (int i = 0; i < points.length; i++) {
+            points[i] = new Point();
+        }
+        long after = rt.totalMemory() - rt.freeMemory();
+        // jvmgo的freeMemory来自堆的记账 这里是估计的大小 Go实际分配的内存见rtda/heap的BenchmarkNewObject
+        System.out.println("estimated bytes/object: " + (after - before - 8L * points.length) / points.length);
+
+        Point p = points[0];
+        int iterations = 1000000;
+        long start = System.currentTimeMillis();
+        for (int i = 0; i < iterations; i++) {
+            p.a = p.a + p.b;
+            p.count = p.count + 1;
+            p.next = p.next;
+        }
+        long millis = System.currentTimeMillis() - start;
+        System.out.println("getfield/putfield: " + millis * 1000000L / iterations + " ns/iter");
+        System.out.println(p.a + " " + p.count);
+    }
+
+}
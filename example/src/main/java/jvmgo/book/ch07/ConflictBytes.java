@@ -0,0 +1,85 @@
+package jvmgo.book.ch07;
+
+import java.io.ByteArrayOutputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+
+// 生成一个实现了两个接口 但没有覆盖它们冲突的默认方法的类:
+// public class <name> implements <iface1>, <iface2> {
+//     public <name>() { super(); }
+// }
+// javac不会编译这样的类 相当于先编译这个类 再分别给两个接口加上同名的默认方法
+public class ConflictBytes {
+
+    public static byte[] generate(String name, String iface1, String iface2) {
+        try {
+            ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+            DataOutputStream out = new DataOutputStream(bytes);
+            out.writeInt(0xCAFEBABE);
+            out.writeShort(0);  // minor_version
+            out.writeShort(50); // major_version 不需要StackMapTable
+
+            out.writeShort(14); // constant_pool_count
+            utf8(out, name.replace('.', '/'));   // #1
+            classInfo(out, 1);                   // #2
+            utf8(out, "java/lang/Object");       // #3
+            classInfo(out, 3);                   // #4
+            utf8(out, "<init>");                 // #5
+            utf8(out, "()V");                    // #6
+            out.writeByte(12);                   // #7 NameAndType
+            out.writeShort(5);
+            out.writeShort(6);
+            out.writeByte(10);                   // #8 Methodref Object.<init>
+            out.writeShort(4);
+            out.writeShort(7);
+            utf8(out, "Code");                   // #9
+            utf8(out, iface1.replace('.', '/')); // #10
+            classInfo(out, 10);                  // #11
+            utf8(out, iface2.replace('.', '/')); // #12
+            classInfo(out, 12);                  // #13
+
+            out.writeShort(0x0021); // ACC_PUBLIC | ACC_SUPER
+            out.writeShort(2);      // this_class
+            out.writeShort(4);      // super_class
+            out.writeShort(2);      // interfaces_count
+            out.writeShort(11);
+            out.writeShort(13);
+            out.writeShort(0);      // fields_count
+
+            out.writeShort(1); // methods_count
+            out.writeShort(0x0001); // ACC_PUBLIC
+            out.writeShort(5);      // <init>
+            out.writeShort(6);      // ()V
+            out.writeShort(1);      // attributes_count
+            out.writeShort(9);      // Code
+            out.writeInt(12 + 5);
+            out.writeShort(1);      // max_stack
+            out.writeShort(1);      // max_locals
+            out.writeInt(5);
+            out.write(new byte[]{
+                0x2a,                   // aload_0
+                (byte) 0xb7, 0x00, 0x08, // invokespecial #8
+                (byte) 0xb1,            // return
+            });
+            out.writeShort(0); // exception_table_length
+            out.writeShort(0); // attributes_count
+
+            out.writeShort(0); // attributes_count
+            out.flush();
+            return bytes.toByteArray();
+        } catch (IOException e) {
+            throw new RuntimeException(e);
+        }
+    }
+
+    private static void utf8(DataOutputStream out, String s) throws IOException {
+        out.writeByte(1);
+        out.writeUTF(s);
+    }
+
+    private static void classInfo(DataOutputStream out, int nameIndex) throws IOException {
+        out.writeByte(7);
+        out.writeShort(nameIndex);
+    }
+
+}
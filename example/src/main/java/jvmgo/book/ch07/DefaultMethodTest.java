@@ -0,0 +1,177 @@
+package jvmgo.book.ch07;
+
+import java.util.Arrays;
+import java.util.Comparator;
+import java.util.Iterator;
+import java.util.Map;
+import java.util.TreeMap;
+import java.util.function.Consumer;
+
+// jvmgo jvmgo.book.ch07.DefaultMethodTest
+public class DefaultMethodTest {
+
+    interface A {
+        default String who() {
+            return "A";
+        }
+    }
+
+    interface B extends A {
+        @Override
+        default String who() {
+            return "B";
+        }
+    }
+
+    interface C extends A {
+    }
+
+    // 菱形继承 B.who()比A.who()更具体
+    static class D implements B, C {
+    }
+
+    interface I {
+        default String name() {
+            return "I";
+        }
+    }
+
+    interface J {
+        default String name() {
+            return "J";
+        }
+    }
+
+    // 两个默认方法冲突 必须自己覆盖 用I.super.name()调用接口的默认方法
+    static class K implements I, J {
+        @Override
+        public String name() {
+            return I.super.name() + J.super.name();
+        }
+    }
+
+    // 子接口把默认方法重新声明为抽象方法
+    interface E extends A {
+        @Override
+        String who();
+    }
+
+    static class F implements E {
+        @Override
+        public String who() {
+            return "F";
+        }
+    }
+
+    public interface Left {
+        default String side() {
+            return "left";
+        }
+    }
+
+    public interface Right {
+        default String side() {
+            return "right";
+        }
+    }
+
+    // 加载一个实现了Left和Right却没有覆盖side()的类 调用side()时抛出IncompatibleClassChangeError
+    static class ConflictLoader extends ClassLoader {
+
+        ConflictLoader() {
+            super(DefaultMethodTest.class.getClassLoader());
+        }
+
+        @Override
+        protected Class<?> findClass(String name) throws ClassNotFoundException {
+            if (!name.equals("jvmgo.book.ch07.Conflict")) {
+                throw new ClassNotFoundException(name);
+            }
+            byte[] bytes = ConflictBytes.generate(name, Left.class.getName(), Right.class.getName());
+            return defineClass(name, bytes, 0, bytes.length);
+        }
+
+    }
+
+    interface Greeter {
+        String greet();
+
+        static Greeter of(final String name) {
+            return new Greeter() {
+                @Override
+                public String greet() {
+                    return "hello, " + name;
+                }
+            };
+        }
+    }
+
+    static class Range implements Iterable<Integer> {
+        private final int n;
+
+        Range(int n) {
+            this.n = n;
+        }
+
+        @Override
+        public Iterator<Integer> iterator() {
+            return new Iterator<Integer>() {
+                private int i;
+
+                @Override
+                public boolean hasNext() {
+                    return i < n;
+                }
+
+                @Override
+                public Integer next() {
+                    return i++;
+                }
+            };
+        }
+    }
+
+    public static void main(String[] args) throws Exception {
+        A d = new D();
+        System.out.println("D.who(): " + d.who());
+        System.out.println("K.name(): " + new K().name());
+        System.out.println("F.who(): " + new F().who());
+        System.out.println(Greeter.of("default methods").greet());
+
+        Object conflict = new ConflictLoader().loadClass("jvmgo.book.ch07.Conflict").newInstance();
+        try {
+            System.out.println("Left.side(): " + ((Left) conflict).side());
+        } catch (IncompatibleClassChangeError e) {
+            System.out.println("Left.side(): " + e);
+        }
+        try {
+            System.out.println("Right.side(): " + ((Right) conflict).side());
+        } catch (IncompatibleClassChangeError e) {
+            System.out.println("Right.side(): " + e);
+        }
+
+        final StringBuilder sb = new StringBuilder();
+        new Range(3).forEach(new Consumer<Integer>() {
+            @Override
+            public void accept(Integer i) {
+                sb.append(i).append(' ');
+            }
+        });
+        System.out.println("Iterable.forEach: " + sb);
+
+        Map<String, Integer> map = new TreeMap<>();
+        map.put("one", 1);
+        System.out.println("Map.getOrDefault: " + map.getOrDefault("one", 0) + " " + map.getOrDefault("two", 0));
+
+        Comparator<Integer> natural = new Comparator<Integer>() {
+            @Override
+            public int compare(Integer a, Integer b) {
+                return a.compareTo(b);
+            }
+        };
+        Integer[] nums = {3, 1, 2};
+        Arrays.sort(nums, natural.reversed());
+        System.out.println("Comparator.reversed: " + Arrays.toString(nums));
+    }
+
+}
@@ -0,0 +1,40 @@
+package jvmgo.book.ch10;
+
+import java.lang.reflect.Field;
+import java.nio.ByteBuffer;
+import java.nio.ByteOrder;
+import sun.misc.Unsafe;
+
+// jvmgo jvmgo.book.ch10.DirectBufferTest
+public class DirectBufferTest {
+
+    public static void main(String[] args) throws Exception {
+        Field f = Unsafe.class.getDeclaredField("theUnsafe");
+        f.setAccessible(true);
+        Unsafe unsafe = (Unsafe) f.get(null);
+
+        long address = unsafe.allocateMemory(32);
+        unsafe.setMemory(address, 32, (byte) 0);
+        unsafe.putInt(address, 42);
+        unsafe.putDouble(address + 8, 3.5);
+        unsafe.putChar(address + 16, 'x');
+        System.out.println("int: " + unsafe.getInt(address));
+        System.out.println("double: " + unsafe.getDouble(address + 8));
+        System.out.println("char: " + unsafe.getChar(address + 16));
+
+        byte[] bytes = new byte[4];
+        unsafe.copyMemory(null, address, bytes, Unsafe.ARRAY_BYTE_BASE_OFFSET, 4);
+        System.out.println("copied: " + (bytes[0] + bytes[1] + bytes[2] + bytes[3]));
+
+        address = unsafe.reallocateMemory(address, 64);
+        System.out.println("after realloc: " + unsafe.getInt(address));
+        unsafe.freeMemory(address);
+
+        ByteBuffer buf = ByteBuffer.allocateDirect(16);
+        System.out.println("native order: " + ByteOrder.nativeOrder());
+        buf.putInt(7).putLong(1L << 40).putFloat(1.5f);
+        buf.flip();
+        System.out.println(buf.getInt() + " " + buf.getLong() + " " + buf.getFloat());
+    }
+
+}
@@ -0,0 +1,46 @@
+package jvmgo.book.ch10;
+
+// jvmgo jvmgo.book.ch10.FinalizeTest
+public class FinalizeTest {
+
+    static int finalized;
+    static FinalizeTest resurrected;
+
+    private final int id;
+
+    FinalizeTest(int id) {
+        this.id = id;
+    }
+
+    @Override
+    protected void finalize() {
+        synchronized (FinalizeTest.class) {
+            finalized++;
+        }
+        if (id == 0) {
+            resurrected = this;
+        }
+        if (id == 1) {
+            throw new RuntimeException("ignored");
+        }
+    }
+
+    public static void main(String[] args) {
+        for (int i = 0; i < 10; i++) {
+            new FinalizeTest(i);
+        }
+        System.gc();
+        System.runFinalization();
+        synchronized (FinalizeTest.class) {
+            System.out.println("finalized: " + finalized);
+        }
+        System.out.println("resurrected: " + (resurrected != null ? resurrected.id : -1));
+
+        // finalize()只执行一次
+        resurrected = null;
+        System.gc();
+        System.runFinalization();
+        System.out.println("finalized: " + finalized);
+    }
+
+}
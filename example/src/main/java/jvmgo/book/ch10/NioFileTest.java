@@ -0,0 +1,53 @@
+package jvmgo.book.ch10;
+
+import java.nio.ByteBuffer;
+import java.nio.MappedByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.DirectoryStream;
+import java.nio.file.Files;
+import java.nio.file.NoSuchFileException;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.nio.file.StandardOpenOption;
+
+// jvmgo jvmgo.book.ch10.NioFileTest
+public class NioFileTest {
+
+    public static void main(String[] args) throws Exception {
+        Path dir = Paths.get(System.getProperty("java.io.tmpdir"), "jvmgo-nio-test");
+        Files.createDirectories(dir);
+        Path file = dir.resolve("hello.txt");
+
+        Files.write(file, "hello, nio".getBytes(StandardCharsets.UTF_8));
+        System.out.println("size: " + Files.size(file));
+        System.out.println("content: " + new String(Files.readAllBytes(file), StandardCharsets.UTF_8));
+
+        try (FileChannel ch = FileChannel.open(file, StandardOpenOption.READ, StandardOpenOption.WRITE)) {
+            ByteBuffer buf = ByteBuffer.allocateDirect(5);
+            ch.read(buf, 7);
+            buf.flip();
+            System.out.println("pread: " + StandardCharsets.UTF_8.decode(buf));
+
+            ch.position(ch.size());
+            ch.write(ByteBuffer.wrap("!".getBytes(StandardCharsets.UTF_8)));
+            MappedByteBuffer mapped = ch.map(FileChannel.MapMode.READ_ONLY, 0, ch.size());
+            System.out.println("mapped: " + StandardCharsets.UTF_8.decode(mapped));
+        }
+
+        try (DirectoryStream<Path> entries = Files.newDirectoryStream(dir)) {
+            for (Path entry : entries) {
+                System.out.println("entry: " + entry.getFileName());
+            }
+        }
+
+        Files.delete(file);
+        try {
+            Files.readAllBytes(file);
+        } catch (NoSuchFileException e) {
+            System.out.println("deleted: " + e.getMessage());
+        }
+        Files.delete(dir);
+    }
+
+}
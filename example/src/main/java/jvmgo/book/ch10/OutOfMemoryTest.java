@@ -0,0 +1,34 @@
+package jvmgo.book.ch10;
+
+// jvmgo -Xmx 32m jvmgo.book.ch10.OutOfMemoryTest
+public class OutOfMemoryTest {
+
+    public static void main(String[] args) {
+        Runtime rt = Runtime.getRuntime();
+        System.out.println("max: " + rt.maxMemory());
+
+        try {
+            int[] huge = new int[Integer.MAX_VALUE];
+            System.out.println(huge.length);
+        } catch (OutOfMemoryError e) {
+            System.out.println("caught: " + e.getMessage());
+        }
+
+        Object[] chain = null;
+        try {
+            while (true) {
+                Object[] next = new Object[1024];
+                next[0] = chain;
+                chain = next;
+            }
+        } catch (OutOfMemoryError e) {
+            chain = null;
+            System.out.println("runaway allocation stopped");
+        }
+
+        rt.gc();
+        System.out.println("total: " + rt.totalMemory());
+        System.out.println("free: " + rt.freeMemory());
+    }
+
+}
@@ -0,0 +1,48 @@
+package jvmgo.book.ch10;
+
+import java.lang.ref.PhantomReference;
+import java.lang.ref.Reference;
+import java.lang.ref.ReferenceQueue;
+import java.lang.ref.SoftReference;
+import java.lang.ref.WeakReference;
+
+// jvmgo -Xmx 32m jvmgo.book.ch10.ReferenceTest
+public class ReferenceTest {
+
+    public static void main(String[] args) throws InterruptedException {
+        ReferenceQueue<Object> queue = new ReferenceQueue<>();
+        Object strong = new Object();
+        WeakReference<Object> weak = new WeakReference<>(new Object(), queue);
+        WeakReference<Object> kept = new WeakReference<>(strong, queue);
+        PhantomReference<Object> phantom = new PhantomReference<>(new Object(), queue);
+        SoftReference<Object> soft = new SoftReference<>(new Object());
+
+        System.gc();
+        System.out.println("weak cleared: " + (weak.get() == null));
+        System.out.println("kept alive: " + (kept.get() == strong));
+        System.out.println("phantom get: " + phantom.get());
+        System.out.println("soft alive: " + (soft.get() != null));
+
+        int enqueued = 0;
+        Reference<?> ref;
+        while ((ref = queue.remove(1000)) != null) {
+            System.out.println("enqueued: " + (ref == weak ? "weak" : ref == phantom ? "phantom" : "?"));
+            if (++enqueued == 2) {
+                break;
+            }
+        }
+
+        // 内存不足时回收软引用
+        try {
+            Object[] chain = null;
+            while (true) {
+                Object[] next = new Object[1024];
+                next[0] = chain;
+                chain = next;
+            }
+        } catch (OutOfMemoryError e) {
+            System.out.println("soft cleared: " + (soft.get() == null));
+        }
+    }
+
+}
@@ -0,0 +1,46 @@
+package jvmgo.book.ch10;
+
+import sun.misc.Signal;
+import sun.misc.SignalHandler;
+
+// 也可以去掉raise(TERM) 然后按Ctrl-C 关闭钩子同样会执行
+// jvmgo jvmgo.book.ch10.SignalTest
+public class SignalTest {
+
+    private static final Object lock = new Object();
+    private static boolean handled;
+
+    public static void main(String[] args) throws Exception {
+        Runtime.getRuntime().addShutdownHook(new Thread() {
+            @Override
+            public void run() {
+                System.out.println("shutdown hook: " + Thread.currentThread().getName());
+            }
+        });
+
+        Signal.handle(new Signal("USR2"), new SignalHandler() {
+            @Override
+            public void handle(Signal sig) {
+                System.out.println("handled SIG" + sig.getName() + " in " + Thread.currentThread().getName());
+                synchronized (lock) {
+                    handled = true;
+                    lock.notifyAll();
+                }
+            }
+        });
+        Signal.raise(new Signal("USR2"));
+        synchronized (lock) {
+            while (!handled) {
+                lock.wait();
+            }
+        }
+
+        // Terminator的处理器调用System.exit(128 + 15)
+        Signal.raise(new Signal("TERM"));
+        synchronized (lock) {
+            lock.wait();
+        }
+        System.out.println("unreachable");
+    }
+
+}
@@ -0,0 +1,50 @@
+package jvmgo.book.ch10;
+
+import java.io.BufferedReader;
+import java.io.InputStreamReader;
+import java.io.PrintWriter;
+import java.net.InetAddress;
+import java.net.ServerSocket;
+import java.net.Socket;
+import java.net.SocketTimeoutException;
+
+// 客户端和服务端在同一个线程里 连接先进入监听队列 再由accept取出
+// jvmgo jvmgo.book.ch10.SocketTest
+public class SocketTest {
+
+    public static void main(String[] args) throws Exception {
+        InetAddress loopback = InetAddress.getByName("127.0.0.1");
+        try (ServerSocket server = new ServerSocket(0, 50, loopback);
+             Socket client = new Socket(loopback, server.getLocalPort());
+             Socket conn = server.accept()) {
+            System.out.println("connected: " + conn.getInetAddress() + " -> " + client.getLocalAddress());
+
+            PrintWriter clientOut = new PrintWriter(client.getOutputStream(), true);
+            BufferedReader clientIn = new BufferedReader(new InputStreamReader(client.getInputStream(), "UTF-8"));
+            PrintWriter serverOut = new PrintWriter(conn.getOutputStream(), true);
+            BufferedReader serverIn = new BufferedReader(new InputStreamReader(conn.getInputStream(), "UTF-8"));
+
+            clientOut.println("hello");
+            serverOut.println("echo: " + serverIn.readLine());
+            System.out.println(clientIn.readLine());
+
+            client.setSoTimeout(100);
+            try {
+                clientIn.readLine();
+            } catch (SocketTimeoutException e) {
+                System.out.println("timeout: " + e.getMessage());
+            }
+
+            client.shutdownOutput();
+            System.out.println("eof: " + serverIn.readLine());
+
+            server.setSoTimeout(100);
+            try {
+                server.accept();
+            } catch (SocketTimeoutException e) {
+                System.out.println("timeout: " + e.getMessage());
+            }
+        }
+    }
+
+}
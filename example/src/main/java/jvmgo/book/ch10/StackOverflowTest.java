@@ -0,0 +1,27 @@
+package jvmgo.book.ch10;
+
+// jvmgo -Xss 256 jvmgo.book.ch10.StackOverflowTest
+// jvmgo -Xss 64k jvmgo.book.ch10.StackOverflowTest
+public class StackOverflowTest {
+
+    private static int depth;
+
+    public static void main(String[] args) {
+        for (int i = 0; i < 3; i++) {
+            depth = 0;
+            try {
+                recurse();
+            } catch (StackOverflowError e) {
+                System.out.println("caught StackOverflowError at depth " + depth);
+                System.out.println("trace length: " + e.getStackTrace().length);
+            }
+        }
+        recurse(); // uncaught
+    }
+
+    private static void recurse() {
+        depth++;
+        recurse();
+    }
+
+}
@@ -0,0 +1,24 @@
+package jvmgo.book.ch10;
+
+public class UncaughtExceptionTest {
+
+    public static void main(String[] args) {
+        try {
+            parse("x");
+        } catch (IllegalStateException e) {
+            e.printStackTrace();
+        }
+        parse("y");
+    }
+
+    private static void parse(String s) {
+        try {
+            Integer.parseInt(s);
+        } catch (NumberFormatException e) {
+            IllegalStateException ise = new IllegalStateException("cannot parse " + s, e);
+            ise.addSuppressed(new RuntimeException("suppressed"));
+            throw ise;
+        }
+    }
+
+}
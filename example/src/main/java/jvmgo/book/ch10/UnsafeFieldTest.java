@@ -0,0 +1,65 @@
+package jvmgo.book.ch10;
+
+import java.lang.reflect.Field;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.AtomicReferenceArray;
+import sun.misc.Unsafe;
+
+// jvmgo jvmgo.book.ch10.UnsafeFieldTest
+public class UnsafeFieldTest {
+
+    private boolean z;
+    private byte b;
+    private short s;
+    private char c;
+    private int i;
+    private long j;
+    private float f;
+    private double d;
+    private Object o;
+
+    public static void main(String[] args) throws Exception {
+        Field theUnsafe = Unsafe.class.getDeclaredField("theUnsafe");
+        theUnsafe.setAccessible(true);
+        Unsafe unsafe = (Unsafe) theUnsafe.get(null);
+
+        UnsafeFieldTest t = new UnsafeFieldTest();
+        unsafe.putBoolean(t, offset(unsafe, "z"), true);
+        unsafe.putByte(t, offset(unsafe, "b"), (byte) -2);
+        unsafe.putShort(t, offset(unsafe, "s"), (short) -3);
+        unsafe.putChar(t, offset(unsafe, "c"), 'x');
+        unsafe.putIntVolatile(t, offset(unsafe, "i"), 42);
+        unsafe.putOrderedLong(t, offset(unsafe, "j"), 1L << 40);
+        unsafe.putFloat(t, offset(unsafe, "f"), 1.5f);
+        unsafe.putDoubleVolatile(t, offset(unsafe, "d"), 2.5);
+        unsafe.putOrderedObject(t, offset(unsafe, "o"), "hello");
+        System.out.println(t.z + " " + t.b + " " + t.s + " " + t.c + " " + t.i + " "
+                + t.j + " " + t.f + " " + t.d + " " + t.o);
+        System.out.println(unsafe.getByte(t, offset(unsafe, "b")) + " "
+                + unsafe.getLongVolatile(t, offset(unsafe, "j")) + " "
+                + unsafe.getObject(t, offset(unsafe, "o")));
+
+        // 反射的Field.get/set走UnsafeFieldAccessorImpl
+        Field fj = UnsafeFieldTest.class.getDeclaredField("j");
+        fj.setLong(t, 7);
+        Field fo = UnsafeFieldTest.class.getDeclaredField("o");
+        fo.set(t, "world");
+        System.out.println(fj.getLong(t) + " " + fo.get(t));
+
+        long[] longs = new long[4];
+        unsafe.putLong(longs, (long) Unsafe.ARRAY_LONG_BASE_OFFSET + 2 * Unsafe.ARRAY_LONG_INDEX_SCALE, 99);
+        System.out.println(longs[2]);
+
+        AtomicLong counter = new AtomicLong();
+        counter.lazySet(5);
+        System.out.println(counter.incrementAndGet());
+        AtomicReferenceArray<String> refs = new AtomicReferenceArray<String>(3);
+        refs.lazySet(1, "lazy");
+        System.out.println(refs.get(1));
+    }
+
+    private static long offset(Unsafe unsafe, String name) throws Exception {
+        return unsafe.objectFieldOffset(UnsafeFieldTest.class.getDeclaredField(name));
+    }
+
+}
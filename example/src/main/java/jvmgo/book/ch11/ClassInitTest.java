@@ -0,0 +1,61 @@
+package jvmgo.book.ch11;
+
+// jvmgo jvmgo.book.ch11.ClassInitTest
+public class ClassInitTest {
+
+    static StringBuilder log = new StringBuilder();
+
+    static class Broken {
+        static int value = 1 / zero();
+    }
+
+    static int zero() {
+        return 0;
+    }
+
+    interface WithDefault {
+        int A = mark("WithDefault");
+
+        default void hello() {
+        }
+    }
+
+    interface WithoutDefault {
+        int B = mark("WithoutDefault");
+    }
+
+    static class Base {
+        static {
+            mark("Base");
+        }
+    }
+
+    static class Impl extends Base implements WithoutDefault, WithDefault {
+        static {
+            mark("Impl");
+        }
+    }
+
+    static int mark(String name) {
+        log.append(name).append(' ');
+        return 0;
+    }
+
+    public static void main(String[] args) {
+        try {
+            System.out.println(Broken.value);
+        } catch (ExceptionInInitializerError e) {
+            System.out.println("first: " + e.getCause());
+        }
+        try {
+            System.out.println(Broken.value);
+        } catch (NoClassDefFoundError e) {
+            System.out.println("retry: " + e.getMessage());
+        }
+
+        // 只有声明了default方法的超接口会被初始化 顺序是超类、超接口、类本身
+        new Impl();
+        System.out.println(log.toString().trim());
+    }
+
+}
@@ -0,0 +1,48 @@
+package jvmgo.book.ch11;
+
+// jvmgo -verbose:class jvmgo.book.ch11.ClassLoaderTest
+public class ClassLoaderTest {
+
+    static class PluginLoader extends ClassLoader {
+
+        private final String message;
+
+        PluginLoader(String message) {
+            super(null); // 父加载器是启动类加载器
+            this.message = message;
+        }
+
+        @Override
+        protected Class<?> findClass(String name) throws ClassNotFoundException {
+            if (!name.equals("plugin.Greeter")) {
+                throw new ClassNotFoundException(name);
+            }
+            byte[] bytes = GreeterBytes.generate(name, message);
+            return defineClass(name, bytes, 0, bytes.length);
+        }
+
+    }
+
+    public static void main(String[] args) throws Exception {
+        PluginLoader loader1 = new PluginLoader("hello from loader1");
+        PluginLoader loader2 = new PluginLoader("hello from loader2");
+
+        Class<?> c1 = loader1.loadClass("plugin.Greeter");
+        Class<?> c2 = loader2.loadClass("plugin.Greeter");
+        System.out.println(c1.getName() + " == " + c2.getName() + ": " + (c1 == c2));
+        System.out.println(c1.getClassLoader() == loader1);
+        System.out.println(String.class.getClassLoader());
+        System.out.println(loader1.loadClass("plugin.Greeter") == c1); // findLoadedClass
+        System.out.println(loader1.loadClass("java.lang.String") == String.class);
+
+        System.out.println(c1.newInstance());
+        System.out.println(Class.forName("plugin.Greeter", true, loader2).newInstance());
+
+        try {
+            loader1.loadClass("plugin.Missing");
+        } catch (ClassNotFoundException e) {
+            System.out.println("not found: " + e.getMessage());
+        }
+    }
+
+}
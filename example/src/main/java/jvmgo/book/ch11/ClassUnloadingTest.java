@@ -0,0 +1,57 @@
+package jvmgo.book.ch11;
+
+import java.lang.ref.WeakReference;
+
+// jvmgo -verbose:class jvmgo.book.ch11.ClassUnloadingTest
+public class ClassUnloadingTest {
+
+    static class PluginLoader extends ClassLoader {
+
+        PluginLoader() {
+            super(null);
+        }
+
+        @Override
+        protected Class<?> findClass(String name) throws ClassNotFoundException {
+            byte[] bytes = GreeterBytes.generate(name, "hello from " + name);
+            return defineClass(name, bytes, 0, bytes.length);
+        }
+
+    }
+
+    public static void main(String[] args) throws Exception {
+        int rounds = 100;
+        int threshold = 90; // 个别加载器可能还没来得及回收
+        WeakReference<?>[] refs = new WeakReference<?>[rounds];
+        for (int i = 0; i < rounds; i++) {
+            refs[i] = loadAndDrop("plugin.Greeter");
+        }
+
+        int unloaded = 0;
+        for (int attempt = 0; attempt < 5 && unloaded < threshold; attempt++) {
+            System.gc();
+            unloaded = 0;
+            for (WeakReference<?> ref : refs) {
+                if (ref.get() == null) {
+                    unloaded++;
+                }
+            }
+        }
+        if (unloaded < threshold) {
+            throw new AssertionError("only " + unloaded + " of " + rounds + " classes unloaded");
+        }
+        System.out.println("class unloading: OK");
+    }
+
+    // 加载器和类只在这个栈帧里被引用 返回之后就不可达了
+    private static WeakReference<Class<?>> loadAndDrop(String name) throws Exception {
+        PluginLoader loader = new PluginLoader();
+        Class<?> c = loader.loadClass(name);
+        Object greeter = c.newInstance();
+        if (!greeter.toString().equals("hello from " + name)) {
+            throw new AssertionError(greeter);
+        }
+        return new WeakReference<Class<?>>(c);
+    }
+
+}
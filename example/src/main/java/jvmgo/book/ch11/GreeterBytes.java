@@ -0,0 +1,93 @@
+package jvmgo.book.ch11;
+
+import java.io.ByteArrayOutputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+
+// 生成一个最简单的类文件:
+// public class <name> {
+//     public <name>() { super(); }
+//     public String toString() { return "<message>"; }
+// }
+public class GreeterBytes {
+
+    public static byte[] generate(String name, String message) {
+        try {
+            ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+            DataOutputStream out = new DataOutputStream(bytes);
+            out.writeInt(0xCAFEBABE);
+            out.writeShort(0);  // minor_version
+            out.writeShort(50); // major_version 不需要StackMapTable
+
+            out.writeShort(14); // constant_pool_count
+            utf8(out, name.replace('.', '/'));  // #1
+            classInfo(out, 1);                  // #2
+            utf8(out, "java/lang/Object");      // #3
+            classInfo(out, 3);                  // #4
+            utf8(out, "<init>");                // #5
+            utf8(out, "()V");                   // #6
+            out.writeByte(12);                  // #7 NameAndType
+            out.writeShort(5);
+            out.writeShort(6);
+            out.writeByte(10);                  // #8 Methodref Object.<init>
+            out.writeShort(4);
+            out.writeShort(7);
+            utf8(out, "Code");                  // #9
+            utf8(out, "toString");              // #10
+            utf8(out, "()Ljava/lang/String;");  // #11
+            utf8(out, message);                 // #12
+            out.writeByte(8);                   // #13 String
+            out.writeShort(12);
+
+            out.writeShort(0x0021); // ACC_PUBLIC | ACC_SUPER
+            out.writeShort(2);      // this_class
+            out.writeShort(4);      // super_class
+            out.writeShort(0);      // interfaces_count
+            out.writeShort(0);      // fields_count
+
+            out.writeShort(2); // methods_count
+            method(out, 5, 6, 1, 1, new byte[]{
+                0x2a,                   // aload_0
+                (byte) 0xb7, 0x00, 0x08, // invokespecial #8
+                (byte) 0xb1,            // return
+            });
+            method(out, 10, 11, 1, 1, new byte[]{
+                0x12, 0x0d,   // ldc #13
+                (byte) 0xb0, // areturn
+            });
+
+            out.writeShort(0); // attributes_count
+            out.flush();
+            return bytes.toByteArray();
+        } catch (IOException e) {
+            throw new RuntimeException(e);
+        }
+    }
+
+    private static void utf8(DataOutputStream out, String s) throws IOException {
+        out.writeByte(1);
+        out.writeUTF(s);
+    }
+
+    private static void classInfo(DataOutputStream out, int nameIndex) throws IOException {
+        out.writeByte(7);
+        out.writeShort(nameIndex);
+    }
+
+    private static void method(DataOutputStream out, int name, int descriptor,
+                               int maxStack, int maxLocals, byte[] code) throws IOException {
+        out.writeShort(0x0001); // ACC_PUBLIC
+        out.writeShort(name);
+        out.writeShort(descriptor);
+        out.writeShort(1); // attributes_count
+        out.writeShort(9); // Code
+        out.writeInt(12 + code.length);
+        out.writeShort(maxStack);
+        out.writeShort(maxLocals);
+        out.writeInt(code.length);
+        out.write(code);
+        out.writeShort(0); // exception_table_length
+        out.writeShort(0); // attributes_count
+    }
+
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"jvm/instructions/base"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+)
+
+// 虚拟机内部的Finalizer线程 逐个执行不可达对象的finalize()
+// finalize()抛出的异常被忽略
+func (self *JVM) startFinalizer() {
+	thread := rtda.NewThread()
+	thread.SetJThread(self.newJThread("Finalizer", self.systemGroup, 8, true)) // Thread.MAX_PRIORITY - 2
+	go func() {
+		vmHeap := self.classLoader.Heap()
+		for {
+			obj := vmHeap.WaitFinalizable()
+			thread.Acquire()
+			runFinalizer(thread, obj)
+			thread.Release()
+			vmHeap.Finalized()
+		}
+	}()
+}
+
+func runFinalizer(thread *rtda.Thread, obj *heap.Object) {
+	method := heap.LookupMethodInClass(obj.Class(), "finalize", "()V")
+	base.InvokeAndWait(thread, method, []rtda.Slot{rtda.RefSlot(obj)})
+}
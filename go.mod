@@ -1,3 +1,3 @@
 module jvm
 
-go 1.19
+go 1.24
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 和HotSpot一样 默认写到当前目录下的java_pid<pid>.hprof
+// -XX:HeapDumpPath是目录时写到这个目录下
+func defaultHeapDumpPath(dumpPath string) string {
+	name := fmt.Sprintf("java_pid%d.hprof", os.Getpid())
+	if dumpPath == "" {
+		return name
+	}
+	if info, err := os.Stat(dumpPath); err == nil && info.IsDir() {
+		return filepath.Join(dumpPath, name)
+	}
+	return dumpPath
+}
+
+// 把所有可达的对象以HPROF格式写到path 已经存在的文件不会被覆盖
+// 调用者必须持有全局解释器锁 返回和HotSpot一样的提示信息
+func dumpHeap(loader *heap.ClassLoader, path string) (string, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	start := time.Now()
+	if err := loader.DumpHeap(file, heapRoots()); err != nil {
+		return "", err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Heap dump file created [%d bytes in %.3f secs]\n",
+		info.Size(), time.Since(start).Seconds()), nil
+}
+
+// 所有线程栈上的GC根
+func heapRoots() []heap.ThreadRoots {
+	var threads []heap.ThreadRoots
+	for _, thread := range rtda.AllThreads() {
+		threads = append(threads, thread.HeapRoots())
+	}
+	return threads
+}
+
+// -XX:+HeapDumpOnOutOfMemoryError 只在第一次抛出OutOfMemoryError时转储
+// 抛出的线程正在分配对象 已经持有全局解释器锁
+func (self *JVM) enableHeapDumpOnOutOfMemory() {
+	self.classLoader.Heap().OnOutOfMemory(func() {
+		path := defaultHeapDumpPath(self.cmd.heapDumpPath)
+		fmt.Printf("java.lang.OutOfMemoryError: Java heap space\nDumping heap to %s ...\n", path)
+		if msg, err := dumpHeap(self.classLoader, path); err != nil {
+			fmt.Printf("Unable to create %s: %v\n", path, err)
+		} else {
+			fmt.Print(msg)
+		}
+	})
+}
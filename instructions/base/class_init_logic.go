@@ -5,30 +5,95 @@ import (
 	"jvm/rtda/heap"
 )
 
-// 初始化类
-// 标志已经初始化
-// 构造函数压栈
-// 递归构造父类
+// 同步初始化类 失败时以panic形式抛出异常 由解释器转换成Java异常
+// 指令执行到一半时调用 初始化完成后指令可以继续执行
 func InitClass(thread *rtda.Thread, class *heap.Class) {
-	class.StartInit()
-	scheduleClinit(thread, class)
-	initSuperClass(thread, class)
+	if thrown := InitClassAndWait(thread, class); thrown != nil {
+		panic(heap.NewThrownException(thrown))
+	}
 }
 
-func scheduleClinit(thread *rtda.Thread, class *heap.Class) {
-	clinit := class.GetClinitMethod()
-	if clinit != nil && clinit.Class() == class {
-		// exec <clinit>
-		newFrame := thread.NewFrame(clinit)
-		thread.PushFrame(newFrame)
+// JVMS 5.5 初始化过程 返回初始化失败时应该抛出的异常
+// 1. 其他线程正在初始化 释放全局解释器锁等待它完成
+// 2. 当前线程正在初始化(递归请求)或者已经初始化完毕 直接返回
+// 3. 之前初始化失败过 抛出NoClassDefFoundError
+// 4. 先初始化超类 以及声明了default方法的超接口 再执行<clinit>
+// 5. <clinit>抛出的异常如果不是Error 包装成ExceptionInInitializerError
+func InitClassAndWait(thread *rtda.Thread, class *heap.Class) *heap.Object {
+	for class.InitState() == heap.CLASS_BEING_INITIALIZED && class.InitThread() != thread {
+		done := class.InitDone()
+		thread.Blocking(func() {
+			<-done
+		})
+	}
+
+	switch class.InitState() {
+	case heap.CLASS_INITIALIZED, heap.CLASS_BEING_INITIALIZED:
+		return nil
+	case heap.CLASS_ERRONEOUS:
+		return NewThrowable(thread, "java/lang/NoClassDefFoundError",
+			"Could not initialize class "+class.JavaName())
+	}
+
+	class.StartInit(thread)
+	thrown := initSupers(thread, class)
+	if thrown == nil {
+		if thrown = runClinit(thread, class); thrown != nil {
+			thrown = wrapInitError(thread, thrown)
+		}
+	}
+	if thrown != nil {
+		class.FailInit()
+	} else {
+		class.FinishInit()
 	}
+	return thrown
 }
 
-func initSuperClass(thread *rtda.Thread, class *heap.Class) {
-	if !class.IsInterface() { // not a interface
-		superClass := class.SuperClass()
-		if superClass != nil && !superClass.InitStarted() {
-			InitClass(thread, superClass)
+// 接口初始化时不需要初始化它的超接口
+func initSupers(thread *rtda.Thread, class *heap.Class) *heap.Object {
+	if class.IsInterface() {
+		return nil
+	}
+	if superClass := class.SuperClass(); superClass != nil {
+		if thrown := InitClassAndWait(thread, superClass); thrown != nil {
+			return thrown
 		}
 	}
+	for _, iface := range class.Interfaces() {
+		if thrown := initSuperInterfaces(thread, iface); thrown != nil {
+			return thrown
+		}
+	}
+	return nil
+}
+
+// 按照接口表的顺序递归 先超接口后接口本身
+func initSuperInterfaces(thread *rtda.Thread, iface *heap.Class) *heap.Object {
+	for _, superIface := range iface.Interfaces() {
+		if thrown := initSuperInterfaces(thread, superIface); thrown != nil {
+			return thrown
+		}
+	}
+	if iface.DeclaresDefaultMethods() {
+		return InitClassAndWait(thread, iface)
+	}
+	return nil
+}
+
+func runClinit(thread *rtda.Thread, class *heap.Class) *heap.Object {
+	clinit := class.GetClinitMethod()
+	if clinit == nil || clinit.Class() != class {
+		return nil
+	}
+	_, thrown := InvokeAndWait(thread, clinit, nil)
+	return thrown
+}
+
+func wrapInitError(thread *rtda.Thread, thrown *heap.Object) *heap.Object {
+	errorClass := thrown.Class().Loader().Bootstrap().LoadClass("java/lang/Error")
+	if thrown.IsInstanceOf(errorClass) {
+		return thrown
+	}
+	return NewThrowableWithCause(thread, "java/lang/ExceptionInInitializerError", thrown)
 }
@@ -0,0 +1,36 @@
+package base
+
+import (
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"strings"
+)
+
+func init() {
+	heap.SetUserClassLoading(loadClassByJava)
+}
+
+// 用户类加载器加载类时 调用它的loadClass(String)方法 双亲委派由Java代码完成
+// 这时没有可用的栈帧 在持有全局解释器锁的线程上同步执行
+func loadClassByJava(loader *heap.ClassLoader, name string) *heap.Class {
+	jLoader := loader.JLoader()
+	method := heap.LookupMethodInClass(jLoader.Class(), "loadClass",
+		"(Ljava/lang/String;)Ljava/lang/Class;")
+	jName := heap.JString(loader, strings.Replace(name, "/", ".", -1))
+	args := []rtda.Slot{rtda.RefSlot(jLoader), rtda.RefSlot(jName)}
+	result, thrown := InvokeAndWait(rtda.CurrentThread(), method, args)
+	if thrown != nil {
+		panic(heap.NewThrownException(thrown))
+	}
+
+	jClass := result.Ref()
+	if jClass == nil {
+		panic(heap.NewJavaException("java/lang/NoClassDefFoundError", name))
+	}
+	class := jClass.Extra().(*heap.Class)
+	if class.Name() != name {
+		panic(heap.NewJavaException("java/lang/NoClassDefFoundError",
+			name+" (wrong name: "+class.Name()+")"))
+	}
+	return class
+}
@@ -0,0 +1,80 @@
+package base
+
+import (
+	"jvm/rtda"
+	"jvm/rtda/heap"
+)
+
+// 在Go代码中抛出Java异常
+// 异常对象构造完毕后 压入athrow过渡帧把它抛出去
+func ThrowException(thread *rtda.Thread, className, msg string) {
+	Rethrow(thread, NewThrowable(thread, className, msg))
+}
+
+// 由虚拟机直接分配异常对象 然后同步执行构造函数
+// 类初始化或者构造函数本身抛出异常时 返回那个异常
+func NewThrowable(thread *rtda.Thread, className, msg string) *heap.Object {
+	loader := findLoader(thread)
+	if msg == "" {
+		return newThrowable(thread, loader, className, "()V", nil)
+	}
+	return newThrowable(thread, loader, className, "(Ljava/lang/String;)V",
+		[]rtda.Slot{rtda.RefSlot(heap.JString(loader, msg))})
+}
+
+// 比如ExceptionInInitializerError
+func NewThrowableWithCause(thread *rtda.Thread, className string, cause *heap.Object) *heap.Object {
+	return newThrowable(thread, findLoader(thread), className, "(Ljava/lang/Throwable;)V",
+		[]rtda.Slot{rtda.RefSlot(cause)})
+}
+
+func newThrowable(thread *rtda.Thread, loader *heap.ClassLoader,
+	className, descriptor string, args []rtda.Slot) *heap.Object {
+
+	exClass := loader.LoadClass(className)
+	if thrown := InitClassAndWait(thread, exClass); thrown != nil {
+		return thrown
+	}
+	ex := exClass.NewObject()
+	constructor := exClass.GetConstructor(descriptor)
+	if _, thrown := InvokeAndWait(thread, constructor, append([]rtda.Slot{rtda.RefSlot(ex)}, args...)); thrown != nil {
+		return thrown
+	}
+	return ex
+}
+
+// 栈溢出时抛出的异常 栈已经进入保留区 构造函数在保留区里执行
+// 保留区也用完时不能再执行构造函数 和HotSpot预先分配的StackOverflowError一样 直接分配一个没有调用栈的对象
+func newStackOverflowError(thread *rtda.Thread) *heap.Object {
+	if !thread.StackExhausted() {
+		return NewThrowable(thread, "java/lang/StackOverflowError", "")
+	}
+	loader := findLoader(thread)
+	ex := loader.LoadClass("java/lang/StackOverflowError").NewObject()
+	ex.SetRefVar("cause", "Ljava/lang/Throwable;", ex) // 和Throwable的构造函数一样 cause是自己表示还没有设置
+	ex.SetRefVar("stackTrace", "[Ljava/lang/StackTraceElement;",
+		loader.LoadClass("[Ljava/lang/StackTraceElement;").NewArray(0))
+	return ex
+}
+
+// 压入Go代码构造的过渡帧 和InvokeMethod一样检查栈的大小
+// 会导致栈溢出时改为抛出StackOverflowError 并返回false
+func PushShimFrame(thread *rtda.Thread, frame *rtda.Frame) bool {
+	if thread.StackOverflow(frame) {
+		Rethrow(thread, newStackOverflowError(thread))
+		return false
+	}
+	thread.PushFrame(frame)
+	return true
+}
+
+// 过渡帧所属的类没有类加载器 需要跳过
+// 异常类都在java包里 直接用启动类加载器 不经过用户类加载器
+func findLoader(thread *rtda.Thread) *heap.ClassLoader {
+	for _, frame := range thread.GetFrames() {
+		if loader := frame.Method().Class().Loader(); loader != nil {
+			return loader.Bootstrap()
+		}
+	}
+	return thread.JThread().Class().Loader()
+}
@@ -1,12 +1,22 @@
 package base
 
-import "jvm/rtda"
+import (
+	"jvm/rtda"
+	"jvm/rtda/heap"
+)
 
 type Instruction interface {
 	FetchOperands(reader *BytecodeReader)
 	Execute(frame *rtda.Frame)
 }
 
+// 符号引用解析成功以后 可以换成直接持有解析结果的快速版本(类似HotSpot的bytecode rewriter)
+// method是指令所在的方法 还不能换(比如类还没有初始化完)时返回nil
+type QuickenableInstruction interface {
+	Instruction
+	Quicken(method *heap.Method) Instruction
+}
+
 type NoOperandsInstruction struct {
 	// empty
 }
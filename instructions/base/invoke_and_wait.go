@@ -0,0 +1,79 @@
+package base
+
+import (
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"strings"
+)
+
+// 解释器循环在main包里 由main包注入 避免base依赖instructions
+// 执行thread的字节码 直到barrier成为栈顶
+var runUntil func(thread *rtda.Thread, barrier *rtda.Frame)
+
+func SetInterpreter(fn func(thread *rtda.Thread, barrier *rtda.Frame)) {
+	runUntil = fn
+}
+
+// 在Go代码中同步调用Java方法 可以重入
+// 先压入过渡帧作为栈底 然后在嵌套的解释器循环里执行被调方法 直到它返回或者抛出异常
+// args按局部变量表的布局给出 实例方法的第一个参数是this
+// 返回long或double的方法必须用InvokeAndWaitWide 否则panic 以免只拿到一半的返回值
+func InvokeAndWait(thread *rtda.Thread, method *heap.Method, args []rtda.Slot) (result rtda.Slot, thrown *heap.Object) {
+	if returnsWide(method) {
+		panic("InvokeAndWait: use InvokeAndWaitWide for " + method.Name() + method.Descriptor())
+	}
+	ops, thrown := invokeAndWait(thread, method, args)
+	if thrown == nil && !strings.HasSuffix(method.Descriptor(), ")V") {
+		result = ops.PopSlot()
+	}
+	return
+}
+
+// 返回long或double的方法 double返回的是Float64bits
+func InvokeAndWaitWide(thread *rtda.Thread, method *heap.Method, args []rtda.Slot) (result int64, thrown *heap.Object) {
+	if !returnsWide(method) {
+		panic("InvokeAndWaitWide: use InvokeAndWait for " + method.Name() + method.Descriptor())
+	}
+	ops, thrown := invokeAndWait(thread, method, args)
+	if thrown == nil {
+		result = ops.PopLong()
+	}
+	return
+}
+
+func returnsWide(method *heap.Method) bool {
+	descriptor := method.Descriptor()
+	return strings.HasSuffix(descriptor, ")J") || strings.HasSuffix(descriptor, ")D")
+}
+
+// 静态方法所属的类还没有初始化时 先同步初始化
+// 过渡帧压不进去时 StackOverflowError作为被调方法抛出的异常返回
+func invokeAndWait(thread *rtda.Thread, method *heap.Method, args []rtda.Slot) (*rtda.OperandStack, *heap.Object) {
+	if method.IsStatic() && !method.Class().IsInitialized() {
+		if thrown := InitClassAndWait(thread, method.Class()); thrown != nil {
+			return nil, thrown
+		}
+	}
+
+	ops := rtda.NewOperandStack(uint(len(args)) + 2)
+	for _, arg := range args {
+		ops.PushSlot(arg)
+	}
+	barrier := rtda.NewCatchShimFrame(thread, ops)
+	if thread.StackOverflow(barrier) {
+		return nil, newStackOverflowError(thread)
+	}
+	thread.PushFrame(barrier)
+	InvokeMethod(barrier, method)
+
+	runUntil(thread, barrier)
+	thread.PopFrame() // barrier
+	return ops, thread.TakeCaughtException()
+}
+
+// 把Go代码拿到的异常对象从当前栈帧重新抛出去
+func Rethrow(thread *rtda.Thread, ex *heap.Object) {
+	ops := rtda.NewOperandStack(1)
+	ops.PushRef(ex)
+	thread.PushFrame(rtda.NewAthrowShimFrame(thread, ops))
+}
@@ -5,9 +5,21 @@ import (
 	"jvm/rtda/heap"
 )
 
+// JIT统计方法的调用次数 由main包注入 解释执行时为nil
+var countInvocation func(method *heap.Method)
+
+func SetInvocationCounter(fn func(method *heap.Method)) {
+	countInvocation = fn
+}
+
 func InvokeMethod(invokerFrame *rtda.Frame, method *heap.Method) {
 	thread := invokerFrame.Thread()
 	newFrame := thread.NewFrame(method) // 分配合适的栈帧空间
+	if thread.StackOverflow(newFrame) {
+		// 参数还留在调用者的操作数栈里 异常处理时会被清空
+		Rethrow(thread, newStackOverflowError(thread))
+		return
+	}
 	thread.PushFrame(newFrame)
 
 	argSlotCount := int(method.ArgSlotCount())
@@ -17,4 +29,18 @@ func InvokeMethod(invokerFrame *rtda.Frame, method *heap.Method) {
 			newFrame.LocalVars().SetSlot(uint(i), slot) // 参数拷贝
 		}
 	}
+
+	if method.IsSynchronized() {
+		// 实例方法锁this 静态方法锁类对象
+		lockedObj := method.Class().JClass()
+		if !method.IsStatic() {
+			lockedObj = newFrame.LocalVars().GetThis()
+		}
+		thread.EnterMonitor(lockedObj)
+		newFrame.SetLockedObject(lockedObj)
+	}
+
+	if countInvocation != nil && !method.IsNative() {
+		countInvocation(method)
+	}
 }
@@ -42,7 +42,7 @@ func _ldc(frame *rtda.Frame, index uint) {
 	case float32:
 		stack.PushFloat(c.(float32))
 	case string:
-		internedStr := heap.JString(class.Loader(), c.(string))
+		internedStr := heap.InternedJString(class.Loader(), c.(string))
 		stack.PushRef(internedStr)
 	case *heap.ClassRef:
 		classRef := c.(*heap.ClassRef) // 常量池的常量是类引用
@@ -0,0 +1,41 @@
+package instructions
+
+import (
+	"jvm/instructions/base"
+	"jvm/rtda/heap"
+)
+
+// 解码以后的指令和下一条指令的位置
+type decodedInstruction struct {
+	inst   base.Instruction
+	nextPC int
+}
+
+// 每条指令只解码一次 以后直接从方法的缓存里取
+// 快速版本的指令也只替换缓存 字节码保持原样 -Xshare:dump归档的仍然是原始的code
+func Decode(method *heap.Method, pc int) (base.Instruction, int) {
+	decoded, _ := method.DecodedCode().([]decodedInstruction)
+	if decoded == nil {
+		decoded = make([]decodedInstruction, len(method.Code()))
+		method.SetDecodedCode(decoded)
+	}
+	if d := decoded[pc]; d.inst != nil {
+		return d.inst, d.nextPC
+	}
+
+	reader := &base.BytecodeReader{}
+	reader.Reset(method.Code(), pc)
+	inst := NewInstruction(reader.ReadUint8())
+	inst.FetchOperands(reader)
+	decoded[pc] = decodedInstruction{inst, reader.PC()}
+	return inst, reader.PC()
+}
+
+// 指令成功执行一次以后调用 这时符号引用已经解析好了
+func Quicken(method *heap.Method, pc int, inst base.Instruction) {
+	if q, ok := inst.(base.QuickenableInstruction); ok {
+		if quick := q.Quicken(method); quick != nil {
+			method.DecodedCode().([]decodedInstruction)[pc].inst = quick
+		}
+	}
+}
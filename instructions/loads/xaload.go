@@ -120,7 +120,7 @@ func (self *SALOAD) Execute(frame *rtda.Frame) {
 
 func checkNotNil(ref *heap.Object) {
 	if ref == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 }
 func checkIndex(arrLen int, index int32) {
@@ -55,10 +55,10 @@ type LSHL struct {
 func (self *LSHL) Execute(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	v2 := stack.PopInt()
-	v1 := stack.PopInt()
+	v1 := stack.PopLong()
 	s := uint32(v2) & 0x3f
 	result := v1 << s
-	stack.PushInt(result)
+	stack.PushLong(result)
 }
 
 // Shift Right Long
@@ -69,10 +69,10 @@ type LSHR struct {
 func (self *LSHR) Execute(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	v2 := stack.PopInt()
-	v1 := stack.PopInt()
+	v1 := stack.PopLong()
 	s := uint32(v2) & 0x3f
 	result := v1 >> s
-	stack.PushInt(result)
+	stack.PushLong(result)
 }
 
 // Long 算术右移 有符号右移
@@ -83,7 +83,7 @@ type LUSHR struct {
 func (self *LUSHR) Execute(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	v2 := stack.PopInt()
-	v1 := stack.PopInt()
+	v1 := stack.PopLong()
 	s := uint32(v2) & 0x3f // 0x0011 1111 2**6=64
 	result := int64(uint64(v1) >> s)
 	stack.PushLong(result)
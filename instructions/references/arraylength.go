@@ -3,6 +3,7 @@ package references
 import (
 	"jvm/instructions/base"
 	"jvm/rtda"
+	"jvm/rtda/heap"
 )
 
 type ARRAY_LENGTH struct {
@@ -13,7 +14,7 @@ func (self *ARRAY_LENGTH) Execute(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	arrRef := stack.PopRef()
 	if arrRef == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 
 	arrLen := arrRef.ArrayLength()
@@ -15,18 +15,24 @@ type ATHROW struct {
 func (self *ATHROW) Execute(frame *rtda.Frame) {
 	ex := frame.OperandStack().PopRef()
 	if ex == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 	thread := frame.Thread()
-	if !findAndGotoExceptionHandler(thread, ex) {
-		handleUncaughtException(thread, ex)
+	if handled, bottom := findAndGotoExceptionHandler(thread, ex); !handled {
+		handleUncaughtException(thread, ex, bottom)
 	}
 }
 
 // Find and goto the Exception handler
-func findAndGotoExceptionHandler(thread *rtda.Thread, ex *heap.Object) bool {
+// 找不到处理代码时 返回最后一个被弹出的栈帧
+func findAndGotoExceptionHandler(thread *rtda.Thread, ex *heap.Object) (bool, *rtda.Frame) {
 	for {
 		frame := thread.CurrentFrame()
+		if frame.Method() == heap.ShimCatchMethod() {
+			// 异常到此为止 交给base.InvokeAndWait返回给Go代码
+			thread.SetCaughtException(ex)
+			return true, nil
+		}
 		pc := frame.NextPC() - 1
 
 		handlerPC := frame.Method().FindExceptionHandler(ex.Class(), pc)
@@ -35,27 +41,61 @@ func findAndGotoExceptionHandler(thread *rtda.Thread, ex *heap.Object) bool {
 			stack.Clear()
 			stack.PushRef(ex)
 			frame.SetNextPC(handlerPC)
-			return true
+			return true, nil
 		}
 
 		thread.PopFrame()
 		if thread.IsStackEmpty() {
-			break
+			return false, frame
 		}
 	}
-	return false
 }
 
-// handle Uncaught Exception by printing its message
-func handleUncaughtException(thread *rtda.Thread, ex *heap.Object) {
-	thread.ClearStack() // 清空虚拟机栈 解释器终止执行
+// 和真正的虚拟机一样 把未捕获的异常交给Thread.dispatchUncaughtException()处理
+// 这样 Thread.UncaughtExceptionHandler 和 printStackTrace() 都能正常工作
+func handleUncaughtException(thread *rtda.Thread, ex *heap.Object, bottom *rtda.Frame) {
+	thread.ClearStack() // 清空虚拟机栈
+
+	jThread := thread.JThread()
+	if jThread == nil || isDispatchFrame(bottom) {
+		// 没有可用的Thread对象 或者dispatchUncaughtException()自身抛出了异常
+		printUncaughtException(ex)
+		return
+	}
+
+	dispatchMethod := jThread.Class().GetInstanceMethod("dispatchUncaughtException",
+		"(Ljava/lang/Throwable;)V")
+	if dispatchMethod == nil {
+		printUncaughtException(ex)
+		return
+	}
+
+	if base.InitClassAndWait(thread, dispatchMethod.Class()) != nil {
+		printUncaughtException(ex)
+		return
+	}
+
+	frame := thread.NewFrame(dispatchMethod)
+	frame.LocalVars().SetRef(0, jThread)
+	frame.LocalVars().SetRef(1, ex)
+	thread.PushFrame(frame)
+}
 
+func isDispatchFrame(frame *rtda.Frame) bool {
+	return frame != nil && frame.Method().Name() == "dispatchUncaughtException"
+}
+
+// handle Uncaught Exception by printing its message
+func printUncaughtException(ex *heap.Object) {
 	jMsg := ex.GetRefVar("detailMessage", "Ljava/lang/String;")
-	goMsg := heap.GoString(jMsg)
-	fmt.Printf("%s: %s\n", ex.Class().JavaName(), goMsg)
+	if jMsg != nil {
+		fmt.Printf("%s: %s\n", ex.Class().JavaName(), heap.GoString(jMsg))
+	} else {
+		fmt.Println(ex.Class().JavaName())
+	}
 
 	stes := reflect.ValueOf(ex.Extra()) // 异常对象的extra存放jvm栈信息
-	for i := 0;i < stes.Len();i++ {
+	for i := 0; stes.IsValid() && i < stes.Len(); i++ {
 		ste := stes.Index(i).Interface().(interface {String() string})
 		fmt.Println("\tat", ste.String())
 	}
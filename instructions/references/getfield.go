@@ -22,25 +22,88 @@ func (self *GET_FIELD) Execute(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	ref := stack.PopRef()
 	if ref == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 
 	descriptor := field.Descriptor()
 	slotId := field.SlotId()
-	slots := ref.Fields()
 
 	switch descriptor[0] {
 	case 'Z', 'B', 'C', 'S', 'I':
-		stack.PushInt(slots.GetInt(slotId))
+		stack.PushInt(ref.GetIntField(slotId))
 	case 'F':
-		stack.PushFloat(slots.GetFloat(slotId))
+		stack.PushFloat(ref.GetFloatField(slotId))
 	case 'J':
-		stack.PushLong(slots.GetLong(slotId))
+		stack.PushLong(ref.GetLongField(slotId))
 	case 'D':
-		stack.PushDouble(slots.GetDouble(slotId))
+		stack.PushDouble(ref.GetDoubleField(slotId))
 	case 'L', '[':
-		stack.PushRef(slots.GetRef(slotId))
+		if field.IsReferent() {
+			stack.PushRef(ref.Referent(field))
+		} else {
+			stack.PushRef(ref.GetRefField(slotId))
+		}
 	default:
 		// TODO
 	}
 }
+
+// 字段解析好以后直接记住槽位 referent字段要经过Reference的特殊处理 不换
+func (self *GET_FIELD) Quicken(method *heap.Method) base.Instruction {
+	field := method.Class().ConstantPool().GetConstant(self.Index).(*heap.FieldRef).Field()
+	if field == nil || field.IsReferent() {
+		return nil
+	}
+	switch field.Descriptor()[0] {
+	case 'J', 'D':
+		return &GET_FIELD_QUICK_LONG{slotId: field.SlotId()}
+	case 'L', '[':
+		return &GET_FIELD_QUICK_REF{slotId: field.SlotId()}
+	default:
+		return &GET_FIELD_QUICK_INT{slotId: field.SlotId()}
+	}
+}
+
+// boolean byte char short int float 都占4个字节
+type GET_FIELD_QUICK_INT struct {
+	base.NoOperandsInstruction
+	slotId uint
+}
+
+func (self *GET_FIELD_QUICK_INT) Execute(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	ref := stack.PopRef()
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	stack.PushInt(ref.GetIntField(self.slotId))
+}
+
+// long double
+type GET_FIELD_QUICK_LONG struct {
+	base.NoOperandsInstruction
+	slotId uint
+}
+
+func (self *GET_FIELD_QUICK_LONG) Execute(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	ref := stack.PopRef()
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	stack.PushLong(ref.GetLongField(self.slotId))
+}
+
+type GET_FIELD_QUICK_REF struct {
+	base.NoOperandsInstruction
+	slotId uint
+}
+
+func (self *GET_FIELD_QUICK_REF) Execute(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	ref := stack.PopRef()
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	stack.PushRef(ref.GetRefField(self.slotId))
+}
@@ -14,10 +14,8 @@ func (self *GET_STATIC) Execute(frame *rtda.Frame) {
 	fieldRef := cp.GetConstant(self.Index).(*heap.FieldRef)
 	field := fieldRef.ResolvedField()
 	class := field.Class()
-	if !class.InitStarted() {
-		frame.RevertNextPC()
+	if !class.IsInitialized() {
 		base.InitClass(frame.Thread(), class)
-		return
 	}
 
 	if !field.IsStatic() {
@@ -44,3 +42,50 @@ func (self *GET_STATIC) Execute(frame *rtda.Frame) {
 		// TODO
 	}
 }
+
+// 类初始化完以后直接记住静态变量表和槽位 初始化完成之前每次都要经过检查
+func (self *GET_STATIC) Quicken(method *heap.Method) base.Instruction {
+	field := method.Class().ConstantPool().GetConstant(self.Index).(*heap.FieldRef).Field()
+	if field == nil || !field.Class().IsInitialized() {
+		return nil
+	}
+	vars := field.Class().StaticVars()
+	switch field.Descriptor()[0] {
+	case 'J', 'D':
+		return &GET_STATIC_QUICK_LONG{vars: vars, slotId: field.SlotId()}
+	case 'L', '[':
+		return &GET_STATIC_QUICK_REF{vars: vars, slotId: field.SlotId()}
+	default:
+		return &GET_STATIC_QUICK_INT{vars: vars, slotId: field.SlotId()}
+	}
+}
+
+type GET_STATIC_QUICK_INT struct {
+	base.NoOperandsInstruction
+	vars   heap.Slots
+	slotId uint
+}
+
+func (self *GET_STATIC_QUICK_INT) Execute(frame *rtda.Frame) {
+	frame.OperandStack().PushInt(self.vars.GetInt(self.slotId))
+}
+
+type GET_STATIC_QUICK_LONG struct {
+	base.NoOperandsInstruction
+	vars   heap.Slots
+	slotId uint
+}
+
+func (self *GET_STATIC_QUICK_LONG) Execute(frame *rtda.Frame) {
+	frame.OperandStack().PushLong(self.vars.GetLong(self.slotId))
+}
+
+type GET_STATIC_QUICK_REF struct {
+	base.NoOperandsInstruction
+	vars   heap.Slots
+	slotId uint
+}
+
+func (self *GET_STATIC_QUICK_REF) Execute(frame *rtda.Frame) {
+	frame.OperandStack().PushRef(self.vars.GetRef(self.slotId))
+}
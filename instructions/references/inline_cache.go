@@ -0,0 +1,18 @@
+package references
+
+import "jvm/rtda/heap"
+
+// 调用点的单态内联缓存 记住上一次的接收者类型和选中的方法
+// 方法的指令只解码一次 改写成的*_QUICK指令对象只属于一个调用点 所以缓存放在指令上
+// 只缓存和调用者同一个类加载器的类 免得调用者的指令让其他加载器的类无法卸载
+type inlineCache struct {
+	loader *heap.ClassLoader // 调用者的类加载器
+	class  *heap.Class
+	method *heap.Method
+}
+
+func (self *inlineCache) update(receiver *heap.Class, method *heap.Method) {
+	if receiver.Loader() == self.loader {
+		self.class, self.method = receiver, method
+	}
+}
@@ -29,17 +29,17 @@ func (self *INVOKE_INTERFACE) Execute(frame *rtda.Frame) {
 	// get "this" ref
 	ref := frame.OperandStack().GetRefFromTop(resolvedMethod.ArgSlotCount() - 1)
 	if ref == nil {
-		panic("java.lang.NullPointerException") // TODO
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 	// 如果引用所指对象的类没有实现解析出来的接口
 	if !ref.Class().IsImplements(methodRef.ResolvedClass()) {
 		panic("java.lang.IncompatibleClassChangeError")
 	}
 
-	methodToBeInvoked := heap.LookupMethodInClass(ref.Class(),
-		methodRef.Name(), methodRef.Descriptor())
+	methodToBeInvoked := ref.Class().InterfaceMethod(resolvedMethod)
 	if methodToBeInvoked == nil || methodToBeInvoked.IsAbstract() {
-		panic("java.lang.AbstructMethodError")
+		panic(heap.NewJavaException("java/lang/AbstractMethodError",
+			ref.Class().JavaName()+"."+methodRef.Name()+methodRef.Descriptor()))
 	}
 	if !methodToBeInvoked.IsPublic() {
 		panic("java.lang.IllegalAccessError")
@@ -47,3 +47,45 @@ func (self *INVOKE_INTERFACE) Execute(frame *rtda.Frame) {
 
 	base.InvokeMethod(frame, methodToBeInvoked)
 }
+
+// 接收者的检查都和类型有关 只省去常量池查找
+func (self *INVOKE_INTERFACE) Quicken(method *heap.Method) base.Instruction {
+	methodRef := method.Class().ConstantPool().GetConstant(self.index).(*heap.InterfaceMethodRef)
+	return &INVOKE_INTERFACE_QUICK{
+		methodRef:    methodRef,
+		argSlotCount: methodRef.ResolvedInterfaceMethod().ArgSlotCount(),
+		cache:        inlineCache{loader: method.Class().Loader()},
+	}
+}
+
+type INVOKE_INTERFACE_QUICK struct {
+	base.NoOperandsInstruction
+	methodRef    *heap.InterfaceMethodRef
+	argSlotCount uint
+	cache        inlineCache
+}
+
+func (self *INVOKE_INTERFACE_QUICK) Execute(frame *rtda.Frame) {
+	ref := frame.OperandStack().GetRefFromTop(self.argSlotCount - 1)
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	if !ref.Class().IsImplements(self.methodRef.ResolvedClass()) {
+		panic("java.lang.IncompatibleClassChangeError")
+	}
+
+	// 同一个接收者类型直接命中内联缓存
+	methodToBeInvoked := self.cache.method
+	if receiver := ref.Class(); receiver != self.cache.class {
+		methodToBeInvoked = receiver.InterfaceMethod(self.methodRef.ResolvedInterfaceMethod())
+		self.cache.update(receiver, methodToBeInvoked)
+	}
+	if methodToBeInvoked == nil || methodToBeInvoked.IsAbstract() {
+		panic(heap.NewJavaException("java/lang/AbstractMethodError",
+			ref.Class().JavaName()+"."+self.methodRef.Name()+self.methodRef.Descriptor()))
+	}
+	if !methodToBeInvoked.IsPublic() {
+		panic("java.lang.IllegalAccessError")
+	}
+	base.InvokeMethod(frame, methodToBeInvoked)
+}
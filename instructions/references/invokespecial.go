@@ -14,9 +14,7 @@ type INVOKE_SPECIAL struct{ base.Index16Instruction }
 func (self *INVOKE_SPECIAL) Execute(frame *rtda.Frame) {
 	currentClass := frame.Method().Class() // 当前类 调用resolvedClass的类
 	cp := currentClass.ConstantPool() // 当前常量池
-	methodRef := cp.GetConstant(self.Index).(*heap.MethodRef) // 方法符号引用
-	resolvedClass := methodRef.ResolvedClass() // 解析类符号引用 获得一个类
-	resolvedMethod := methodRef.ResolvedMethod() // 解析方法符号引用 获得一个方法
+	resolvedClass, resolvedMethod := resolveMethodRef(cp, self.Index) // 解析方法符号引用 获得类和方法
 	if resolvedMethod.Name() == "<init>" && resolvedMethod.Class() != resolvedClass {
 		panic("java.lang.NoSuchMethodError")
 	}
@@ -27,34 +25,65 @@ func (self *INVOKE_SPECIAL) Execute(frame *rtda.Frame) {
 	//get "this" ref of resolvedMethod
 	ref := frame.OperandStack().GetRefFromTop(resolvedMethod.ArgSlotCount() - 1)
 	if ref == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 
 	// 确保protected方法只能被声明该方法的类或子类调用
-	if resolvedMethod.IsProtected() &&
-		resolvedMethod.Class().IsSubClassOf(currentClass) &&
-		resolvedMethod.Class().GetPackageName() != currentClass.GetPackageName() &&
+	if needsProtectedCheck(currentClass, resolvedMethod) &&
 		ref.Class() != currentClass &&
 		!ref.Class().IsSubClassOf(currentClass) {
 
 		panic("java.lang.IllegalAccessError")
 	}
 
-	// 如果调用的中超类中的函数,但不是构造函数,且当前类的ACC_SUPER标志被设置,
-	// 需要一个额外的过程查找最终要调用的方法;
-	// 否则前面从方法符号引用中解析出来的方法就是要调用的方法
-	methodToBeInvoked := resolvedMethod
+	methodToBeInvoked := selectSpecialMethod(currentClass, resolvedClass, resolvedMethod)
+	if methodToBeInvoked == nil || methodToBeInvoked.IsAbstract() {
+		panic(heap.NewJavaException("java/lang/AbstractMethodError",
+			resolvedClass.JavaName()+"."+resolvedMethod.Name()+resolvedMethod.Descriptor()))
+	}
+
+	base.InvokeMethod(frame, methodToBeInvoked)
+}
+
+// 如果调用的中超类中的函数,但不是构造函数,且当前类的ACC_SUPER标志被设置,
+// 需要一个额外的过程查找最终要调用的方法;
+// I.super.m()形式的调用从接口I开始查找 可能选中它继承的默认方法;
+// 否则前面从方法符号引用中解析出来的方法就是要调用的方法
+func selectSpecialMethod(currentClass, resolvedClass *heap.Class, resolvedMethod *heap.Method) *heap.Method {
 	if currentClass.IsSuper() &&
+		!resolvedClass.IsInterface() &&
 		resolvedClass.IsSuperClassOf(currentClass) &&
 		resolvedMethod.Name() != "<init>" {
 
-		methodToBeInvoked = heap.LookupMethodInClass(currentClass.SuperClass(),
-			methodRef.Name(), methodRef.Descriptor())
+		return heap.SelectMethod(currentClass.SuperClass(),
+			resolvedMethod.Name(), resolvedMethod.Descriptor())
+	} else if resolvedClass.IsInterface() {
+		return heap.SelectMethod(resolvedClass,
+			resolvedMethod.Name(), resolvedMethod.Descriptor())
 	}
+	return resolvedMethod
+}
 
-	if methodToBeInvoked == nil || methodToBeInvoked.IsAbstract() {
-		panic("java.lang.AbstractMethodError")
+// 要调用的方法只取决于调用点 第一次选出来以后直接记住
+func (self *INVOKE_SPECIAL) Quicken(method *heap.Method) base.Instruction {
+	currentClass := method.Class()
+	resolvedClass, resolvedMethod := resolveMethodRef(currentClass.ConstantPool(), self.Index)
+	if needsProtectedCheck(currentClass, resolvedMethod) {
+		return nil
+	}
+	return &INVOKE_SPECIAL_QUICK{
+		method: selectSpecialMethod(currentClass, resolvedClass, resolvedMethod),
 	}
+}
 
-	base.InvokeMethod(frame, methodToBeInvoked)
+type INVOKE_SPECIAL_QUICK struct {
+	base.NoOperandsInstruction
+	method *heap.Method
+}
+
+func (self *INVOKE_SPECIAL_QUICK) Execute(frame *rtda.Frame) {
+	if frame.OperandStack().GetRefFromTop(self.method.ArgSlotCount()-1) == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	base.InvokeMethod(frame, self.method)
 }
@@ -12,17 +12,32 @@ type INVOKE_STATIC struct {
 
 func (self *INVOKE_STATIC) Execute(frame *rtda.Frame) {
 	cp := frame.Method().Class().ConstantPool()
-	methodRef := cp.GetConstant(self.Index).(*heap.MethodRef)
-	resolvedMethod := methodRef.ResolvedMethod()
+	_, resolvedMethod := resolveMethodRef(cp, self.Index)
 	if !resolvedMethod.IsStatic() {
 		panic("java.lang.IncompatibleClassChangeError")
 	}
 
 	class := resolvedMethod.Class()
-	if !class.InitStarted() {
-		frame.RevertNextPC()
+	if !class.IsInitialized() {
 		base.InitClass(frame.Thread(), class)
-		return
 	}
 	base.InvokeMethod(frame, resolvedMethod)
 }
+
+// 类初始化完以后直接记住要调用的方法
+func (self *INVOKE_STATIC) Quicken(method *heap.Method) base.Instruction {
+	_, resolvedMethod := resolveMethodRef(method.Class().ConstantPool(), self.Index)
+	if !resolvedMethod.Class().IsInitialized() {
+		return nil
+	}
+	return &INVOKE_STATIC_QUICK{method: resolvedMethod}
+}
+
+type INVOKE_STATIC_QUICK struct {
+	base.NoOperandsInstruction
+	method *heap.Method
+}
+
+func (self *INVOKE_STATIC_QUICK) Execute(frame *rtda.Frame) {
+	base.InvokeMethod(frame, self.method)
+}
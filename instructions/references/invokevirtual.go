@@ -21,24 +21,63 @@ func (self *INVOKE_VIRTUAL) Execute(frame *rtda.Frame) {
 	// get "this" ref
 	ref := frame.OperandStack().GetRefFromTop(resolvedMethod.ArgSlotCount() - 1)
 	if ref == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 
 	// 确保protected方法只能被声明该方法的类或子类调用
-	if resolvedMethod.IsProtected() &&
-		resolvedMethod.Class().IsSubClassOf(currentClass) &&
-		resolvedMethod.Class().GetPackageName() != currentClass.GetPackageName() &&
+	if needsProtectedCheck(currentClass, resolvedMethod) &&
 		ref.Class() != currentClass &&
 		!ref.Class().IsSubClassOf(currentClass) {
 
 		panic("java.lang.IllegalAccessError")
 	}
 
-	// 从抽象类中查找真正调用的方法
-	methodToBeInvoked := heap.LookupMethodInClass(ref.Class(),
-		methodRef.Name(), methodRef.Descriptor())
+	// 按vtable选择真正调用的方法
+	methodToBeInvoked := ref.Class().VirtualMethod(resolvedMethod)
 	if methodToBeInvoked == nil || methodToBeInvoked.IsAbstract() {
-		panic("java.lang.AbstructMethodError")
+		panic(heap.NewJavaException("java/lang/AbstractMethodError",
+			ref.Class().JavaName()+"."+methodRef.Name()+methodRef.Descriptor()))
+	}
+	base.InvokeMethod(frame, methodToBeInvoked)
+}
+
+// 不需要按接收者检查protected访问权限时 直接记住方法符号引用 省去常量池查找和检查
+func (self *INVOKE_VIRTUAL) Quicken(method *heap.Method) base.Instruction {
+	currentClass := method.Class()
+	methodRef := currentClass.ConstantPool().GetConstant(self.Index).(*heap.MethodRef)
+	resolvedMethod := methodRef.ResolvedMethod()
+	if needsProtectedCheck(currentClass, resolvedMethod) {
+		return nil
+	}
+	return &INVOKE_VIRTUAL_QUICK{
+		methodRef:    methodRef,
+		argSlotCount: resolvedMethod.ArgSlotCount(),
+		cache:        inlineCache{loader: currentClass.Loader()},
+	}
+}
+
+type INVOKE_VIRTUAL_QUICK struct {
+	base.NoOperandsInstruction
+	methodRef    *heap.MethodRef
+	argSlotCount uint
+	cache        inlineCache
+}
+
+func (self *INVOKE_VIRTUAL_QUICK) Execute(frame *rtda.Frame) {
+	ref := frame.OperandStack().GetRefFromTop(self.argSlotCount - 1)
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+
+	// 同一个接收者类型直接命中内联缓存
+	methodToBeInvoked := self.cache.method
+	if receiver := ref.Class(); receiver != self.cache.class {
+		methodToBeInvoked = receiver.VirtualMethod(self.methodRef.ResolvedMethod())
+		self.cache.update(receiver, methodToBeInvoked)
+	}
+	if methodToBeInvoked == nil || methodToBeInvoked.IsAbstract() {
+		panic(heap.NewJavaException("java/lang/AbstractMethodError",
+			ref.Class().JavaName()+"."+self.methodRef.Name()+self.methodRef.Descriptor()))
 	}
 	base.InvokeMethod(frame, methodToBeInvoked)
 }
@@ -0,0 +1,23 @@
+package references
+
+import "jvm/rtda/heap"
+
+// invokestatic和invokespecial的操作数既可以是Methodref 也可以是InterfaceMethodref
+// 后者用于调用接口的静态方法 以及I.super.m()形式的默认方法调用
+func resolveMethodRef(cp *heap.ConstantPool, index uint) (*heap.Class, *heap.Method) {
+	switch ref := cp.GetConstant(index).(type) {
+	case *heap.InterfaceMethodRef:
+		return ref.ResolvedClass(), ref.ResolvedInterfaceMethod()
+	default:
+		methodRef := ref.(*heap.MethodRef)
+		return methodRef.ResolvedClass(), methodRef.ResolvedMethod()
+	}
+}
+
+// 其他包里的子类调用超类的protected方法时 还要按接收者的类型检查访问权限
+// 这时指令不能换成快速版本
+func needsProtectedCheck(currentClass *heap.Class, resolvedMethod *heap.Method) bool {
+	return resolvedMethod.IsProtected() &&
+		resolvedMethod.Class().IsSubClassOf(currentClass) &&
+		resolvedMethod.Class().GetPackageName() != currentClass.GetPackageName()
+}
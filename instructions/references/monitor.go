@@ -3,26 +3,29 @@ package references
 import (
 	"jvm/instructions/base"
 	"jvm/rtda"
+	"jvm/rtda/heap"
 )
 
 // Enter monitor for object
 type MONITOR_ENTER struct{ base.NoOperandsInstruction }
 
-// todo
 func (self *MONITOR_ENTER) Execute(frame *rtda.Frame) {
   ref := frame.OperandStack().PopRef()
   if ref == nil {
-    panic("java.lang.NullPointerException")
+    panic(heap.NewJavaException("java/lang/NullPointerException", ""))
   }
+  frame.Thread().EnterMonitor(ref)
 }
 
 // Exit monitor for object
 type MONITOR_EXIT struct{ base.NoOperandsInstruction }
 
-// todo
 func (self *MONITOR_EXIT) Execute(frame *rtda.Frame) {
   ref := frame.OperandStack().PopRef()
   if ref == nil {
-    panic("java.lang.NullPointerException")
+    panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+  }
+  if !frame.Thread().ExitMonitor(ref) {
+    panic(heap.NewJavaException("java/lang/IllegalMonitorStateException", ""))
   }
 }
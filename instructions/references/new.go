@@ -15,11 +15,9 @@ func (self *NEW) Execute(frame *rtda.Frame) {
 	cp := frame.Method().Class().ConstantPool()
 	classRef := cp.GetConstant(self.Index).(*heap.ClassRef)
 	class := classRef.ResolvedClass() // 用符号引用加载整个类信息
-	// new指令触发构建类实例 但类还没有初始化 终止指令执行
-	if !class.InitStarted() {
-		frame.RevertNextPC() // 回置PC
+	// new指令触发类的初始化 初始化完成后继续执行
+	if !class.IsInitialized() {
 		base.InitClass(frame.Thread(), class)
-		return
 	}
 
 	// interface and abstract class can be instantced
@@ -29,3 +27,21 @@ func (self *NEW) Execute(frame *rtda.Frame) {
 	ref := class.NewObject() // 实例化
 	frame.OperandStack().PushRef(ref)
 }
+
+// 类初始化完以后直接记住类
+func (self *NEW) Quicken(method *heap.Method) base.Instruction {
+	class := method.Class().ConstantPool().GetConstant(self.Index).(*heap.ClassRef).ResolvedClass()
+	if !class.IsInitialized() {
+		return nil
+	}
+	return &NEW_QUICK{class: class}
+}
+
+type NEW_QUICK struct {
+	base.NoOperandsInstruction
+	class *heap.Class
+}
+
+func (self *NEW_QUICK) Execute(frame *rtda.Frame) {
+	frame.OperandStack().PushRef(self.class.NewObject())
+}
@@ -35,38 +35,103 @@ func (self *PUT_FIELD) Execute(frame *rtda.Frame) {
 		val := stack.PopInt()
 		ref := stack.PopRef()
 		if ref == nil {
-			panic("java.lang.NullPointerException")
+			panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 		}
-		ref.Fields().SetInt(slotId, val)
+		ref.SetIntField(slotId, val)
 	case 'F':
 		val := stack.PopFloat()
 		ref := stack.PopRef()
 		if ref == nil {
-			panic("java.lang.NullPointerException")
+			panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 		}
-		ref.Fields().SetFloat(slotId, val)
+		ref.SetFloatField(slotId, val)
 	case 'J':
 		val := stack.PopLong()
 		ref := stack.PopRef()
 		if ref == nil {
-			panic("java.lang.NullPointerException")
+			panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 		}
-		ref.Fields().SetLong(slotId, val)
+		ref.SetLongField(slotId, val)
 	case 'D':
 		val := stack.PopDouble()
 		ref := stack.PopRef()
 		if ref == nil {
-			panic("java.lang.NullPointerException")
+			panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 		}
-		ref.Fields().SetDouble(slotId, val)
+		ref.SetDoubleField(slotId, val)
 	case 'L', '[':
 		val := stack.PopRef()
 		ref := stack.PopRef()
 		if ref == nil {
-			panic("java.lang.NullPointerException")
+			panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+		}
+		if field.IsReferent() {
+			ref.SetReferent(field, val)
+		} else {
+			ref.SetRefField(slotId, val)
 		}
-		ref.Fields().SetRef(slotId, val)
 	default:
 		// TODO
 	}
 }
+
+// final字段的检查只和当前方法有关 第一次执行通过了以后也总能通过
+func (self *PUT_FIELD) Quicken(method *heap.Method) base.Instruction {
+	field := method.Class().ConstantPool().GetConstant(self.Index).(*heap.FieldRef).Field()
+	if field == nil || field.IsReferent() {
+		return nil
+	}
+	switch field.Descriptor()[0] {
+	case 'J', 'D':
+		return &PUT_FIELD_QUICK_LONG{slotId: field.SlotId()}
+	case 'L', '[':
+		return &PUT_FIELD_QUICK_REF{slotId: field.SlotId()}
+	default:
+		return &PUT_FIELD_QUICK_INT{slotId: field.SlotId()}
+	}
+}
+
+type PUT_FIELD_QUICK_INT struct {
+	base.NoOperandsInstruction
+	slotId uint
+}
+
+func (self *PUT_FIELD_QUICK_INT) Execute(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	val := stack.PopInt()
+	ref := stack.PopRef()
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	ref.SetIntField(self.slotId, val)
+}
+
+type PUT_FIELD_QUICK_LONG struct {
+	base.NoOperandsInstruction
+	slotId uint
+}
+
+func (self *PUT_FIELD_QUICK_LONG) Execute(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	val := stack.PopLong()
+	ref := stack.PopRef()
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	ref.SetLongField(self.slotId, val)
+}
+
+type PUT_FIELD_QUICK_REF struct {
+	base.NoOperandsInstruction
+	slotId uint
+}
+
+func (self *PUT_FIELD_QUICK_REF) Execute(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	val := stack.PopRef()
+	ref := stack.PopRef()
+	if ref == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	ref.SetRefField(self.slotId, val)
+}
@@ -20,10 +20,8 @@ func (self *PUT_STATIC) Execute(frame *rtda.Frame) {
 	field := fieldRef.ResolvedField()
 	class := field.Class()
 	// init class
-	if !class.InitStarted() {
-		frame.RevertNextPC()
+	if !class.IsInitialized() {
 		base.InitClass(frame.Thread(), class)
-		return
 	}
 
 	if !field.IsStatic() {
@@ -57,3 +55,50 @@ func (self *PUT_STATIC) Execute(frame *rtda.Frame) {
 		// TODO
 	}
 }
+
+// 和getstatic一样 类初始化完以后才换成快速版本
+func (self *PUT_STATIC) Quicken(method *heap.Method) base.Instruction {
+	field := method.Class().ConstantPool().GetConstant(self.Index).(*heap.FieldRef).Field()
+	if field == nil || !field.Class().IsInitialized() {
+		return nil
+	}
+	vars := field.Class().StaticVars()
+	switch field.Descriptor()[0] {
+	case 'J', 'D':
+		return &PUT_STATIC_QUICK_LONG{vars: vars, slotId: field.SlotId()}
+	case 'L', '[':
+		return &PUT_STATIC_QUICK_REF{vars: vars, slotId: field.SlotId()}
+	default:
+		return &PUT_STATIC_QUICK_INT{vars: vars, slotId: field.SlotId()}
+	}
+}
+
+type PUT_STATIC_QUICK_INT struct {
+	base.NoOperandsInstruction
+	vars   heap.Slots
+	slotId uint
+}
+
+func (self *PUT_STATIC_QUICK_INT) Execute(frame *rtda.Frame) {
+	self.vars.SetInt(self.slotId, frame.OperandStack().PopInt())
+}
+
+type PUT_STATIC_QUICK_LONG struct {
+	base.NoOperandsInstruction
+	vars   heap.Slots
+	slotId uint
+}
+
+func (self *PUT_STATIC_QUICK_LONG) Execute(frame *rtda.Frame) {
+	self.vars.SetLong(self.slotId, frame.OperandStack().PopLong())
+}
+
+type PUT_STATIC_QUICK_REF struct {
+	base.NoOperandsInstruction
+	vars   heap.Slots
+	slotId uint
+}
+
+func (self *PUT_STATIC_QUICK_REF) Execute(frame *rtda.Frame) {
+	self.vars.SetRef(self.slotId, frame.OperandStack().PopRef())
+}
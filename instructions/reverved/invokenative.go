@@ -6,10 +6,14 @@ import (
 	"jvm/rtda"
 	_ "jvm/native/java/lang"
 	_ "jvm/native/java/io"
+	_ "jvm/native/java/net"
+	_ "jvm/native/java/nio"
 	_ "jvm/native/java/security"
 	_ "jvm/native/java/util/concurrent/atomic"
 	_ "jvm/native/sun/io"
 	_ "jvm/native/sun/misc"
+	_ "jvm/native/sun/nio/ch"
+	_ "jvm/native/sun/nio/fs"
 	_ "jvm/native/sun/reflect"
 )
 
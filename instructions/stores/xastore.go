@@ -129,7 +129,7 @@ func (self *SASTORE) Execute(frame *rtda.Frame) {
 
 func checkNotNil(ref *heap.Object) {
 	if ref == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 }
 func checkIndex(arrLen int, index int32) {
@@ -5,11 +5,46 @@ import (
 	"jvm/instructions"
 	"jvm/instructions/base"
 	"jvm/rtda"
+	"jvm/rtda/heap"
 )
 
 func interpret(thread *rtda.Thread, logInst bool) {
+	run(thread, logInst, nil)
+}
+
+// 执行到栈空 或者barrier成为栈顶(base.InvokeAndWait的嵌套循环)
+func run(thread *rtda.Thread, logInst bool, barrier *rtda.Frame) {
 	defer catchErr(thread)
-	loop(thread, logInst)
+	for !finished(thread, barrier) {
+		safeLoop(thread, logInst, barrier)
+	}
+}
+
+func finished(thread *rtda.Thread, barrier *rtda.Frame) bool {
+	return thread.IsStackEmpty() || thread.TopFrame() == barrier
+}
+
+// Go代码中以panic形式抛出的Java异常(比如OutOfMemoryError)
+// 在这里转换成真正的异常对象 然后继续执行
+func safeLoop(thread *rtda.Thread, logInst bool, barrier *rtda.Frame) {
+	defer func() {
+		if r := recover(); r != nil {
+			if ex, ok := r.(*heap.JavaException); ok {
+				if thrown := ex.Thrown(); thrown != nil {
+					base.Rethrow(thread, thrown)
+				} else {
+					base.ThrowException(thread, ex.ClassName(), ex.Message())
+				}
+				return
+			}
+			panic(r)
+		}
+	}()
+	if fastInterpreter && !logInst {
+		fastLoop(thread, barrier)
+	} else {
+		loop(thread, logInst, barrier)
+	}
 }
 
 func catchErr(thread *rtda.Thread) {
@@ -19,29 +54,45 @@ func catchErr(thread *rtda.Thread) {
 	}
 }
 
-func loop(thread *rtda.Thread, logInst bool) {
-	reader := &base.BytecodeReader{}
-	for {
+// -Xint:fast时使用fastLoop -verbose:inst需要逐条打印指令 仍然使用loop
+var fastInterpreter bool
+
+// 每执行这么多条指令 让其他Java线程有机会获得全局解释器锁
+const yieldInterval = 1024
+
+func loop(thread *rtda.Thread, logInst bool, barrier *rtda.Frame) {
+	var lastFrame *rtda.Frame // 只在换了栈帧或者回边触发编译以后才看能不能进入编译好的代码
+	for count := 1; ; count++ {
+		if count%yieldInterval == 0 {
+			thread.Yield()
+		}
+
 		frame := thread.CurrentFrame() // 当前函数栈帧
+		if useJIT && frame != lastFrame {
+			enterCompiled(thread, frame) // 先执行编译好的代码 直到需要解释器处理的指令
+			lastFrame = frame
+		}
+		method := frame.Method()
 		pc := frame.NextPC()
 		thread.SetPC(pc)
 
-		// decode
-		reader.Reset(frame.Method().Code(), pc)
-		opcode := reader.ReadUint8()
-		inst := instructions.NewInstruction(opcode)
-		inst.FetchOperands(reader)
-		frame.SetNextPC(reader.PC())
+		// decode 解码过的指令缓存在方法里
+		inst, nextPC := instructions.Decode(method, pc)
+		frame.SetNextPC(nextPC)
 
 		if logInst {
 			logInstruction(frame, inst)
 		}
 
-		// execute
+		// execute 成功执行以后换成快速版本
 		inst.Execute(frame)
-		if thread.IsStackEmpty() {
+		instructions.Quicken(method, pc, inst)
+		if finished(thread, barrier) {
 			break
 		}
+		if useJIT && frame.NextPC() <= pc && countBackedge(method) {
+			lastFrame = nil
+		}
 	}
 }
 
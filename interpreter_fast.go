@@ -0,0 +1,800 @@
+package main
+
+import (
+	"jvm/instructions"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"math"
+)
+
+// -Xint:fast选择的解释器
+// 当前栈帧、pc、局部变量表和操作数栈都放在局部变量里 常用的指令直接在switch里执行
+// 调用、返回、抛异常以及其余不常用的指令 先把pc和栈顶写回rtda.Frame 再交给base.Instruction执行
+// 执行完以后如果当前栈帧变了 就从新的栈帧重新载入这些状态
+func fastLoop(thread *rtda.Thread, barrier *rtda.Frame) {
+	count := 0
+	for {
+		frame := thread.CurrentFrame()
+		if useJIT {
+			enterCompiled(thread, frame)
+		}
+		method := frame.Method()
+		cp := method.Class().ConstantPool()
+		code := method.Code()
+		pc := frame.NextPC()
+		locals := frame.LocalVars()
+		stack := frame.OperandStack()
+		slots := stack.Slots()
+		sp := stack.Size()
+
+	interp:
+		for {
+			count++
+			if count%yieldInterval == 0 {
+				// 让出锁期间其他线程可能会查看这个线程的栈(比如打印线程栈)
+				frame.SetNextPC(pc)
+				thread.SetPC(pc)
+				stack.SetSize(sp)
+				thread.Yield()
+			}
+
+			opcode := code[pc]
+		dispatch:
+			switch opcode {
+			// constants
+			case 0x00: // nop
+				pc++
+				continue
+			case 0x01: // aconst_null
+				slots[sp] = rtda.Slot{}
+				sp++
+				pc++
+				continue
+			case 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08: // iconst_m1 ~ iconst_5
+				slots[sp] = rtda.IntSlot(int32(opcode) - 3)
+				sp++
+				pc++
+				continue
+			case 0x09, 0x0a: // lconst_0 lconst_1
+				setLong(slots, sp, int64(opcode-0x09))
+				sp += 2
+				pc++
+				continue
+			case 0x0b, 0x0c, 0x0d: // fconst_0 ~ fconst_2
+				slots[sp] = rtda.FloatSlot(float32(opcode - 0x0b))
+				sp++
+				pc++
+				continue
+			case 0x0e, 0x0f: // dconst_0 dconst_1
+				setDouble(slots, sp, float64(opcode-0x0e))
+				sp += 2
+				pc++
+				continue
+			case 0x10: // bipush
+				slots[sp] = rtda.IntSlot(int32(int8(code[pc+1])))
+				sp++
+				pc += 2
+				continue
+			case 0x11: // sipush
+				slots[sp] = rtda.IntSlot(int32(int16(uint16(code[pc+1])<<8 | uint16(code[pc+2]))))
+				sp++
+				pc += 3
+				continue
+
+			// loads 局部变量表和操作数栈的槽不区分类型 直接复制
+			case 0x15, 0x17, 0x19: // iload fload aload
+				slots[sp] = locals[code[pc+1]]
+				sp++
+				pc += 2
+				continue
+			case 0x16, 0x18: // lload dload
+				index := uint(code[pc+1])
+				slots[sp] = locals[index]
+				slots[sp+1] = locals[index+1]
+				sp += 2
+				pc += 2
+				continue
+			case 0x1a, 0x1b, 0x1c, 0x1d, // iload_<n>
+				0x22, 0x23, 0x24, 0x25, // fload_<n>
+				0x2a, 0x2b, 0x2c, 0x2d: // aload_<n>
+				slots[sp] = locals[(opcode-0x1a)&3]
+				sp++
+				pc++
+				continue
+			case 0x1e, 0x1f, 0x20, 0x21, // lload_<n>
+				0x26, 0x27, 0x28, 0x29: // dload_<n>
+				index := uint(opcode-0x1a) & 3
+				slots[sp] = locals[index]
+				slots[sp+1] = locals[index+1]
+				sp += 2
+				pc++
+				continue
+
+			// 数组为null或者下标越界时交给原来的指令抛出异常
+			case 0x2e: // iaload
+				if vals := arrayData[int32](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						sp--
+						slots[sp-1] = rtda.IntSlot(vals[index])
+						pc++
+						continue
+					}
+				}
+			case 0x2f: // laload
+				if vals := arrayData[int64](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						setLong(slots, sp-2, vals[index])
+						pc++
+						continue
+					}
+				}
+			case 0x30: // faload
+				if vals := arrayData[float32](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						sp--
+						slots[sp-1] = rtda.FloatSlot(vals[index])
+						pc++
+						continue
+					}
+				}
+			case 0x31: // daload
+				if vals := arrayData[float64](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						setDouble(slots, sp-2, vals[index])
+						pc++
+						continue
+					}
+				}
+			case 0x32: // aaload
+				if vals := arrayRefs(slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						sp--
+						slots[sp-1] = rtda.RefSlot(vals[index])
+						pc++
+						continue
+					}
+				}
+			case 0x33: // baload
+				if vals := arrayData[int8](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						sp--
+						slots[sp-1] = rtda.IntSlot(int32(vals[index]))
+						pc++
+						continue
+					}
+				}
+			case 0x34: // caload
+				if vals := arrayData[uint16](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						sp--
+						slots[sp-1] = rtda.IntSlot(int32(vals[index]))
+						pc++
+						continue
+					}
+				}
+			case 0x35: // saload
+				if vals := arrayData[int16](slots, sp); vals != nil {
+					if index := uint(slots[sp-1].Int()); index < uint(len(vals)) {
+						sp--
+						slots[sp-1] = rtda.IntSlot(int32(vals[index]))
+						pc++
+						continue
+					}
+				}
+
+			// stores
+			case 0x36, 0x38, 0x3a: // istore fstore astore
+				sp--
+				locals[code[pc+1]] = slots[sp]
+				pc += 2
+				continue
+			case 0x37, 0x39: // lstore dstore
+				index := uint(code[pc+1])
+				sp -= 2
+				locals[index] = slots[sp]
+				locals[index+1] = slots[sp+1]
+				pc += 2
+				continue
+			case 0x3b, 0x3c, 0x3d, 0x3e, // istore_<n>
+				0x43, 0x44, 0x45, 0x46, // fstore_<n>
+				0x4b, 0x4c, 0x4d, 0x4e: // astore_<n>
+				sp--
+				locals[(opcode-0x3b)&3] = slots[sp]
+				pc++
+				continue
+			case 0x3f, 0x40, 0x41, 0x42, // lstore_<n>
+				0x47, 0x48, 0x49, 0x4a: // dstore_<n>
+				index := uint(opcode-0x3b) & 3
+				sp -= 2
+				locals[index] = slots[sp]
+				locals[index+1] = slots[sp+1]
+				pc++
+				continue
+
+			// aastore要检查元素类型 交给原来的指令
+			case 0x4f: // iastore
+				if vals := arrayData[int32](slots, sp-1); vals != nil {
+					if index := uint(slots[sp-2].Int()); index < uint(len(vals)) {
+						vals[index] = slots[sp-1].Int()
+						sp -= 3
+						pc++
+						continue
+					}
+				}
+			case 0x50: // lastore
+				if vals := arrayData[int64](slots, sp-2); vals != nil {
+					if index := uint(slots[sp-3].Int()); index < uint(len(vals)) {
+						vals[index] = getLong(slots, sp-2)
+						sp -= 4
+						pc++
+						continue
+					}
+				}
+			case 0x51: // fastore
+				if vals := arrayData[float32](slots, sp-1); vals != nil {
+					if index := uint(slots[sp-2].Int()); index < uint(len(vals)) {
+						vals[index] = slots[sp-1].Float()
+						sp -= 3
+						pc++
+						continue
+					}
+				}
+			case 0x52: // dastore
+				if vals := arrayData[float64](slots, sp-2); vals != nil {
+					if index := uint(slots[sp-3].Int()); index < uint(len(vals)) {
+						vals[index] = getDouble(slots, sp-2)
+						sp -= 4
+						pc++
+						continue
+					}
+				}
+			case 0x54: // bastore
+				if vals := arrayData[int8](slots, sp-1); vals != nil {
+					if index := uint(slots[sp-2].Int()); index < uint(len(vals)) {
+						vals[index] = int8(slots[sp-1].Int())
+						sp -= 3
+						pc++
+						continue
+					}
+				}
+			case 0x55: // castore
+				if vals := arrayData[uint16](slots, sp-1); vals != nil {
+					if index := uint(slots[sp-2].Int()); index < uint(len(vals)) {
+						vals[index] = uint16(slots[sp-1].Int())
+						sp -= 3
+						pc++
+						continue
+					}
+				}
+			case 0x56: // sastore
+				if vals := arrayData[int16](slots, sp-1); vals != nil {
+					if index := uint(slots[sp-2].Int()); index < uint(len(vals)) {
+						vals[index] = int16(slots[sp-1].Int())
+						sp -= 3
+						pc++
+						continue
+					}
+				}
+
+			// stack
+			case 0x57: // pop
+				sp--
+				pc++
+				continue
+			case 0x58: // pop2
+				sp -= 2
+				pc++
+				continue
+			case 0x59: // dup
+				slots[sp] = slots[sp-1]
+				sp++
+				pc++
+				continue
+			case 0x5a: // dup_x1
+				slot1, slot2 := slots[sp-1], slots[sp-2]
+				slots[sp-2], slots[sp-1], slots[sp] = slot1, slot2, slot1
+				sp++
+				pc++
+				continue
+			case 0x5b: // dup_x2
+				slot1, slot2, slot3 := slots[sp-1], slots[sp-2], slots[sp-3]
+				slots[sp-3], slots[sp-2], slots[sp-1], slots[sp] = slot1, slot3, slot2, slot1
+				sp++
+				pc++
+				continue
+			case 0x5c: // dup2
+				slots[sp], slots[sp+1] = slots[sp-2], slots[sp-1]
+				sp += 2
+				pc++
+				continue
+			case 0x5d: // dup2_x1
+				slot1, slot2, slot3 := slots[sp-1], slots[sp-2], slots[sp-3]
+				slots[sp-3], slots[sp-2], slots[sp-1], slots[sp], slots[sp+1] = slot2, slot1, slot3, slot2, slot1
+				sp += 2
+				pc++
+				continue
+			case 0x5e: // dup2_x2
+				slot1, slot2, slot3, slot4 := slots[sp-1], slots[sp-2], slots[sp-3], slots[sp-4]
+				slots[sp-4], slots[sp-3], slots[sp-2], slots[sp-1], slots[sp], slots[sp+1] = slot2, slot1, slot4, slot3, slot2, slot1
+				sp += 2
+				pc++
+				continue
+			case 0x5f: // swap
+				slots[sp-1], slots[sp-2] = slots[sp-2], slots[sp-1]
+				pc++
+				continue
+
+			// math
+			case 0x60: // iadd
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() + slots[sp].Int())
+				pc++
+				continue
+			case 0x61: // ladd
+				sp -= 2
+				setLong(slots, sp-2, getLong(slots, sp-2)+getLong(slots, sp))
+				pc++
+				continue
+			case 0x62: // fadd
+				sp--
+				slots[sp-1] = rtda.FloatSlot(slots[sp-1].Float() + slots[sp].Float())
+				pc++
+				continue
+			case 0x63: // dadd
+				sp -= 2
+				setDouble(slots, sp-2, getDouble(slots, sp-2)+getDouble(slots, sp))
+				pc++
+				continue
+			case 0x64: // isub
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() - slots[sp].Int())
+				pc++
+				continue
+			case 0x65: // lsub
+				sp -= 2
+				setLong(slots, sp-2, getLong(slots, sp-2)-getLong(slots, sp))
+				pc++
+				continue
+			case 0x66: // fsub
+				sp--
+				slots[sp-1] = rtda.FloatSlot(slots[sp-1].Float() - slots[sp].Float())
+				pc++
+				continue
+			case 0x67: // dsub
+				sp -= 2
+				setDouble(slots, sp-2, getDouble(slots, sp-2)-getDouble(slots, sp))
+				pc++
+				continue
+			case 0x68: // imul
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() * slots[sp].Int())
+				pc++
+				continue
+			case 0x69: // lmul
+				sp -= 2
+				setLong(slots, sp-2, getLong(slots, sp-2)*getLong(slots, sp))
+				pc++
+				continue
+			case 0x6a: // fmul
+				sp--
+				slots[sp-1] = rtda.FloatSlot(slots[sp-1].Float() * slots[sp].Float())
+				pc++
+				continue
+			case 0x6b: // dmul
+				sp -= 2
+				setDouble(slots, sp-2, getDouble(slots, sp-2)*getDouble(slots, sp))
+				pc++
+				continue
+			case 0x6c: // idiv 除数为0时交给原来的指令
+				if v2 := slots[sp-1].Int(); v2 != 0 {
+					sp--
+					slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() / v2)
+					pc++
+					continue
+				}
+			case 0x6d: // ldiv
+				if v2 := getLong(slots, sp-2); v2 != 0 {
+					sp -= 2
+					setLong(slots, sp-2, getLong(slots, sp-2)/v2)
+					pc++
+					continue
+				}
+			case 0x6e: // fdiv
+				sp--
+				slots[sp-1] = rtda.FloatSlot(slots[sp-1].Float() / slots[sp].Float())
+				pc++
+				continue
+			case 0x6f: // ddiv
+				sp -= 2
+				setDouble(slots, sp-2, getDouble(slots, sp-2)/getDouble(slots, sp))
+				pc++
+				continue
+			case 0x70: // irem
+				if v2 := slots[sp-1].Int(); v2 != 0 {
+					sp--
+					slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() % v2)
+					pc++
+					continue
+				}
+			case 0x71: // lrem
+				if v2 := getLong(slots, sp-2); v2 != 0 {
+					sp -= 2
+					setLong(slots, sp-2, getLong(slots, sp-2)%v2)
+					pc++
+					continue
+				}
+			case 0x72: // frem
+				sp--
+				slots[sp-1] = rtda.FloatSlot(float32(math.Mod(float64(slots[sp-1].Float()), float64(slots[sp].Float()))))
+				pc++
+				continue
+			case 0x73: // drem
+				sp -= 2
+				setDouble(slots, sp-2, math.Mod(getDouble(slots, sp-2), getDouble(slots, sp)))
+				pc++
+				continue
+			case 0x74: // ineg
+				slots[sp-1] = rtda.IntSlot(-slots[sp-1].Int())
+				pc++
+				continue
+			case 0x75: // lneg
+				setLong(slots, sp-2, -getLong(slots, sp-2))
+				pc++
+				continue
+			case 0x76: // fneg
+				slots[sp-1] = rtda.FloatSlot(-slots[sp-1].Float())
+				pc++
+				continue
+			case 0x77: // dneg
+				setDouble(slots, sp-2, -getDouble(slots, sp-2))
+				pc++
+				continue
+			case 0x78: // ishl
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() << (uint32(slots[sp].Int()) & 0x1f))
+				pc++
+				continue
+			case 0x79: // lshl
+				sp--
+				setLong(slots, sp-2, getLong(slots, sp-2)<<(uint32(slots[sp].Int())&0x3f))
+				pc++
+				continue
+			case 0x7a: // ishr
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() >> (uint32(slots[sp].Int()) & 0x1f))
+				pc++
+				continue
+			case 0x7b: // lshr
+				sp--
+				setLong(slots, sp-2, getLong(slots, sp-2)>>(uint32(slots[sp].Int())&0x3f))
+				pc++
+				continue
+			case 0x7c: // iushr
+				sp--
+				slots[sp-1] = rtda.IntSlot(int32(uint32(slots[sp-1].Int()) >> (uint32(slots[sp].Int()) & 0x1f)))
+				pc++
+				continue
+			case 0x7d: // lushr
+				sp--
+				setLong(slots, sp-2, int64(uint64(getLong(slots, sp-2))>>(uint32(slots[sp].Int())&0x3f)))
+				pc++
+				continue
+			case 0x7e: // iand
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() & slots[sp].Int())
+				pc++
+				continue
+			case 0x7f: // land
+				sp -= 2
+				setLong(slots, sp-2, getLong(slots, sp-2)&getLong(slots, sp))
+				pc++
+				continue
+			case 0x80: // ior
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() | slots[sp].Int())
+				pc++
+				continue
+			case 0x81: // lor
+				sp -= 2
+				setLong(slots, sp-2, getLong(slots, sp-2)|getLong(slots, sp))
+				pc++
+				continue
+			case 0x82: // ixor
+				sp--
+				slots[sp-1] = rtda.IntSlot(slots[sp-1].Int() ^ slots[sp].Int())
+				pc++
+				continue
+			case 0x83: // lxor
+				sp -= 2
+				setLong(slots, sp-2, getLong(slots, sp-2)^getLong(slots, sp))
+				pc++
+				continue
+			case 0x84: // iinc
+				index := code[pc+1]
+				locals[index] = rtda.IntSlot(locals[index].Int() + int32(int8(code[pc+2])))
+				pc += 3
+				continue
+
+			// conversions 和conversions包里的指令一样直接用Go的类型转换
+			case 0x85: // i2l
+				setLong(slots, sp-1, int64(slots[sp-1].Int()))
+				sp++
+				pc++
+				continue
+			case 0x86: // i2f
+				slots[sp-1] = rtda.FloatSlot(float32(slots[sp-1].Int()))
+				pc++
+				continue
+			case 0x87: // i2d
+				setDouble(slots, sp-1, float64(slots[sp-1].Int()))
+				sp++
+				pc++
+				continue
+			case 0x88: // l2i
+				sp--
+				slots[sp-1] = rtda.IntSlot(int32(getLong(slots, sp-1)))
+				pc++
+				continue
+			case 0x89: // l2f
+				sp--
+				slots[sp-1] = rtda.FloatSlot(float32(getLong(slots, sp-1)))
+				pc++
+				continue
+			case 0x8a: // l2d
+				setDouble(slots, sp-2, float64(getLong(slots, sp-2)))
+				pc++
+				continue
+			case 0x8b: // f2i
+				slots[sp-1] = rtda.IntSlot(int32(slots[sp-1].Float()))
+				pc++
+				continue
+			case 0x8c: // f2l
+				setLong(slots, sp-1, int64(slots[sp-1].Float()))
+				sp++
+				pc++
+				continue
+			case 0x8d: // f2d
+				setDouble(slots, sp-1, float64(slots[sp-1].Float()))
+				sp++
+				pc++
+				continue
+			case 0x8e: // d2i
+				sp--
+				slots[sp-1] = rtda.IntSlot(int32(getDouble(slots, sp-1)))
+				pc++
+				continue
+			case 0x8f: // d2l
+				setLong(slots, sp-2, int64(getDouble(slots, sp-2)))
+				pc++
+				continue
+			case 0x90: // d2f
+				sp--
+				slots[sp-1] = rtda.FloatSlot(float32(getDouble(slots, sp-1)))
+				pc++
+				continue
+			case 0x91: // i2b
+				slots[sp-1] = rtda.IntSlot(int32(int8(slots[sp-1].Int())))
+				pc++
+				continue
+			case 0x92: // i2c
+				slots[sp-1] = rtda.IntSlot(int32(uint16(slots[sp-1].Int())))
+				pc++
+				continue
+			case 0x93: // i2s
+				slots[sp-1] = rtda.IntSlot(int32(int16(slots[sp-1].Int())))
+				pc++
+				continue
+
+			// comparisons
+			case 0x94: // lcmp
+				v2 := getLong(slots, sp-2)
+				v1 := getLong(slots, sp-4)
+				sp -= 3
+				slots[sp-1] = rtda.IntSlot(lcmp(v1, v2))
+				pc++
+				continue
+			case 0x95, 0x96: // fcmpl fcmpg
+				sp--
+				slots[sp-1] = rtda.IntSlot(fcmp(float64(slots[sp-1].Float()), float64(slots[sp].Float()), opcode == 0x96))
+				pc++
+				continue
+			case 0x97, 0x98: // dcmpl dcmpg
+				v2 := getDouble(slots, sp-2)
+				v1 := getDouble(slots, sp-4)
+				sp -= 3
+				slots[sp-1] = rtda.IntSlot(fcmp(v1, v2, opcode == 0x98))
+				pc++
+				continue
+			case 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f, 0xa0, 0xa1, 0xa2,
+				0xa3, 0xa4, 0xa5, 0xa6, 0xa7, 0xc6, 0xc7: // if<cond> if_icmp<cond> if_acmp<cond> goto ifnull ifnonnull
+				var taken bool
+				switch {
+				case opcode <= 0x9e:
+					sp--
+					taken = ifcond(opcode-0x99, slots[sp].Int(), 0)
+				case opcode <= 0xa4:
+					sp -= 2
+					taken = ifcond(opcode-0x9f, slots[sp].Int(), slots[sp+1].Int())
+				case opcode <= 0xa6:
+					sp -= 2
+					taken = (slots[sp].Ref() == slots[sp+1].Ref()) == (opcode == 0xa5)
+				case opcode == 0xa7:
+					taken = true
+				default:
+					sp--
+					taken = (slots[sp].Ref() == nil) == (opcode == 0xc6)
+				}
+				if !taken {
+					pc += 3
+					continue
+				}
+				offset := branchOffset(code, pc)
+				pc += offset
+				// 方法变热以后回到外层循环 从跳转目标进入编译好的代码
+				if offset < 0 && useJIT && countBackedge(method) {
+					frame.SetNextPC(pc)
+					stack.SetSize(sp)
+					break interp
+				}
+				continue
+
+			// references 字段还没有解析、是静态或者final字段、对象为null时交给原来的指令
+			case 0xb4: // getfield
+				index := uint(code[pc+1])<<8 | uint(code[pc+2])
+				field := cp.GetConstant(index).(*heap.FieldRef).Field()
+				ref := slots[sp-1].Ref()
+				if field == nil || ref == nil || field.IsStatic() || field.IsReferent() {
+					break dispatch
+				}
+				slotId := field.SlotId()
+				switch field.Descriptor()[0] {
+				case 'J', 'D':
+					slots[sp-1], slots[sp] = rtda.LongSlots(ref.GetLongField(slotId))
+					sp++
+				case 'L', '[':
+					slots[sp-1] = rtda.RefSlot(ref.GetRefField(slotId))
+				default:
+					slots[sp-1] = rtda.IntSlot(ref.GetIntField(slotId))
+				}
+				pc += 3
+				continue
+			case 0xb5: // putfield
+				index := uint(code[pc+1])<<8 | uint(code[pc+2])
+				field := cp.GetConstant(index).(*heap.FieldRef).Field()
+				if field == nil || field.IsStatic() || field.IsFinal() || field.IsReferent() {
+					break dispatch
+				}
+				slotId := field.SlotId()
+				switch field.Descriptor()[0] {
+				case 'J', 'D':
+					ref := slots[sp-3].Ref()
+					if ref == nil {
+						break dispatch
+					}
+					ref.SetLongField(slotId, getLong(slots, sp-2))
+					sp -= 3
+				case 'L', '[':
+					ref := slots[sp-2].Ref()
+					if ref == nil {
+						break dispatch
+					}
+					ref.SetRefField(slotId, slots[sp-1].Ref())
+					sp -= 2
+				default:
+					ref := slots[sp-2].Ref()
+					if ref == nil {
+						break dispatch
+					}
+					ref.SetIntField(slotId, slots[sp-1].Int())
+					sp -= 2
+				}
+				pc += 3
+				continue
+			case 0xbe: // arraylength
+				if arr := slots[sp-1].Ref(); arr != nil {
+					slots[sp-1] = rtda.IntSlot(arr.ArrayLength())
+					pc++
+					continue
+				}
+			}
+
+			// 其余的指令由原来的实现执行
+			thread.SetPC(pc)
+			stack.SetSize(sp)
+			inst, nextPC := instructions.Decode(method, pc)
+			frame.SetNextPC(nextPC)
+			inst.Execute(frame)
+			instructions.Quicken(method, pc, inst)
+
+			// 没有调用、返回或者抛出异常 继续用这个栈帧的状态 方法编译过时回到外层循环进入编译好的代码
+			if !thread.IsStackEmpty() && thread.CurrentFrame() == frame && !(useJIT && jitted(method) != nil) {
+				pc = frame.NextPC()
+				sp = stack.Size()
+				continue
+			}
+			break
+		}
+
+		if finished(thread, barrier) {
+			return
+		}
+	}
+}
+
+// 数组引用在栈顶下面一个槽 为null时返回nil
+// 空数组也返回nil 任何下标都越界 同样交给原来的指令
+func arrayData[T heap.PrimitiveElement](slots []rtda.Slot, top uint) []T {
+	if arr := slots[top-2].Ref(); arr != nil {
+		return heap.ArrayElements[T](arr)
+	}
+	return nil
+}
+
+func arrayRefs(slots []rtda.Slot, top uint) []*heap.Object {
+	if arr := slots[top-2].Ref(); arr != nil {
+		return arr.Refs()
+	}
+	return nil
+}
+
+func getLong(slots []rtda.Slot, index uint) int64 {
+	return rtda.SlotsLong(slots[index], slots[index+1])
+}
+
+func setLong(slots []rtda.Slot, index uint, val int64) {
+	slots[index], slots[index+1] = rtda.LongSlots(val)
+}
+
+func getDouble(slots []rtda.Slot, index uint) float64 {
+	return math.Float64frombits(uint64(getLong(slots, index)))
+}
+
+func setDouble(slots []rtda.Slot, index uint, val float64) {
+	setLong(slots, index, int64(math.Float64bits(val)))
+}
+
+// 跳转指令的偏移量是相对于指令本身的
+func branchOffset(code []byte, pc int) int {
+	return int(int16(uint16(code[pc+1])<<8 | uint16(code[pc+2])))
+}
+
+// cond依次是eq ne lt ge gt le
+func ifcond(cond byte, v1, v2 int32) bool {
+	switch cond {
+	case 0:
+		return v1 == v2
+	case 1:
+		return v1 != v2
+	case 2:
+		return v1 < v2
+	case 3:
+		return v1 >= v2
+	case 4:
+		return v1 > v2
+	default:
+		return v1 <= v2
+	}
+}
+
+func lcmp(v1, v2 int64) int32 {
+	if v1 > v2 {
+		return 1
+	} else if v1 == v2 {
+		return 0
+	}
+	return -1
+}
+
+// 有NaN时fcmpg/dcmpg得到1 fcmpl/dcmpl得到-1
+func fcmp(v1, v2 float64, gFlag bool) int32 {
+	if v1 > v2 {
+		return 1
+	} else if v1 == v2 {
+		return 0
+	} else if v1 < v2 {
+		return -1
+	} else if gFlag {
+		return 1
+	}
+	return -1
+}
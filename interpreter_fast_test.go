@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"jvm/classpath"
+	"jvm/instructions/base"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// -Xint和-Xint:fast的差分测试
+// fastLoop自己实现了常用指令 结果必须和逐条Execute的loop完全一样
+
+type testCall struct {
+	name, descriptor string
+	args             []rtda.Slot
+}
+
+// 类T: 每个静态方法是一段覆盖某一类指令的小程序
+func newInterpreterTestClass(t testing.TB) *testClass {
+	cp := newTestConstantPool()
+	objectInit := cp.methodRef("java/lang/Object", "<init>", "()V")
+	tInit := cp.methodRef("T", "<init>", "()V")
+	fib := cp.methodRef("T", "fib", "(I)I")
+	get := cp.methodRef("T", "get", "()I")
+	x := cp.fieldRef("T", "x", "I")
+	l := cp.fieldRef("T", "l", "J")
+	next := cp.fieldRef("T", "next", "LT;")
+	s := cp.fieldRef("T", "s", "J")
+	tClass := cp.class("T")
+
+	// static int ints(int n) 整数运算、移位和窄化转换
+	ints := newBytecode().
+		op(op_iconst_0, op_istore_1).
+		op(op_iconst_0, op_istore_2).
+		mark("loop").op(op_iload_2, op_iload_0).jump(op_if_icmpge, "end").
+		op(op_iload_1).
+		op(op_iload_2, op_iload_2, op_imul).
+		op(op_iload_2, op_iconst_1, op_iushr).
+		op(op_ixor, op_iadd).
+		op(op_iload_2, op_bipush, 7, op_irem, op_isub).
+		op(op_iload_2, op_iconst_3, op_ishl, op_iload_2, op_ineg, op_ior, op_ixor).
+		op(op_iload_2, op_iconst_2, op_ishr, op_iadd).
+		op(op_istore_1).
+		op(op_iinc, 2, 1).jump(op_goto, "loop").
+		mark("end").
+		op(op_iload_1, op_iload_1, op_i2b, op_iload_1, op_i2c, op_iadd, op_iload_1, op_i2s, op_iadd, op_ixor).
+		op(op_ireturn)
+
+	// static long longs(long x, int n) long运算 移位次数是int 结果是long
+	longs := newBytecode().
+		mark("loop").op(op_iload_2).jump(op_ifle, "end").
+		op(op_lload_0, op_iconst_3, op_lshl).
+		op(op_lload_0, op_bipush, 7, op_lushr, op_lxor).
+		op(op_lload_0, op_bipush, 33, op_lshr, op_ladd).
+		op(op_lload_0, op_iload_2, op_i2l, op_ldiv, op_lsub).
+		op(op_lload_0, op_sipush, 0x27, 0x17, op_i2l, op_lrem, op_ladd). // x % 10007
+		op(op_lstore_0).
+		op(op_lload_0, op_lconst_0, op_lcmp).jump(op_ifge, "positive").
+		op(op_lload_0, op_lneg, op_lstore_0).
+		mark("positive").
+		op(op_lload_0, op_lload_0, op_iload_2, op_i2l, op_lmul, op_lxor, op_lstore_0).
+		op(op_iinc, 2, 0xff).jump(op_goto, "loop").
+		mark("end").
+		op(op_lload_0, op_lreturn)
+
+	// static double doubles(double d, int n) 浮点运算、比较和转换
+	doubles := newBytecode().
+		op(op_fconst_1, op_fstore_3).
+		mark("loop").op(op_iload_2).jump(op_ifle, "end").
+		op(op_dload_0, op_iload_2, op_i2d, op_dadd).
+		op(op_dload_0, op_dconst_1, op_dadd, op_ddiv).
+		op(op_fload_3, op_f2d, op_dadd, op_dstore_0).
+		op(op_dload_0, op_dconst_1, op_dcmpl).jump(op_ifle, "small").
+		op(op_dload_0, op_dconst_1, op_drem, op_dconst_1, op_dadd, op_dstore_0).
+		mark("small").
+		op(op_fload_3, op_iload_2, op_i2f, op_fmul, op_fconst_2, op_fadd).
+		op(op_fload_3, op_fconst_1, op_fadd, op_fdiv, op_fstore_3).
+		op(op_iinc, 2, 0xff).jump(op_goto, "loop").
+		mark("end").
+		op(op_dload_0, op_fload_3, op_f2d, op_dmul).
+		op(op_dload_0, op_d2f, op_f2i, op_i2d, op_dsub).
+		op(op_dload_0, op_d2l, op_l2d, op_dadd).
+		op(op_dreturn)
+
+	// static int arrays(int n) 各种基本类型数组 char和byte会截断
+	arrays := newBytecode().
+		op(op_iload_0, op_newarray, t_int, op_astore_1).
+		op(op_iload_0, op_newarray, t_long, op_astore_2).
+		op(op_iload_0, op_newarray, t_char, op_astore_3).
+		op(op_iload_0, op_newarray, t_byte, op_astore, 4).
+		op(op_iconst_0, op_istore, 5).
+		mark("fill").op(op_iload, 5, op_iload_0).jump(op_if_icmpge, "filled").
+		op(op_aload_1, op_iload, 5, op_iload, 5, op_bipush, 7, op_imul, op_iastore).
+		op(op_aload_2, op_iload, 5, op_iload, 5, op_i2l, op_dup2, op_lmul, op_lastore).
+		op(op_aload_3, op_iload, 5, op_iload, 5, op_sipush, 0x01, 0x2c, op_imul, op_castore).  // i * 300
+		op(op_aload, 4, op_iload, 5, op_iload, 5, op_sipush, 0xfc, 0x18, op_imul, op_bastore). // i * -1000
+		op(op_iinc, 5, 1).jump(op_goto, "fill").
+		mark("filled").
+		op(op_iconst_0, op_istore, 6, op_iconst_0, op_istore, 5).
+		mark("sum").op(op_iload, 5, op_aload_1, op_arraylength).jump(op_if_icmpge, "end").
+		op(op_iload, 6).
+		op(op_aload_1, op_iload, 5, op_iaload, op_iadd).
+		op(op_aload_2, op_iload, 5, op_laload, op_l2i, op_iadd).
+		op(op_aload_3, op_iload, 5, op_caload, op_iadd).
+		op(op_aload, 4, op_iload, 5, op_baload, op_iadd).
+		op(op_istore, 6).
+		op(op_iinc, 5, 1).jump(op_goto, "sum").
+		mark("end").
+		op(op_iload, 6, op_ireturn)
+
+	// static int fib(int n) 递归的invokestatic
+	fibCode := newBytecode().
+		op(op_iload_0, op_iconst_2).jump(op_if_icmpge, "recurse").
+		op(op_iload_0, op_ireturn).
+		mark("recurse").
+		op(op_iload_0, op_iconst_1, op_isub).u2(op_invokestatic, fib).
+		op(op_iload_0, op_iconst_2, op_isub).u2(op_invokestatic, fib).
+		op(op_iadd, op_ireturn)
+
+	// static int switches(int n) tableswitch和lookupswitch
+	switches := newBytecode().
+		op(op_iconst_0, op_istore_1).
+		op(op_iconst_0, op_istore_2).
+		mark("loop").op(op_iload_2, op_iload_0).jump(op_if_icmpge, "end").
+		op(op_iload_2, op_bipush, 7, op_irem, op_iconst_2, op_isub).
+		tableswitch("other", 0, "c0", "c1", "c2", "c3").
+		mark("c0").op(op_iinc, 1, 1).jump(op_goto, "next").
+		mark("c1").op(op_iinc, 1, 10).jump(op_goto, "next").
+		mark("c2").op(op_iload_1, op_iconst_2, op_imul, op_istore_1).jump(op_goto, "next").
+		mark("c3").op(op_iload_1, op_iconst_3, op_isub, op_istore_1).jump(op_goto, "next").
+		mark("other").op(op_iload_2, op_bipush, 10, op_irem).
+		lookupswitch("default", []int32{1, 5, 9}, "k1", "k5", "k9").
+		mark("k1").op(op_iinc, 1, 50).jump(op_goto, "next").
+		mark("k5").op(op_iinc, 1, 100).jump(op_goto, "next").
+		mark("k9").op(op_iload_1, op_iconst_m1, op_ixor, op_istore_1).jump(op_goto, "next").
+		mark("default").op(op_iinc, 1, 0xff).
+		mark("next").op(op_iinc, 2, 1).jump(op_goto, "loop").
+		mark("end").
+		op(op_iload_1, op_ireturn)
+
+	// static long objects(int n) 对象、字段、静态字段、虚方法和监视器
+	objects := newBytecode().
+		u2(op_new, tClass).op(op_dup).u2(op_invokespecial, tInit).op(op_astore_1).
+		op(op_aload_1, op_aload_1).u2(op_putfield, next).
+		mark("loop").op(op_iload_0).jump(op_ifle, "end").
+		op(op_aload_1, op_monitorenter).
+		op(op_aload_1).u2(op_getfield, next).op(op_dup).u2(op_getfield, x).
+		op(op_iload_0, op_iadd).u2(op_putfield, x).
+		op(op_aload_1, op_dup).u2(op_getfield, l).
+		op(op_aload_1).u2(op_invokevirtual, get).op(op_i2l, op_bipush, 33, op_lshl, op_ladd).
+		u2(op_putfield, l).
+		op(op_aload_1, op_monitorexit).
+		u2(op_getstatic, s).op(op_aload_1).u2(op_getfield, l).op(op_ladd).u2(op_putstatic, s).
+		op(op_aload_1).u2(op_instanceof, tClass).jump(op_ifeq, "end").
+		op(op_aload_1).u2(op_checkcast, tClass).op(op_pop).
+		op(op_iinc, 0, 0xff).jump(op_goto, "loop").
+		mark("end").
+		u2(op_getstatic, s).op(op_aload_1).u2(op_getfield, x).op(op_i2l, op_ladd, op_lreturn)
+
+	init := newBytecode().op(op_aload_0).u2(op_invokespecial, objectInit).op(op_return)
+	getCode := newBytecode().op(op_aload_0).u2(op_getfield, x).op(op_ireturn)
+
+	return &testClass{
+		flags:     0x21,
+		name:      "T",
+		superName: "java/lang/Object",
+		cp:        cp,
+		fields: []testField{
+			{0x0, "x", "I"},
+			{0x0, "l", "J"},
+			{0x0, "next", "LT;"},
+			{0x8, "s", "J"},
+		},
+		methods: []testMethod{
+			{0x1, "<init>", "()V", 1, 1, init.bytes(t)},
+			{0x1, "get", "()I", 1, 1, getCode.bytes(t)},
+			{0x9, "ints", "(I)I", 8, 3, ints.bytes(t)},
+			{0x9, "longs", "(JI)J", 8, 3, longs.bytes(t)},
+			{0x9, "doubles", "(DI)D", 8, 4, doubles.bytes(t)},
+			{0x9, "arrays", "(I)I", 8, 7, arrays.bytes(t)},
+			{0x9, "fib", "(I)I", 4, 1, fibCode.bytes(t)},
+			{0x9, "switches", "(I)I", 4, 3, switches.bytes(t)},
+			{0x9, "objects", "(I)J", 8, 2, objects.bytes(t)},
+		},
+	}
+}
+
+func interpreterTestCalls() []testCall {
+	xLow, xHigh := rtda.LongSlots(123456789)
+	dLow, dHigh := rtda.DoubleSlots(1.25)
+	return []testCall{
+		{"ints", "(I)I", []rtda.Slot{rtda.IntSlot(100000)}},
+		{"longs", "(JI)J", []rtda.Slot{xLow, xHigh, rtda.IntSlot(5000)}},
+		{"doubles", "(DI)D", []rtda.Slot{dLow, dHigh, rtda.IntSlot(5000)}},
+		{"arrays", "(I)I", []rtda.Slot{rtda.IntSlot(3000)}},
+		{"fib", "(I)I", []rtda.Slot{rtda.IntSlot(20)}},
+		{"switches", "(I)I", []rtda.Slot{rtda.IntSlot(10000)}},
+		{"objects", "(I)J", []rtda.Slot{rtda.IntSlot(10000)}},
+	}
+}
+
+// 每次都用新的类加载器和堆 静态字段从零开始
+func runInterpreterTestCalls(t *testing.T, jre string, fast, jit bool) []int64 {
+	return runInterpreterTestCallsOn(t, jre, fast, jit, nil)
+}
+
+// compiled不为nil时 调用完以后检查这些方法有没有被JIT编译
+func runInterpreterTestCallsOn(t *testing.T, jre string, fast, jit bool, compiled []string) []int64 {
+	fastInterpreter, useJIT = fast, jit
+	defer func() { fastInterpreter, useJIT = false, false }()
+	base.SetInterpreter(func(thread *rtda.Thread, barrier *rtda.Frame) {
+		run(thread, false, barrier)
+	})
+	if jit {
+		base.SetInvocationCounter(countInvocation)
+		defer base.SetInvocationCounter(nil)
+	}
+
+	loader := heap.NewClassLoader(classpath.Parse(jre, jre), heap.NewHeap(0), false, false, nil)
+	thread := rtda.NewThread()
+	thread.Acquire()
+	defer thread.Release()
+
+	class := loader.LoadClass("T")
+	var results []int64
+	for _, call := range interpreterTestCalls() {
+		method := class.GetStaticMethod(call.name, call.descriptor)
+		var result int64
+		var thrown *heap.Object
+		if strings.HasSuffix(call.descriptor, "J") || strings.HasSuffix(call.descriptor, "D") {
+			result, thrown = base.InvokeAndWaitWide(thread, method, call.args)
+		} else {
+			var slot rtda.Slot
+			slot, thrown = base.InvokeAndWait(thread, method, call.args)
+			result = int64(slot.Int())
+		}
+		if thrown != nil {
+			t.Fatalf("%s%s threw %s", call.name, call.descriptor, thrown.Class().JavaName())
+		}
+		results = append(results, result)
+	}
+	for _, name := range compiled {
+		for _, call := range interpreterTestCalls() {
+			if call.name == name && jitted(class.GetStaticMethod(call.name, call.descriptor)) == nil {
+				t.Errorf("%s%s was not compiled", call.name, call.descriptor)
+			}
+		}
+	}
+	return results
+}
+
+func TestFastInterpreterMatchesDefault(t *testing.T) {
+	jre := writeTestJre(t, newInterpreterTestClass(t))
+	want := runInterpreterTestCalls(t, jre, false, false)
+	got := runInterpreterTestCalls(t, jre, true, false)
+	for i, call := range interpreterTestCalls() {
+		if got[i] != want[i] {
+			t.Errorf("%s%s: -Xint:fast returned %#x, -Xint returned %#x",
+				call.name, call.descriptor, got[i], want[i])
+		}
+	}
+}
+
+// fib递归调用超过invocationThreshold次 其余方法的循环回边超过backedgeThreshold次
+func TestJITMatchesInterpreter(t *testing.T) {
+	jre := writeTestJre(t, newInterpreterTestClass(t))
+	want := runInterpreterTestCalls(t, jre, false, false)
+	got := runInterpreterTestCallsOn(t, jre, false, true, []string{"ints", "fib"})
+	for i, call := range interpreterTestCalls() {
+		if got[i] != want[i] {
+			t.Errorf("%s%s: -XX:+UseJIT returned %#x, -Xint returned %#x",
+				call.name, call.descriptor, got[i], want[i])
+		}
+	}
+}
+
+// 输出和时间、内存、GC或者外部环境有关的例子 两次运行本来就可能不一样
+var nondeterministicExamples = map[string]bool{
+	"jvmgo.book.ch06.FieldLayoutTest":    true, // 打印耗时
+	"jvmgo.book.ch09.ObjectTest":         true, // 打印hashCode
+	"jvmgo.book.ch10.OutOfMemoryTest":    true, // 打印堆的使用量
+	"jvmgo.book.ch10.FinalizeTest":       true, // 依赖GC的时机
+	"jvmgo.book.ch10.ReferenceTest":      true,
+	"jvmgo.book.ch11.ClassUnloadingTest": true,
+	"jvmgo.book.ch10.SignalTest":         true, // 给自己发信号
+	"jvmgo.book.ch10.SocketTest":         true, // 需要网络
+}
+
+// 用两种解释器运行example里的每个例子 比较输出
+// 需要JDK8的jre(JAVA_HOME或者./jre)和编译好的例子(JVMGO_EXAMPLE_CLASSES或者gradle build的输出)
+func TestExamplesMatchAcrossInterpreters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs every example twice")
+	}
+	jre := exampleJre()
+	if jre == "" {
+		t.Skip("no jre: set JAVA_HOME")
+	}
+	classes := exampleClasses()
+	if classes == "" {
+		t.Skip("examples not compiled: run gradle build in example or set JVMGO_EXAMPLE_CLASSES")
+	}
+	jvmgo := filepath.Join(t.TempDir(), "jvmgo")
+	if out, err := exec.Command("go", "build", "-o", jvmgo, ".").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+
+	for _, example := range findExamples(t, filepath.Join("example", "src", "main", "java")) {
+		if nondeterministicExamples[example.class] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(classes, strings.ReplaceAll(example.class, ".", "/")+".class")); err != nil {
+			continue
+		}
+		t.Run(example.class, func(t *testing.T) {
+			run := func(interpreter string) string {
+				args := append([]string{"-Xjre", jre, "-cp", classes, interpreter}, example.flags...)
+				return runExample(t, jvmgo, append(args, example.class)...)
+			}
+			want, got := run("-Xint"), run("-Xint:fast")
+			if got != want {
+				t.Errorf("-Xint:fast output differs from -Xint\n--- -Xint\n%s\n--- -Xint:fast\n%s", want, got)
+			}
+		})
+	}
+}
+
+type example struct {
+	class string
+	flags []string // 例子开头注释"// jvmgo <flags> <class>"里的选项
+}
+
+func findExamples(t *testing.T, root string) []example {
+	var examples []example
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".java") {
+			return err
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(source, []byte("public static void main(")) {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, strings.TrimSuffix(path, ".java"))
+		examples = append(examples, example{
+			class: strings.ReplaceAll(filepath.ToSlash(rel), "/", "."),
+			flags: exampleFlags(string(source)),
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return examples
+}
+
+// 解释器和-verbose选项由测试决定 其他选项(-Xss、-Xmx等)照用
+func exampleFlags(source string) []string {
+	for _, line := range strings.Split(source, "\n") {
+		if !strings.HasPrefix(line, "// jvmgo ") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "// jvmgo "))
+		var flags []string
+		for _, field := range fields[:len(fields)-1] {
+			if !strings.HasPrefix(field, "-Xint") && !strings.HasPrefix(field, "-verbose") {
+				flags = append(flags, field)
+			}
+		}
+		return flags
+	}
+	return nil
+}
+
+// 退出码也算在输出里 抛出未捕获异常的例子两边都应该失败
+func runExample(t *testing.T, jvmgo string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, jvmgo, args...)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		t.Fatalf("%v timed out", args)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return string(out) + "\nexit status " + exitErr.Error()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func exampleJre() string {
+	for _, dir := range []string{"jre", filepath.Join(os.Getenv("JAVA_HOME"), "jre")} {
+		if _, err := os.Stat(filepath.Join(dir, "lib", "rt.jar")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+func exampleClasses() string {
+	for _, dir := range []string{
+		os.Getenv("JVMGO_EXAMPLE_CLASSES"),
+		filepath.Join("example", "build", "classes", "java", "main"),
+		filepath.Join("example", "build", "classes", "main"),
+	} {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			abs, _ := filepath.Abs(dir)
+			return abs
+		}
+	}
+	return ""
+}
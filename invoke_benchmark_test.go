@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"jvm/classpath"
+	"jvm/instructions/base"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"testing"
+)
+
+// 和InvokeVirtualTest、HashMapTest一样 每个调用点的接收者类型不变
+// Shape <- Square <- Cube 都实现了接口Area 每个类另外有fillerMethods个方法 让按名字查找的代价接近JDK里的类
+const fillerMethods = 12
+
+func newInvokeBenchmarkClasses(t testing.TB) []*testClass {
+	iface := &testClass{flags: 0x601, name: "Area", superName: "java/lang/Object",
+		methods: []testMethod{{0x401, "area", "()I", 0, 0, nil}}}
+	newShape := func(name, superName string, area int) *testClass {
+		cp := newTestConstantPool()
+		class := &testClass{flags: 0x21, name: name, superName: superName, interfaces: []string{"Area"}, cp: cp}
+		for i := 0; i < fillerMethods; i++ {
+			class.methods = append(class.methods, testMethod{0x1, fmt.Sprintf("%s%d", name, i), "()I", 1, 1,
+				[]byte{op_iconst_0, op_ireturn}})
+		}
+		class.methods = append(class.methods, testMethod{0x1, "area", "()I", 1, 1,
+			newBytecode().op(op_bipush, area, op_ireturn).bytes(t)})
+		return class
+	}
+
+	// virtual(LShape;LShape;I)I和iface(LArea;LArea;I)I 循环n次 每次调用两个对象的area()
+	cp := newTestConstantPool()
+	loop := func(invoke func(b *bytecode) *bytecode) []byte {
+		b := newBytecode().op(op_iconst_0, op_istore, 3).
+			mark("loop").op(op_iload_2).jump(op_ifle, "end").
+			op(op_iload, 3, op_aload_0)
+		invoke(b).op(op_iadd, op_aload_1)
+		invoke(b).op(op_iadd, op_istore, 3).
+			op(op_iinc, 2, 0xff).jump(op_goto, "loop").
+			mark("end").op(op_iload, 3, op_ireturn)
+		return b.bytes(t)
+	}
+	area := cp.methodRef("Shape", "area", "()I")
+	iarea := cp.interfaceMethodRef("Area", "area", "()I")
+	bench := &testClass{flags: 0x21, name: "Bench", superName: "java/lang/Object", cp: cp,
+		methods: []testMethod{
+			{0x9, "virtual", "(LShape;LShape;I)I", 3, 4, loop(func(b *bytecode) *bytecode { return b.u2(op_invokevirtual, area) })},
+			{0x9, "iface", "(LArea;LArea;I)I", 3, 4, loop(func(b *bytecode) *bytecode { return b.u2(op_invokeinterface, iarea).op(1, 0) })},
+		},
+	}
+	return []*testClass{iface, newShape("Shape", "java/lang/Object", 1),
+		newShape("Square", "Shape", 2), newShape("Cube", "Square", 3), bench}
+}
+
+// 每次调用方法循环1000次 ns/op除以2000就是一次调用的时间
+func benchmarkInvoke(b *testing.B, method, descriptor string) {
+	jre := writeTestJre(b, newInvokeBenchmarkClasses(b)...)
+	base.SetInterpreter(func(thread *rtda.Thread, barrier *rtda.Frame) {
+		run(thread, false, barrier)
+	})
+	loader := heap.NewClassLoader(classpath.Parse(jre, jre), heap.NewHeap(0), false, false, nil)
+	thread := rtda.NewThread()
+	thread.Acquire()
+	defer thread.Release()
+
+	square, cube := loader.LoadClass("Square").NewObject(), loader.LoadClass("Cube").NewObject()
+	m := loader.LoadClass("Bench").GetStaticMethod(method, descriptor)
+	args := []rtda.Slot{rtda.RefSlot(square), rtda.RefSlot(cube), rtda.IntSlot(1000)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, thrown := base.InvokeAndWait(thread, m, args)
+		if thrown != nil || result.Int() != 5000 {
+			b.Fatalf("%s returned %d, %v, want 5000", method, result.Int(), thrown)
+		}
+	}
+}
+
+func BenchmarkInvokeVirtual(b *testing.B) {
+	benchmarkInvoke(b, "virtual", "(LShape;LShape;I)I")
+}
+
+func BenchmarkInvokeInterface(b *testing.B) {
+	benchmarkInvoke(b, "iface", "(LArea;LArea;I)I")
+}
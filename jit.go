@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"strings"
+	"time"
+)
+
+// -XX:+UseJIT时 解释执行的热点方法由JIT编译
+// 字节码被翻译成预先绑定了操作数和栈槽下标的Go闭包 按基本块组织
+// 调用、返回、抛异常等不支持的指令 以及数组越界、除数为0这些需要抛异常的情况
+// 先把pc和操作数栈的深度写回rtda.Frame 再交给解释器执行
+var useJIT bool
+
+// -XX:+PrintCompilation
+var printCompilation bool
+
+// 和HotSpot的client编译器一样 调用1500次或者向回跳转14000次以后编译
+const (
+	invocationThreshold = 1500
+	backedgeThreshold   = 14000
+)
+
+var jitStartTime = time.Now()
+var compileCount = 0
+
+// 编译好的代码
+type compiledCode struct {
+	blocks []*basicBlock // 按pc索引 只有基本块的入口不为nil
+	depths []int         // 执行每条指令之前操作数栈的深度 到不了的指令是-1
+}
+
+// 不能编译的方法也记下来 不再重复尝试
+var notCompilable = &compiledCode{}
+
+// 基本块里的指令依次执行 最后由next决定下一个基本块
+type basicBlock struct {
+	ops  []jitOp
+	pcs  []int // 每个op对应的指令
+	next func(locals, stack []rtda.Slot) int
+}
+
+// 返回false表示需要去优化 由解释器重新执行这条指令(通常会抛出异常)
+type jitOp func(locals, stack []rtda.Slot) bool
+
+// next返回负数表示回到解释器 由exitTo编码
+func exitTo(pc int) int {
+	return -pc - 1
+}
+
+func (self *basicBlock) execute(locals, stack []rtda.Slot) int {
+	for i, op := range self.ops {
+		if !op(locals, stack) {
+			return exitTo(self.pcs[i])
+		}
+	}
+	return self.next(locals, stack)
+}
+
+func (self *compiledCode) canEnter(pc int) bool {
+	return pc < len(self.blocks) && self.blocks[pc] != nil
+}
+
+// 从栈帧当前的pc开始执行 直到需要解释器处理的指令
+func (self *compiledCode) run(thread *rtda.Thread, frame *rtda.Frame) {
+	locals := frame.LocalVars()
+	stack := frame.OperandStack()
+	slots := stack.Slots()
+	pc := frame.NextPC()
+	for count := 1; ; count++ {
+		next := self.blocks[pc].execute(locals, slots)
+		if next < 0 {
+			pc = -next - 1
+			break
+		}
+		pc = next
+		if count%yieldInterval == 0 {
+			frame.SetNextPC(pc)
+			thread.SetPC(pc)
+			stack.SetSize(uint(self.depths[pc]))
+			thread.Yield()
+		}
+	}
+	frame.SetNextPC(pc)
+	thread.SetPC(pc)
+	stack.SetSize(uint(self.depths[pc]))
+}
+
+// 已经编译好的代码 还没有编译或者不能编译时返回nil
+func jitted(method *heap.Method) *compiledCode {
+	if code, ok := method.CompiledCode().(*compiledCode); ok && code != notCompilable {
+		return code
+	}
+	return nil
+}
+
+// 解释器换到另一个栈帧(调用、返回或者抛出异常)以后 以及回边触发编译以后调用
+// 方法编译过并且pc是基本块的入口时 执行编译好的代码
+func enterCompiled(thread *rtda.Thread, frame *rtda.Frame) {
+	if code := jitted(frame.Method()); code != nil && code.canEnter(frame.NextPC()) {
+		code.run(thread, frame)
+	}
+}
+
+// base.InvokeMethod压入新栈帧以后调用 向回跳到偏移0的指令不算调用
+func countInvocation(method *heap.Method) {
+	if method.CompiledCode() == nil && method.IncInvocationCount() == invocationThreshold {
+		compileMethod(method, false)
+	}
+}
+
+// 解释器向回跳转时调用 方法已经(或者刚刚)编译好时返回true
+func countBackedge(method *heap.Method) bool {
+	if method.CompiledCode() == nil {
+		if method.IncBackedgeCount() != backedgeThreshold {
+			return false
+		}
+		compileMethod(method, true)
+	}
+	return jitted(method) != nil
+}
+
+// 由回边触发的编译和HotSpot一样用%标记
+func compileMethod(method *heap.Method, osr bool) *compiledCode {
+	code, err := newJITCompiler(method).compile()
+	if err != "" {
+		code = notCompilable
+	}
+	method.SetCompiledCode(code)
+
+	compileCount++
+	if printCompilation {
+		attrs := " "
+		if osr {
+			attrs = "%"
+		}
+		fmt.Printf("%7d %5d %s     %s::%s (%d bytes)",
+			time.Since(jitStartTime).Milliseconds(), compileCount, attrs,
+			strings.Replace(method.Class().Name(), "/", ".", -1), method.Name(), len(method.Code()))
+		if err != "" {
+			fmt.Printf("   COMPILE SKIPPED: %s", err)
+		}
+		fmt.Println()
+	}
+	return code
+}
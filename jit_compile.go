@@ -0,0 +1,1262 @@
+package main
+
+import (
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"math"
+	"strings"
+)
+
+// 编译分三步
+// 1. 按指令长度切分字节码 从入口和异常处理器开始推算每条指令执行前操作数栈的深度
+// 2. 把能编译的指令翻译成闭包 栈槽的下标在编译时就确定了 运行时不需要维护栈顶
+// 3. 按跳转目标和回到解释器的位置划分基本块 基本块里的int表达式组合成闭包树(见jit_expr.go)
+type jitCompiler struct {
+	method  *heap.Method
+	class   *heap.Class
+	cp      *heap.ConstantPool
+	code    []byte
+	lengths []int // 每条指令的长度 不是指令开头的位置为0
+	depths  []int
+	leaders []bool
+	ops     []jitOp
+	kinds   []byte
+}
+
+// 指令编译以后的去向
+const (
+	kindOp     = iota // 编译成jitOp 可能去优化
+	kindBranch        // 跳转 结束基本块
+	kindExit          // 交给解释器执行 结束基本块
+)
+
+func newJITCompiler(method *heap.Method) *jitCompiler {
+	code := method.Code()
+	return &jitCompiler{
+		method:  method,
+		class:   method.Class(),
+		cp:      method.Class().ConstantPool(),
+		code:    code,
+		lengths: make([]int, len(code)),
+		depths:  make([]int, len(code)),
+		leaders: make([]bool, len(code)),
+		ops:     make([]jitOp, len(code)),
+		kinds:   make([]byte, len(code)),
+	}
+}
+
+// 不能编译时返回原因
+func (self *jitCompiler) compile() (*compiledCode, string) {
+	if self.method.IsNative() || len(self.code) == 0 {
+		return nil, "no bytecode"
+	}
+	for pc := 0; pc < len(self.code); {
+		length := instructionLength(self.code, pc)
+		if length <= 0 || pc+length > len(self.code) {
+			return nil, "unsupported opcode"
+		}
+		self.lengths[pc] = length
+		pc += length
+	}
+	if err := self.computeDepths(); err != "" {
+		return nil, err
+	}
+
+	self.leaders[0] = true
+	for _, handlerPC := range self.method.ExceptionHandlerPCs() {
+		self.leaders[handlerPC] = true
+	}
+	for pc := 0; pc < len(self.code); pc += self.lengths[pc] {
+		if self.depths[pc] < 0 {
+			continue
+		}
+		if successors, ok := self.branchTargets(pc); ok {
+			self.kinds[pc] = kindBranch
+			for _, target := range successors {
+				self.leaders[target] = true
+			}
+		} else if op, ok := self.compileOp(pc); ok {
+			self.kinds[pc] = kindOp
+			self.ops[pc] = op
+			continue
+		} else {
+			self.kinds[pc] = kindExit
+		}
+		// 解释器执行完以后从下一条指令回到编译好的代码
+		if next := pc + self.lengths[pc]; next < len(self.code) && self.depths[next] >= 0 {
+			self.leaders[next] = true
+		}
+	}
+
+	blocks := make([]*basicBlock, len(self.code))
+	for pc := range self.code {
+		if self.leaders[pc] && self.depths[pc] >= 0 {
+			blocks[pc] = self.buildBlock(pc)
+		}
+	}
+	return &compiledCode{blocks: blocks, depths: self.depths}, ""
+}
+
+func (self *jitCompiler) buildBlock(start int) *basicBlock {
+	block := &basicBlock{}
+	var pending []intExpr
+	pc := start
+	for {
+		if self.kinds[pc] != kindExit && self.fuse(pc, block, &pending) {
+			if block.next != nil {
+				return block // 比较两个表达式的跳转
+			}
+		} else {
+			self.spill(pc, block, &pending, 0)
+			switch self.kinds[pc] {
+			case kindBranch:
+				block.next = self.compileBranch(pc)
+				return block
+			case kindExit:
+				exit := exitTo(pc)
+				block.next = func(locals, stack []rtda.Slot) int { return exit }
+				return block
+			}
+			if op := self.ops[pc]; op != nil {
+				block.add(pc, op)
+			}
+		}
+		pc += self.lengths[pc]
+		if self.leaders[pc] {
+			self.spill(pc, block, &pending, 0)
+			next := pc
+			block.next = func(locals, stack []rtda.Slot) int { return next }
+			return block
+		}
+	}
+}
+
+// 从入口(深度0)和异常处理器(深度1 只有异常对象)开始沿控制流推算
+// 同一条指令从不同路径到达时深度必须相同
+func (self *jitCompiler) computeDepths() string {
+	for i := range self.depths {
+		self.depths[i] = -1
+	}
+	var worklist []int
+	reach := func(pc, depth int) string {
+		if pc < 0 || pc >= len(self.code) || self.lengths[pc] == 0 {
+			return "bad branch target"
+		}
+		if depth < 0 || depth > int(self.method.MaxStack()) {
+			return "bad stack depth"
+		}
+		if self.depths[pc] < 0 {
+			self.depths[pc] = depth
+			worklist = append(worklist, pc)
+		} else if self.depths[pc] != depth {
+			return "inconsistent stack depth"
+		}
+		return ""
+	}
+
+	if err := reach(0, 0); err != "" {
+		return err
+	}
+	for _, handlerPC := range self.method.ExceptionHandlerPCs() {
+		if err := reach(handlerPC, 1); err != "" {
+			return err
+		}
+	}
+	for len(worklist) > 0 {
+		pc := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		effect, err := self.stackEffect(pc)
+		if err != "" {
+			return err
+		}
+		depth := self.depths[pc] + effect
+		successors, isBranch := self.branchTargets(pc)
+		if !isBranch {
+			if opcode := self.code[pc]; opcode >= 0xac && opcode <= 0xb1 || opcode == 0xbf {
+				continue // return athrow
+			}
+			successors = []int{pc + self.lengths[pc]}
+		}
+		for _, next := range successors {
+			if err := reach(next, depth); err != "" {
+				return err
+			}
+		}
+	}
+	return ""
+}
+
+// 执行一条指令以后操作数栈深度的变化
+func (self *jitCompiler) stackEffect(pc int) (int, string) {
+	code := self.code
+	opcode := code[pc]
+	switch {
+	case opcode == 0x00:
+		return 0, ""
+	case opcode <= 0x08, opcode >= 0x0b && opcode <= 0x0d, opcode == 0x10, opcode == 0x11, opcode == 0x12, opcode == 0x13:
+		return 1, ""
+	case opcode == 0x09, opcode == 0x0a, opcode == 0x0e, opcode == 0x0f, opcode == 0x14:
+		return 2, ""
+	case opcode >= 0x15 && opcode <= 0x19:
+		return loadStoreSize(opcode - 0x15), ""
+	case opcode >= 0x1a && opcode <= 0x2d:
+		return loadStoreSize((opcode - 0x1a) / 4), ""
+	case opcode == 0x2f, opcode == 0x31: // laload daload
+		return 0, ""
+	case opcode >= 0x2e && opcode <= 0x35:
+		return -1, ""
+	case opcode >= 0x36 && opcode <= 0x3a:
+		return -loadStoreSize(opcode - 0x36), ""
+	case opcode >= 0x3b && opcode <= 0x4e:
+		return -loadStoreSize((opcode - 0x3b) / 4), ""
+	case opcode == 0x50, opcode == 0x52: // lastore dastore
+		return -4, ""
+	case opcode >= 0x4f && opcode <= 0x56:
+		return -3, ""
+	case opcode == 0x57:
+		return -1, ""
+	case opcode == 0x58:
+		return -2, ""
+	case opcode >= 0x59 && opcode <= 0x5b:
+		return 1, ""
+	case opcode >= 0x5c && opcode <= 0x5e:
+		return 2, ""
+	case opcode == 0x5f:
+		return 0, ""
+	case opcode >= 0x60 && opcode <= 0x73: // add sub mul div rem
+		return -loadStoreSize((opcode - 0x60) % 4), ""
+	case opcode >= 0x74 && opcode <= 0x77: // neg
+		return 0, ""
+	case opcode >= 0x78 && opcode <= 0x7d: // 移位的位数总是int
+		return -1, ""
+	case opcode >= 0x7e && opcode <= 0x83: // and or xor
+		return -loadStoreSize((opcode - 0x7e) % 2), ""
+	case opcode == 0x84:
+		return 0, ""
+	case opcode >= 0x85 && opcode <= 0x93:
+		return [...]int{1, 0, 1, -1, -1, 0, 0, 1, 1, -1, 0, -1, 0, 0, 0}[opcode-0x85], ""
+	case opcode == 0x94, opcode == 0x97, opcode == 0x98:
+		return -3, ""
+	case opcode == 0x95, opcode == 0x96:
+		return -1, ""
+	case opcode >= 0x99 && opcode <= 0x9e, opcode == 0xc6, opcode == 0xc7:
+		return -1, ""
+	case opcode >= 0x9f && opcode <= 0xa6:
+		return -2, ""
+	case opcode == 0xa7, opcode == 0xc8:
+		return 0, ""
+	case opcode == 0xaa, opcode == 0xab:
+		return -1, ""
+	case opcode >= 0xac && opcode <= 0xb1, opcode == 0xbf:
+		return 0, "" // 没有后继
+	case opcode >= 0xb2 && opcode <= 0xb5:
+		size := typeSlots(self.cp.GetConstant(u16(code, pc+1)).(*heap.FieldRef).Descriptor())
+		return [...]int{size, -size, size - 1, -size - 1}[opcode-0xb2], ""
+	case opcode >= 0xb6 && opcode <= 0xb9:
+		ref := self.cp.GetConstant(u16(code, pc+1)).(interface{ Descriptor() string })
+		argSlots, returnSlots := methodSlots(ref.Descriptor())
+		if opcode != 0xb8 {
+			argSlots++ // this
+		}
+		return returnSlots - argSlots, ""
+	case opcode == 0xbb:
+		return 1, ""
+	case opcode == 0xbc, opcode == 0xbd, opcode == 0xbe, opcode == 0xc0, opcode == 0xc1:
+		return 0, ""
+	case opcode == 0xc2, opcode == 0xc3:
+		return -1, ""
+	case opcode == 0xc4: // wide
+		switch modified := code[pc+1]; {
+		case modified >= 0x15 && modified <= 0x19:
+			return loadStoreSize(modified - 0x15), ""
+		case modified >= 0x36 && modified <= 0x3a:
+			return -loadStoreSize(modified - 0x36), ""
+		case modified == 0x84:
+			return 0, ""
+		}
+		return 0, "unsupported wide instruction"
+	case opcode == 0xc5:
+		return 1 - int(code[pc+3]), ""
+	}
+	return 0, "unsupported opcode"
+}
+
+// i l f d a 占用的槽位数
+func loadStoreSize(typeIndex byte) int {
+	if typeIndex == 1 || typeIndex == 3 {
+		return 2
+	}
+	return 1
+}
+
+func typeSlots(descriptor string) int {
+	switch descriptor[0] {
+	case 'V':
+		return 0
+	case 'J', 'D':
+		return 2
+	}
+	return 1
+}
+
+func methodSlots(descriptor string) (argSlots, returnSlots int) {
+	i := 1
+	for descriptor[i] != ')' {
+		switch descriptor[i] {
+		case 'J', 'D':
+			argSlots += 2
+		default:
+			argSlots++
+		}
+		for descriptor[i] == '[' {
+			i++
+		}
+		if descriptor[i] == 'L' {
+			i += strings.IndexByte(descriptor[i:], ';')
+		}
+		i++
+	}
+	return argSlots, typeSlots(descriptor[i+1:])
+}
+
+// 跳转指令的所有后继 不是跳转指令时返回false
+func (self *jitCompiler) branchTargets(pc int) ([]int, bool) {
+	code := self.code
+	switch opcode := code[pc]; {
+	case opcode >= 0x99 && opcode <= 0xa6, opcode == 0xc6, opcode == 0xc7:
+		return []int{pc + 3, pc + branchOffset(code, pc)}, true
+	case opcode == 0xa7:
+		return []int{pc + branchOffset(code, pc)}, true
+	case opcode == 0xc8:
+		return []int{pc + int(s32(code, pc+1))}, true
+	case opcode == 0xaa:
+		p := (pc + 4) &^ 3
+		low, high := s32(code, p+4), s32(code, p+8)
+		targets := []int{pc + int(s32(code, p))}
+		for i := 0; i <= int(high-low); i++ {
+			targets = append(targets, pc+int(s32(code, p+12+4*i)))
+		}
+		return targets, true
+	case opcode == 0xab:
+		p := (pc + 4) &^ 3
+		targets := []int{pc + int(s32(code, p))}
+		for i := 0; i < int(s32(code, p+4)); i++ {
+			targets = append(targets, pc+int(s32(code, p+12+8*i)))
+		}
+		return targets, true
+	}
+	return nil, false
+}
+
+// 不认识的指令和jsr、ret返回0
+func instructionLength(code []byte, pc int) int {
+	switch opcode := code[pc]; {
+	case opcode == 0x10, opcode == 0x12, opcode >= 0x15 && opcode <= 0x19,
+		opcode >= 0x36 && opcode <= 0x3a, opcode == 0xbc:
+		return 2
+	case opcode == 0x11, opcode == 0x13, opcode == 0x14, opcode == 0x84,
+		opcode >= 0x99 && opcode <= 0xa7, opcode >= 0xb2 && opcode <= 0xb8,
+		opcode == 0xbb, opcode == 0xbd, opcode == 0xc0, opcode == 0xc1, opcode == 0xc6, opcode == 0xc7:
+		return 3
+	case opcode == 0xc5:
+		return 4
+	case opcode == 0xb9, opcode == 0xba, opcode == 0xc8:
+		return 5
+	case opcode == 0xc4:
+		if pc+1 < len(code) && code[pc+1] == 0x84 {
+			return 6
+		}
+		return 4
+	case opcode == 0xaa:
+		p := (pc + 4) &^ 3
+		if p+12 > len(code) {
+			return 0
+		}
+		return p + 12 + 4*int(s32(code, p+8)-s32(code, p+4)+1) - pc
+	case opcode == 0xab:
+		p := (pc + 4) &^ 3
+		if p+8 > len(code) {
+			return 0
+		}
+		return p + 8 + 8*int(s32(code, p+4)) - pc
+	case opcode == 0xa8, opcode == 0xa9, opcode == 0xc9, opcode > 0xc9:
+		return 0
+	}
+	return 1
+}
+
+func u16(code []byte, i int) uint {
+	return uint(code[i])<<8 | uint(code[i+1])
+}
+
+func s32(code []byte, i int) int32 {
+	return int32(uint32(code[i])<<24 | uint32(code[i+1])<<16 | uint32(code[i+2])<<8 | uint32(code[i+3]))
+}
+
+// 跳转指令编译成基本块的next
+func (self *jitCompiler) compileBranch(pc int) func(locals, stack []rtda.Slot) int {
+	code := self.code
+	d := uint(self.depths[pc])
+	opcode := code[pc]
+	next, target := pc+3, pc+branchOffset(code, pc)
+	switch {
+	case opcode >= 0x99 && opcode <= 0x9e: // if<cond>
+		cond := opcode - 0x99
+		return func(locals, stack []rtda.Slot) int {
+			if ifcond(cond, stack[d-1].Int(), 0) {
+				return target
+			}
+			return next
+		}
+	case opcode >= 0x9f && opcode <= 0xa4: // if_icmp<cond>
+		cond := opcode - 0x9f
+		return func(locals, stack []rtda.Slot) int {
+			if ifcond(cond, stack[d-2].Int(), stack[d-1].Int()) {
+				return target
+			}
+			return next
+		}
+	case opcode == 0xa5, opcode == 0xa6: // if_acmpeq if_acmpne
+		eq := opcode == 0xa5
+		return func(locals, stack []rtda.Slot) int {
+			if (stack[d-2].Ref() == stack[d-1].Ref()) == eq {
+				return target
+			}
+			return next
+		}
+	case opcode == 0xc6, opcode == 0xc7: // ifnull ifnonnull
+		isNull := opcode == 0xc6
+		return func(locals, stack []rtda.Slot) int {
+			if (stack[d-1].Ref() == nil) == isNull {
+				return target
+			}
+			return next
+		}
+	case opcode == 0xa7: // goto
+		return func(locals, stack []rtda.Slot) int { return target }
+	case opcode == 0xc8: // goto_w
+		target = pc + int(s32(code, pc+1))
+		return func(locals, stack []rtda.Slot) int { return target }
+	case opcode == 0xaa: // tableswitch
+		p := (pc + 4) &^ 3
+		defaultPC := pc + int(s32(code, p))
+		low, high := s32(code, p+4), s32(code, p+8)
+		targets := make([]int, high-low+1)
+		for i := range targets {
+			targets[i] = pc + int(s32(code, p+12+4*i))
+		}
+		return func(locals, stack []rtda.Slot) int {
+			if index := stack[d-1].Int(); index >= low && index <= high {
+				return targets[index-low]
+			}
+			return defaultPC
+		}
+	default: // lookupswitch
+		p := (pc + 4) &^ 3
+		defaultPC := pc + int(s32(code, p))
+		targets := map[int32]int{}
+		for i := 0; i < int(s32(code, p+4)); i++ {
+			targets[s32(code, p+8+8*i)] = pc + int(s32(code, p+12+8*i))
+		}
+		return func(locals, stack []rtda.Slot) int {
+			if target, ok := targets[stack[d-1].Int()]; ok {
+				return target
+			}
+			return defaultPC
+		}
+	}
+}
+
+// 不能编译的指令返回false 由解释器执行
+// nop、pop这样只改变栈深度的指令编译成nil 不需要运行时的代码
+func (self *jitCompiler) compileOp(pc int) (jitOp, bool) {
+	code := self.code
+	d := uint(self.depths[pc])
+	opcode := code[pc]
+	switch opcode {
+	// constants
+	case 0x00: // nop
+		return nil, true
+	case 0x01: // aconst_null
+		return pushSlot(d, rtda.RefSlot(nil)), true
+	case 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08: // iconst_m1 ~ iconst_5
+		return pushSlot(d, rtda.IntSlot(int32(opcode)-3)), true
+	case 0x09, 0x0a: // lconst_0 lconst_1
+		return pushLong(d, int64(opcode-0x09)), true
+	case 0x0b, 0x0c, 0x0d: // fconst_0 ~ fconst_2
+		return pushSlot(d, rtda.FloatSlot(float32(opcode-0x0b))), true
+	case 0x0e, 0x0f: // dconst_0 dconst_1
+		return pushLong(d, int64(math.Float64bits(float64(opcode-0x0e)))), true
+	case 0x10: // bipush
+		return pushSlot(d, rtda.IntSlot(int32(int8(code[pc+1])))), true
+	case 0x11: // sipush
+		return pushSlot(d, rtda.IntSlot(int32(int16(u16(code, pc+1))))), true
+	case 0x12, 0x13: // ldc ldc_w 类引用可能触发类加载 交给解释器
+		index := uint(code[pc+1])
+		if opcode == 0x13 {
+			index = u16(code, pc+1)
+		}
+		switch c := self.cp.GetConstant(index).(type) {
+		case int32:
+			return pushSlot(d, rtda.IntSlot(c)), true
+		case float32:
+			return pushSlot(d, rtda.FloatSlot(c)), true
+		case string:
+			return pushSlot(d, rtda.RefSlot(heap.InternedJString(self.class.Loader(), c))), true
+		}
+		return nil, false
+	case 0x14: // ldc2_w
+		switch c := self.cp.GetConstant(u16(code, pc+1)).(type) {
+		case int64:
+			return pushLong(d, c), true
+		case float64:
+			return pushLong(d, int64(math.Float64bits(c))), true
+		}
+		return nil, false
+
+	// loads stores
+	case 0x15, 0x16, 0x17, 0x18, 0x19:
+		return load(d, uint(code[pc+1]), loadStoreSize(opcode-0x15)), true
+	case 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20, 0x21, 0x22, 0x23,
+		0x24, 0x25, 0x26, 0x27, 0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2d: // <t>load_<n>
+		return load(d, uint(opcode-0x1a)&3, loadStoreSize((opcode-0x1a)/4)), true
+	case 0x36, 0x37, 0x38, 0x39, 0x3a:
+		return store(d, uint(code[pc+1]), loadStoreSize(opcode-0x36)), true
+	case 0x3b, 0x3c, 0x3d, 0x3e, 0x3f, 0x40, 0x41, 0x42, 0x43, 0x44,
+		0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e: // <t>store_<n>
+		return store(d, uint(opcode-0x3b)&3, loadStoreSize((opcode-0x3b)/4)), true
+	case 0xc4: // wide
+		index := u16(code, pc+2)
+		switch modified := code[pc+1]; {
+		case modified >= 0x15 && modified <= 0x19:
+			return load(d, index, loadStoreSize(modified-0x15)), true
+		case modified >= 0x36 && modified <= 0x3a:
+			return store(d, index, loadStoreSize(modified-0x36)), true
+		case modified == 0x84:
+			return iinc(index, int32(int16(u16(code, pc+4)))), true
+		}
+		return nil, false
+	case 0x84: // iinc
+		return iinc(uint(code[pc+1]), int32(int8(code[pc+2]))), true
+
+	// 数组为null或者下标越界时去优化 aastore需要检查类型 交给解释器
+	case 0x2e, 0x2f, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35:
+		return arrayLoad(d, opcode), true
+	case 0x4f, 0x50, 0x51, 0x52, 0x54, 0x55, 0x56:
+		return arrayStore(d, opcode), true
+	case 0xbe: // arraylength
+		return func(locals, stack []rtda.Slot) bool {
+			if arr := stack[d-1].Ref(); arr != nil {
+				stack[d-1] = rtda.IntSlot(arr.ArrayLength())
+				return true
+			}
+			return false
+		}, true
+
+	// stack
+	case 0x57, 0x58: // pop pop2
+		return nil, true
+	case 0x59, 0x5a, 0x5b, 0x5c, 0x5d, 0x5e, 0x5f:
+		return stackOp(d, opcode), true
+
+	// math
+	case 0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+		0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70, 0x71, 0x72, 0x73,
+		0x74, 0x75, 0x76, 0x77, 0x78, 0x79, 0x7a, 0x7b, 0x7c, 0x7d,
+		0x7e, 0x7f, 0x80, 0x81, 0x82, 0x83:
+		return mathOp(d, opcode), true
+
+	// conversions comparisons
+	case 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e,
+		0x8f, 0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98:
+		return convertOp(d, opcode), true
+
+	// references 字段没有解析或者类还没有初始化完成的指令交给解释器
+	case 0xb2, 0xb3, 0xb4, 0xb5:
+		return self.fieldOp(pc, d, opcode)
+	}
+	return nil, false
+}
+
+func pushSlot(d uint, slot rtda.Slot) jitOp {
+	return func(locals, stack []rtda.Slot) bool {
+		stack[d] = slot
+		return true
+	}
+}
+
+// double按位当作long压栈
+func pushLong(d uint, val int64) jitOp {
+	low, high := rtda.LongSlots(val)
+	return func(locals, stack []rtda.Slot) bool {
+		stack[d], stack[d+1] = low, high
+		return true
+	}
+}
+
+func load(d, index uint, size int) jitOp {
+	if size == 2 {
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d], stack[d+1] = locals[index], locals[index+1]
+			return true
+		}
+	}
+	return func(locals, stack []rtda.Slot) bool {
+		stack[d] = locals[index]
+		return true
+	}
+}
+
+func store(d, index uint, size int) jitOp {
+	if size == 2 {
+		return func(locals, stack []rtda.Slot) bool {
+			locals[index], locals[index+1] = stack[d-2], stack[d-1]
+			return true
+		}
+	}
+	return func(locals, stack []rtda.Slot) bool {
+		locals[index] = stack[d-1]
+		return true
+	}
+}
+
+func iinc(index uint, c int32) jitOp {
+	return func(locals, stack []rtda.Slot) bool {
+		locals[index] = rtda.IntSlot(locals[index].Int() + c)
+		return true
+	}
+}
+
+// 数组引用和下标在arr、arr+1两个槽里 为null时返回nil
+func jitArrayData[T heap.PrimitiveElement](stack []rtda.Slot, arr uint) []T {
+	if ref := stack[arr].Ref(); ref != nil {
+		return heap.ArrayElements[T](ref)
+	}
+	return nil
+}
+
+func jitArrayRefs(stack []rtda.Slot, arr uint) []*heap.Object {
+	if ref := stack[arr].Ref(); ref != nil {
+		return ref.Refs()
+	}
+	return nil
+}
+
+func arrayLoad(d uint, opcode byte) jitOp {
+	a, i := d-2, d-1
+	switch opcode {
+	case 0x2e: // iaload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int32](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					stack[a] = rtda.IntSlot(vals[index])
+					return true
+				}
+			}
+			return false
+		}
+	case 0x2f: // laload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int64](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					setLong(stack, a, vals[index])
+					return true
+				}
+			}
+			return false
+		}
+	case 0x30: // faload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[float32](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					stack[a] = rtda.FloatSlot(vals[index])
+					return true
+				}
+			}
+			return false
+		}
+	case 0x31: // daload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[float64](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					setDouble(stack, a, vals[index])
+					return true
+				}
+			}
+			return false
+		}
+	case 0x32: // aaload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayRefs(stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					stack[a] = rtda.RefSlot(vals[index])
+					return true
+				}
+			}
+			return false
+		}
+	case 0x33: // baload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int8](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					stack[a] = rtda.IntSlot(int32(vals[index]))
+					return true
+				}
+			}
+			return false
+		}
+	case 0x34: // caload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[uint16](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					stack[a] = rtda.IntSlot(int32(vals[index]))
+					return true
+				}
+			}
+			return false
+		}
+	default: // saload
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int16](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					stack[a] = rtda.IntSlot(int32(vals[index]))
+					return true
+				}
+			}
+			return false
+		}
+	}
+}
+
+func arrayStore(d uint, opcode byte) jitOp {
+	switch opcode {
+	case 0x4f: // iastore
+		a, i, v := d-3, d-2, d-1
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int32](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = stack[v].Int()
+					return true
+				}
+			}
+			return false
+		}
+	case 0x50: // lastore
+		a, i, v := d-4, d-3, d-2
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int64](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = getLong(stack, v)
+					return true
+				}
+			}
+			return false
+		}
+	case 0x51: // fastore
+		a, i, v := d-3, d-2, d-1
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[float32](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = stack[v].Float()
+					return true
+				}
+			}
+			return false
+		}
+	case 0x52: // dastore
+		a, i, v := d-4, d-3, d-2
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[float64](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = getDouble(stack, v)
+					return true
+				}
+			}
+			return false
+		}
+	case 0x54: // bastore
+		a, i, v := d-3, d-2, d-1
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int8](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = int8(stack[v].Int())
+					return true
+				}
+			}
+			return false
+		}
+	case 0x55: // castore
+		a, i, v := d-3, d-2, d-1
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[uint16](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = uint16(stack[v].Int())
+					return true
+				}
+			}
+			return false
+		}
+	default: // sastore
+		a, i, v := d-3, d-2, d-1
+		return func(locals, stack []rtda.Slot) bool {
+			if vals := jitArrayData[int16](stack, a); vals != nil {
+				if index := uint(stack[i].Int()); index < uint(len(vals)) {
+					vals[index] = int16(stack[v].Int())
+					return true
+				}
+			}
+			return false
+		}
+	}
+}
+
+func stackOp(d uint, opcode byte) jitOp {
+	switch opcode {
+	case 0x59: // dup
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d] = stack[d-1]
+			return true
+		}
+	case 0x5a: // dup_x1
+		return func(locals, stack []rtda.Slot) bool {
+			slot1, slot2 := stack[d-1], stack[d-2]
+			stack[d-2], stack[d-1], stack[d] = slot1, slot2, slot1
+			return true
+		}
+	case 0x5b: // dup_x2
+		return func(locals, stack []rtda.Slot) bool {
+			slot1, slot2, slot3 := stack[d-1], stack[d-2], stack[d-3]
+			stack[d-3], stack[d-2], stack[d-1], stack[d] = slot1, slot3, slot2, slot1
+			return true
+		}
+	case 0x5c: // dup2
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d], stack[d+1] = stack[d-2], stack[d-1]
+			return true
+		}
+	case 0x5d: // dup2_x1
+		return func(locals, stack []rtda.Slot) bool {
+			slot1, slot2, slot3 := stack[d-1], stack[d-2], stack[d-3]
+			stack[d-3], stack[d-2], stack[d-1], stack[d], stack[d+1] = slot2, slot1, slot3, slot2, slot1
+			return true
+		}
+	case 0x5e: // dup2_x2
+		return func(locals, stack []rtda.Slot) bool {
+			slot1, slot2, slot3, slot4 := stack[d-1], stack[d-2], stack[d-3], stack[d-4]
+			stack[d-4], stack[d-3], stack[d-2], stack[d-1], stack[d], stack[d+1] = slot2, slot1, slot4, slot3, slot2, slot1
+			return true
+		}
+	default: // swap
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1], stack[d-2] = stack[d-2], stack[d-1]
+			return true
+		}
+	}
+}
+
+// 和fastLoop一样 整数除以0时去优化 由解释器抛出ArithmeticException
+func mathOp(d uint, opcode byte) jitOp {
+	switch opcode {
+	case 0x60: // iadd
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() + stack[d-1].Int())
+			return true
+		}
+	case 0x61: // ladd
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-4, getLong(stack, d-4)+getLong(stack, d-2))
+			return true
+		}
+	case 0x62: // fadd
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(stack[d-2].Float() + stack[d-1].Float())
+			return true
+		}
+	case 0x63: // dadd
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-4, getDouble(stack, d-4)+getDouble(stack, d-2))
+			return true
+		}
+	case 0x64: // isub
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() - stack[d-1].Int())
+			return true
+		}
+	case 0x65: // lsub
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-4, getLong(stack, d-4)-getLong(stack, d-2))
+			return true
+		}
+	case 0x66: // fsub
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(stack[d-2].Float() - stack[d-1].Float())
+			return true
+		}
+	case 0x67: // dsub
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-4, getDouble(stack, d-4)-getDouble(stack, d-2))
+			return true
+		}
+	case 0x68: // imul
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() * stack[d-1].Int())
+			return true
+		}
+	case 0x69: // lmul
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-4, getLong(stack, d-4)*getLong(stack, d-2))
+			return true
+		}
+	case 0x6a: // fmul
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(stack[d-2].Float() * stack[d-1].Float())
+			return true
+		}
+	case 0x6b: // dmul
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-4, getDouble(stack, d-4)*getDouble(stack, d-2))
+			return true
+		}
+	case 0x6c: // idiv
+		return func(locals, stack []rtda.Slot) bool {
+			if v2 := stack[d-1].Int(); v2 != 0 {
+				stack[d-2] = rtda.IntSlot(stack[d-2].Int() / v2)
+				return true
+			}
+			return false
+		}
+	case 0x6d: // ldiv
+		return func(locals, stack []rtda.Slot) bool {
+			if v2 := getLong(stack, d-2); v2 != 0 {
+				setLong(stack, d-4, getLong(stack, d-4)/v2)
+				return true
+			}
+			return false
+		}
+	case 0x6e: // fdiv
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(stack[d-2].Float() / stack[d-1].Float())
+			return true
+		}
+	case 0x6f: // ddiv
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-4, getDouble(stack, d-4)/getDouble(stack, d-2))
+			return true
+		}
+	case 0x70: // irem
+		return func(locals, stack []rtda.Slot) bool {
+			if v2 := stack[d-1].Int(); v2 != 0 {
+				stack[d-2] = rtda.IntSlot(stack[d-2].Int() % v2)
+				return true
+			}
+			return false
+		}
+	case 0x71: // lrem
+		return func(locals, stack []rtda.Slot) bool {
+			if v2 := getLong(stack, d-2); v2 != 0 {
+				setLong(stack, d-4, getLong(stack, d-4)%v2)
+				return true
+			}
+			return false
+		}
+	case 0x72: // frem
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(float32(math.Mod(float64(stack[d-2].Float()), float64(stack[d-1].Float()))))
+			return true
+		}
+	case 0x73: // drem
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-4, math.Mod(getDouble(stack, d-4), getDouble(stack, d-2)))
+			return true
+		}
+	case 0x74: // ineg
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.IntSlot(-stack[d-1].Int())
+			return true
+		}
+	case 0x75: // lneg
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-2, -getLong(stack, d-2))
+			return true
+		}
+	case 0x76: // fneg
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.FloatSlot(-stack[d-1].Float())
+			return true
+		}
+	case 0x77: // dneg
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-2, -getDouble(stack, d-2))
+			return true
+		}
+	case 0x78: // ishl
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() << (uint32(stack[d-1].Int()) & 0x1f))
+			return true
+		}
+	case 0x79: // lshl
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-3, getLong(stack, d-3)<<(uint32(stack[d-1].Int())&0x3f))
+			return true
+		}
+	case 0x7a: // ishr
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() >> (uint32(stack[d-1].Int()) & 0x1f))
+			return true
+		}
+	case 0x7b: // lshr
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-3, getLong(stack, d-3)>>(uint32(stack[d-1].Int())&0x3f))
+			return true
+		}
+	case 0x7c: // iushr
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(int32(uint32(stack[d-2].Int()) >> (uint32(stack[d-1].Int()) & 0x1f)))
+			return true
+		}
+	case 0x7d: // lushr
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-3, int64(uint64(getLong(stack, d-3))>>(uint32(stack[d-1].Int())&0x3f)))
+			return true
+		}
+	case 0x7e: // iand
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() & stack[d-1].Int())
+			return true
+		}
+	case 0x7f: // land
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-4, getLong(stack, d-4)&getLong(stack, d-2))
+			return true
+		}
+	case 0x80: // ior
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() | stack[d-1].Int())
+			return true
+		}
+	case 0x81: // lor
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-4, getLong(stack, d-4)|getLong(stack, d-2))
+			return true
+		}
+	case 0x82: // ixor
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(stack[d-2].Int() ^ stack[d-1].Int())
+			return true
+		}
+	default: // lxor
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-4, getLong(stack, d-4)^getLong(stack, d-2))
+			return true
+		}
+	}
+}
+
+func convertOp(d uint, opcode byte) jitOp {
+	switch opcode {
+	case 0x85: // i2l
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-1, int64(stack[d-1].Int()))
+			return true
+		}
+	case 0x86: // i2f
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.FloatSlot(float32(stack[d-1].Int()))
+			return true
+		}
+	case 0x87: // i2d
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-1, float64(stack[d-1].Int()))
+			return true
+		}
+	case 0x88: // l2i
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(int32(getLong(stack, d-2)))
+			return true
+		}
+	case 0x89: // l2f
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(float32(getLong(stack, d-2)))
+			return true
+		}
+	case 0x8a: // l2d
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-2, float64(getLong(stack, d-2)))
+			return true
+		}
+	case 0x8b: // f2i
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.IntSlot(int32(stack[d-1].Float()))
+			return true
+		}
+	case 0x8c: // f2l
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-1, int64(stack[d-1].Float()))
+			return true
+		}
+	case 0x8d: // f2d
+		return func(locals, stack []rtda.Slot) bool {
+			setDouble(stack, d-1, float64(stack[d-1].Float()))
+			return true
+		}
+	case 0x8e: // d2i
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(int32(getDouble(stack, d-2)))
+			return true
+		}
+	case 0x8f: // d2l
+		return func(locals, stack []rtda.Slot) bool {
+			setLong(stack, d-2, int64(getDouble(stack, d-2)))
+			return true
+		}
+	case 0x90: // d2f
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.FloatSlot(float32(getDouble(stack, d-2)))
+			return true
+		}
+	case 0x91: // i2b
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.IntSlot(int32(int8(stack[d-1].Int())))
+			return true
+		}
+	case 0x92: // i2c
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.IntSlot(int32(uint16(stack[d-1].Int())))
+			return true
+		}
+	case 0x93: // i2s
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-1] = rtda.IntSlot(int32(int16(stack[d-1].Int())))
+			return true
+		}
+	case 0x94: // lcmp
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-4] = rtda.IntSlot(lcmp(getLong(stack, d-4), getLong(stack, d-2)))
+			return true
+		}
+	case 0x95, 0x96: // fcmpl fcmpg
+		gFlag := opcode == 0x96
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-2] = rtda.IntSlot(fcmp(float64(stack[d-2].Float()), float64(stack[d-1].Float()), gFlag))
+			return true
+		}
+	default: // dcmpl dcmpg
+		gFlag := opcode == 0x98
+		return func(locals, stack []rtda.Slot) bool {
+			stack[d-4] = rtda.IntSlot(fcmp(getDouble(stack, d-4), getDouble(stack, d-2), gFlag))
+			return true
+		}
+	}
+}
+
+// 和快速版本的指令一样 静态字段要等类初始化完成 实例字段在对象为null时去优化
+// final字段和Reference.referent交给解释器
+func (self *jitCompiler) fieldOp(pc int, d uint, opcode byte) (jitOp, bool) {
+	field := self.cp.GetConstant(u16(self.code, pc+1)).(*heap.FieldRef).Field()
+	if field == nil || field.IsStatic() != (opcode <= 0xb3) || field.IsReferent() ||
+		(opcode == 0xb3 || opcode == 0xb5) && field.IsFinal() {
+		return nil, false
+	}
+	slotId := field.SlotId()
+	size := typeSlots(field.Descriptor())
+	isRef := field.Descriptor()[0] == 'L' || field.Descriptor()[0] == '['
+
+	switch opcode {
+	case 0xb2, 0xb3: // getstatic putstatic
+		if !field.Class().IsInitialized() {
+			return nil, false
+		}
+		vars := field.Class().StaticVars()
+		if opcode == 0xb2 {
+			switch {
+			case size == 2:
+				return func(locals, stack []rtda.Slot) bool {
+					setLong(stack, d, vars.GetLong(slotId))
+					return true
+				}, true
+			case isRef:
+				return func(locals, stack []rtda.Slot) bool {
+					stack[d] = rtda.RefSlot(vars.GetRef(slotId))
+					return true
+				}, true
+			default:
+				return func(locals, stack []rtda.Slot) bool {
+					stack[d] = rtda.IntSlot(vars.GetInt(slotId))
+					return true
+				}, true
+			}
+		}
+		switch {
+		case size == 2:
+			return func(locals, stack []rtda.Slot) bool {
+				vars.SetLong(slotId, getLong(stack, d-2))
+				return true
+			}, true
+		case isRef:
+			return func(locals, stack []rtda.Slot) bool {
+				vars.SetRef(slotId, stack[d-1].Ref())
+				return true
+			}, true
+		default:
+			return func(locals, stack []rtda.Slot) bool {
+				vars.SetInt(slotId, stack[d-1].Int())
+				return true
+			}, true
+		}
+	case 0xb4: // getfield
+		switch {
+		case size == 2:
+			return func(locals, stack []rtda.Slot) bool {
+				if ref := stack[d-1].Ref(); ref != nil {
+					setLong(stack, d-1, ref.GetLongField(slotId))
+					return true
+				}
+				return false
+			}, true
+		case isRef:
+			return func(locals, stack []rtda.Slot) bool {
+				if ref := stack[d-1].Ref(); ref != nil {
+					stack[d-1] = rtda.RefSlot(ref.GetRefField(slotId))
+					return true
+				}
+				return false
+			}, true
+		default:
+			return func(locals, stack []rtda.Slot) bool {
+				if ref := stack[d-1].Ref(); ref != nil {
+					stack[d-1] = rtda.IntSlot(ref.GetIntField(slotId))
+					return true
+				}
+				return false
+			}, true
+		}
+	default: // putfield
+		switch {
+		case size == 2:
+			return func(locals, stack []rtda.Slot) bool {
+				if ref := stack[d-3].Ref(); ref != nil {
+					ref.SetLongField(slotId, getLong(stack, d-2))
+					return true
+				}
+				return false
+			}, true
+		case isRef:
+			return func(locals, stack []rtda.Slot) bool {
+				if ref := stack[d-2].Ref(); ref != nil {
+					ref.SetRefField(slotId, stack[d-1].Ref())
+					return true
+				}
+				return false
+			}, true
+		default:
+			return func(locals, stack []rtda.Slot) bool {
+				if ref := stack[d-2].Ref(); ref != nil {
+					ref.SetIntField(slotId, stack[d-1].Int())
+					return true
+				}
+				return false
+			}, true
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"jvm/rtda"
+)
+
+// 基本块里的int表达式不经过操作数栈 直接组合成闭包树
+// 比如iload_1 iload_2 iconst_1 ishl iadd istore_3编译成一个闭包
+// 叶子是局部变量或者常量 只有被istore、跳转等指令用到时才求值
+// 表达式树只读局部变量 所以在写局部变量或者执行其他指令之前 先把还没用掉的表达式写回操作数栈
+type intExpr struct {
+	kind  byte
+	index uint  // exprLocal
+	value int32 // exprConst
+	eval  func(locals, stack []rtda.Slot) int32
+}
+
+const (
+	exprConst = iota
+	exprLocal
+	exprTree
+)
+
+func constExpr(value int32) intExpr {
+	return intExpr{kind: exprConst, value: value}
+}
+
+func localExpr(index uint) intExpr {
+	return intExpr{kind: exprLocal, index: index}
+}
+
+func treeExpr(eval func(locals, stack []rtda.Slot) int32) intExpr {
+	return intExpr{kind: exprTree, eval: eval}
+}
+
+func (self intExpr) fn() func(locals, stack []rtda.Slot) int32 {
+	switch self.kind {
+	case exprConst:
+		value := self.value
+		return func(locals, stack []rtda.Slot) int32 { return value }
+	case exprLocal:
+		index := self.index
+		return func(locals, stack []rtda.Slot) int32 { return locals[index].Int() }
+	}
+	return self.eval
+}
+
+// 能进入表达式树的int运算 除法和求余可能抛异常 不在其中
+func isIntBinary(opcode byte) bool {
+	switch opcode {
+	case 0x60, 0x64, 0x68, 0x78, 0x7a, 0x7c, 0x7e, 0x80, 0x82:
+		return true
+	}
+	return false
+}
+
+func intBinary(opcode byte, v1, v2 int32) int32 {
+	switch opcode {
+	case 0x60: // iadd
+		return v1 + v2
+	case 0x64: // isub
+		return v1 - v2
+	case 0x68: // imul
+		return v1 * v2
+	case 0x78: // ishl
+		return v1 << (uint32(v2) & 0x1f)
+	case 0x7a: // ishr
+		return v1 >> (uint32(v2) & 0x1f)
+	case 0x7c: // iushr
+		return int32(uint32(v1) >> (uint32(v2) & 0x1f))
+	case 0x7e: // iand
+		return v1 & v2
+	case 0x80: // ior
+		return v1 | v2
+	default: // ixor
+		return v1 ^ v2
+	}
+}
+
+// 常量折叠 叶子直接读局部变量 省掉一层闭包调用
+func binaryExpr(opcode byte, x, y intExpr) intExpr {
+	switch {
+	case x.kind == exprConst && y.kind == exprConst:
+		return constExpr(intBinary(opcode, x.value, y.value))
+	case x.kind == exprLocal && y.kind == exprConst:
+		i, c := x.index, y.value
+		return treeExpr(func(locals, stack []rtda.Slot) int32 {
+			return intBinary(opcode, locals[i].Int(), c)
+		})
+	case x.kind == exprLocal && y.kind == exprLocal:
+		i, j := x.index, y.index
+		return treeExpr(func(locals, stack []rtda.Slot) int32 {
+			return intBinary(opcode, locals[i].Int(), locals[j].Int())
+		})
+	case y.kind == exprConst:
+		f, c := x.fn(), y.value
+		return treeExpr(func(locals, stack []rtda.Slot) int32 {
+			return intBinary(opcode, f(locals, stack), c)
+		})
+	case y.kind == exprLocal:
+		f, j := x.fn(), y.index
+		return treeExpr(func(locals, stack []rtda.Slot) int32 {
+			return intBinary(opcode, f(locals, stack), locals[j].Int())
+		})
+	}
+	f, g := x.fn(), y.fn()
+	return treeExpr(func(locals, stack []rtda.Slot) int32 {
+		return intBinary(opcode, f(locals, stack), g(locals, stack))
+	})
+}
+
+func unaryExpr(opcode byte, x intExpr) intExpr {
+	convert := func(v int32) int32 {
+		switch opcode {
+		case 0x74: // ineg
+			return -v
+		case 0x91: // i2b
+			return int32(int8(v))
+		case 0x92: // i2c
+			return int32(uint16(v))
+		default: // i2s
+			return int32(int16(v))
+		}
+	}
+	if x.kind == exprConst {
+		return constExpr(convert(x.value))
+	}
+	f := x.fn()
+	return treeExpr(func(locals, stack []rtda.Slot) int32 {
+		return convert(f(locals, stack))
+	})
+}
+
+// 表达式写回操作数栈的第slot个槽
+func spillExpr(slot uint, x intExpr) jitOp {
+	f := x.fn()
+	return func(locals, stack []rtda.Slot) bool {
+		stack[slot] = rtda.IntSlot(f(locals, stack))
+		return true
+	}
+}
+
+func storeExpr(index uint, x intExpr) jitOp {
+	if x.kind == exprLocal {
+		from := x.index
+		return func(locals, stack []rtda.Slot) bool {
+			locals[index] = locals[from]
+			return true
+		}
+	}
+	f := x.fn()
+	return func(locals, stack []rtda.Slot) bool {
+		locals[index] = rtda.IntSlot(f(locals, stack))
+		return true
+	}
+}
+
+// if<cond>和if_icmp<cond>的两个操作数都是表达式时 直接比较 不经过操作数栈
+func branchExpr(cond byte, x, y intExpr, target, next int) func(locals, stack []rtda.Slot) int {
+	if x.kind == exprLocal && y.kind == exprConst {
+		i, c := x.index, y.value
+		return func(locals, stack []rtda.Slot) int {
+			if ifcond(cond, locals[i].Int(), c) {
+				return target
+			}
+			return next
+		}
+	}
+	if x.kind == exprLocal && y.kind == exprLocal {
+		i, j := x.index, y.index
+		return func(locals, stack []rtda.Slot) int {
+			if ifcond(cond, locals[i].Int(), locals[j].Int()) {
+				return target
+			}
+			return next
+		}
+	}
+	f, g := x.fn(), y.fn()
+	return func(locals, stack []rtda.Slot) int {
+		if ifcond(cond, f(locals, stack), g(locals, stack)) {
+			return target
+		}
+		return next
+	}
+}
+
+// 把一条指令加进表达式树 不能处理时返回false 由调用者先写回pending再正常编译
+func (self *jitCompiler) fuse(pc int, block *basicBlock, pending *[]intExpr) bool {
+	code := self.code
+	opcode := code[pc]
+	n := len(*pending)
+	push := func(x intExpr) bool {
+		*pending = append(*pending, x)
+		return true
+	}
+	switch {
+	case opcode >= 0x02 && opcode <= 0x08: // iconst_<i>
+		return push(constExpr(int32(opcode) - 3))
+	case opcode == 0x10: // bipush
+		return push(constExpr(int32(int8(code[pc+1]))))
+	case opcode == 0x11: // sipush
+		return push(constExpr(int32(int16(u16(code, pc+1)))))
+	case opcode == 0x15: // iload
+		return push(localExpr(uint(code[pc+1])))
+	case opcode >= 0x1a && opcode <= 0x1d: // iload_<n>
+		return push(localExpr(uint(opcode - 0x1a)))
+	case isIntBinary(opcode) && n >= 2:
+		(*pending)[n-2] = binaryExpr(opcode, (*pending)[n-2], (*pending)[n-1])
+		*pending = (*pending)[:n-1]
+		return true
+	case (opcode == 0x74 || opcode >= 0x91 && opcode <= 0x93) && n >= 1:
+		(*pending)[n-1] = unaryExpr(opcode, (*pending)[n-1])
+		return true
+	case (opcode == 0x36 || opcode >= 0x3b && opcode <= 0x3e) && n >= 1: // istore istore_<n>
+		index := uint(opcode - 0x3b)
+		if opcode == 0x36 {
+			index = uint(code[pc+1])
+		}
+		x := (*pending)[n-1]
+		*pending = (*pending)[:n-1]
+		self.spill(pc, block, pending, 1)
+		block.add(pc, storeExpr(index, x))
+		return true
+	case opcode >= 0x99 && opcode <= 0x9e && n >= 1: // if<cond>
+		x := (*pending)[n-1]
+		*pending = (*pending)[:n-1]
+		self.spill(pc, block, pending, 1)
+		block.next = branchExpr(opcode-0x99, x, constExpr(0), pc+branchOffset(code, pc), pc+3)
+		return true
+	case opcode >= 0x9f && opcode <= 0xa4 && n >= 2: // if_icmp<cond>
+		x, y := (*pending)[n-2], (*pending)[n-1]
+		*pending = (*pending)[:n-2]
+		self.spill(pc, block, pending, 2)
+		block.next = branchExpr(opcode-0x9f, x, y, pc+branchOffset(code, pc), pc+3)
+		return true
+	}
+	return false
+}
+
+// 还没用掉的表达式写回操作数栈 above是pc处的指令已经从pending里取走的槽数
+func (self *jitCompiler) spill(pc int, block *basicBlock, pending *[]intExpr, above int) {
+	bottom := uint(self.depths[pc] - above - len(*pending))
+	for i, x := range *pending {
+		block.add(pc, spillExpr(bottom+uint(i), x))
+	}
+	*pending = (*pending)[:0]
+}
+
+func (self *basicBlock) add(pc int, op jitOp) {
+	self.ops = append(self.ops, op)
+	self.pcs = append(self.pcs, pc)
+}
@@ -4,20 +4,59 @@ import (
 	"fmt"
 	"jvm/classpath"
 	"jvm/instructions/base"
+	"jvm/native"
 	"jvm/rtda"
 	"jvm/rtda/heap"
+	"os"
 	"strings"
+	"unicode/utf16"
 )
 
 type JVM struct {
 	cmd *Cmd
 	classLoader *heap.ClassLoader
 	mainThread *rtda.Thread
+	systemGroup *heap.Object // 虚拟机内部线程所属的线程组
 }
 
 func newJVM(cmd *Cmd) *JVM {
+	frames, bytes, err := parseStackSize(cmd.XssOption)
+	if err != nil || frames == 0 && bytes == 0 && cmd.XssOption != "" {
+		usageError("Invalid thread stack size: -Xss" + cmd.XssOption)
+	}
+	rtda.SetStackSize(frames, bytes)
+
+	maxHeap, err := parseMemorySize(cmd.XmxOption)
+	if err != nil {
+		usageError("Invalid maximum heap size: -Xmx" + cmd.XmxOption)
+	}
+
+	// -XX:+UseJIT并且没有-Xint时是混合模式 默认的解释器加上JIT
+	if cmd.XintOption != "" && cmd.XintOption != "default" && cmd.XintOption != "fast" {
+		usageError("Invalid interpreter: -Xint:" + cmd.XintOption)
+	}
+	fastInterpreter = cmd.XintOption == "fast"
+	useJIT = cmd.useJITFlag && cmd.XintOption == "" && !cmd.verboseInstFlag
+	printCompilation = cmd.printCompilationFlag
+	base.SetInterpreter(func(thread *rtda.Thread, barrier *rtda.Frame) {
+		run(thread, cmd.verboseInstFlag, barrier)
+	})
+	if useJIT {
+		base.SetInvocationCounter(countInvocation)
+	}
+
+	if cmd.XlinkOption != "lazy" && cmd.XlinkOption != "eager" {
+		usageError("Invalid linking mode: -Xlink:" + cmd.XlinkOption)
+	}
+
+	if cmd.XshareOption != "auto" && cmd.XshareOption != "dump" && cmd.XshareOption != "off" {
+		usageError("Invalid sharing mode: -Xshare:" + cmd.XshareOption)
+	}
+
 	cp := classpath.Parse(cmd.XjreOption, cmd.cpOption)
-	classLoader := heap.NewClassLoader(cp, cmd.verboseClassFlag)
+	classLoader := heap.NewClassLoader(cp, heap.NewHeap(maxHeap),
+		cmd.verboseClassFlag, cmd.XlinkOption == "eager", openSharedArchive(cmd, cp))
+	classLoader.Heap().SetThreadRoots(heapRoots)
 	return &JVM{
 		cmd: cmd,
 		classLoader: classLoader,
@@ -25,15 +64,115 @@ func newJVM(cmd *Cmd) *JVM {
 	}
 }
 
+// -Xshare:auto时归档不存在或者已经过期 就照常从jar包加载
+func openSharedArchive(cmd *Cmd, cp *classpath.Classpath) *heap.SharedArchive {
+	path := cmd.sharedArchiveFile
+	if path == "" {
+		path = defaultSharedArchiveFile()
+	}
+	switch cmd.XshareOption {
+	case "off":
+		return nil
+	case "dump":
+		return heap.NewSharedArchive(path)
+	}
+	archive, err := heap.OpenSharedArchive(path, cp)
+	if err != nil {
+		if cmd.verboseClassFlag {
+			fmt.Printf("[Shared archive %s ignored: %v]\n", path, err)
+		}
+		return nil
+	}
+	return archive
+}
+
 func (self *JVM) start() {
+	self.mainThread.Acquire()
+	self.classLoader.BeginPhase("vm init")
 	self.initVM()
+	if self.cmd.XshareOption == "dump" {
+		self.dumpSharedArchive()
+		return
+	}
+	self.startAttachListener()
+	if self.cmd.heapDumpOnOutOfMemoryFlag {
+		self.enableHeapDumpOnOutOfMemory()
+	}
+	self.classLoader.BeginPhase("application")
 	self.execMain()
+	self.destroyVM()
+	native.RunExitHooks()
+	if self.cmd.verboseClassFlag {
+		self.classLoader.PrintStats()
+	}
+}
+
+// 和DestroyJavaVM一样 等其余的非守护线程都结束之后执行关闭钩子
+func (self *JVM) destroyVM() {
+	self.mainThread.Blocking(rtda.WaitNonDaemonThreads)
+	shutdownClass := self.classLoader.LoadClass("java/lang/Shutdown")
+	shutdown := shutdownClass.GetStaticMethod("shutdown", "()V")
+	base.InvokeAndWait(self.mainThread, shutdown, nil)
 }
 
 func (self *JVM) initVM() {
+	self.createMainThread()
+	self.startReferenceHandler()
+	self.startFinalizer()
+	self.startSignalDispatcher()
 	vmClass := self.classLoader.LoadClass("sun/misc/VM")
-	base.InitClass(self.mainThread, vmClass)
-	interpret(self.mainThread, self.cmd.verboseInstFlag)
+	if ex := base.InitClassAndWait(self.mainThread, vmClass); ex != nil {
+		base.Rethrow(self.mainThread, ex) // 交给athrow按未捕获的异常处理
+		interpret(self.mainThread, self.cmd.verboseInstFlag)
+	}
+}
+
+// 只归档initVM期间加载的类 应用的类每次运行都不一样
+func (self *JVM) dumpSharedArchive() {
+	n, err := self.classLoader.DumpSharedArchive()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error dumping shared archive: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Dumped %d classes to %s\n", n, self.classLoader.SharedArchivePath())
+}
+
+// 主线程的java.lang.Thread对象由虚拟机直接构造 而不是执行构造函数
+// system线程组 -> main线程组 -> main线程
+func (self *JVM) createMainThread() {
+	loader := self.classLoader
+	groupClass := loader.LoadClass("java/lang/ThreadGroup")
+
+	systemGroup := groupClass.NewObject()
+	systemGroup.SetRefVar("name", "Ljava/lang/String;", heap.JString(loader, "system"))
+	systemGroup.SetIntVar("maxPriority", "I", 10) // Thread.MAX_PRIORITY
+
+	mainGroup := groupClass.NewObject()
+	mainGroup.SetRefVar("parent", "Ljava/lang/ThreadGroup;", systemGroup)
+	mainGroup.SetRefVar("name", "Ljava/lang/String;", heap.JString(loader, "main"))
+	mainGroup.SetIntVar("maxPriority", "I", 10)
+	groups := groupClass.ArrayClass().NewArray(4)
+	groups.Refs()[0] = mainGroup
+	systemGroup.SetRefVar("groups", "[Ljava/lang/ThreadGroup;", groups)
+	systemGroup.SetIntVar("ngroups", "I", 1)
+
+	self.systemGroup = systemGroup
+	self.mainThread.SetJThread(self.newJThread("main", mainGroup, 5, false)) // Thread.NORM_PRIORITY
+}
+
+func (self *JVM) newJThread(name string, group *heap.Object, priority int32, daemon bool) *heap.Object {
+	loader := self.classLoader
+	jThread := loader.LoadClass("java/lang/Thread").NewObject()
+	jThread.SetRefVar("name", "[C", heap.NewCharArray(loader, utf16.Encode([]rune(name))))
+	jThread.SetRefVar("group", "Ljava/lang/ThreadGroup;", group)
+	jThread.SetRefVar("blockerLock", "Ljava/lang/Object;",
+		loader.LoadClass("java/lang/Object").NewObject())
+	jThread.SetIntVar("priority", "I", priority)
+	if daemon {
+		jThread.SetIntVar("daemon", "Z", 1)
+	}
+	jThread.SetIntVar("threadStatus", "I", 5) // JVMTI_THREAD_STATE_ALIVE | JVMTI_THREAD_STATE_RUNNABLE
+	return jThread
 }
 
 func (self *JVM) execMain() {
@@ -45,6 +184,12 @@ func (self *JVM) execMain() {
 		return
 	}
 
+	if ex := base.InitClassAndWait(self.mainThread, mainClass); ex != nil {
+		base.Rethrow(self.mainThread, ex)
+		interpret(self.mainThread, self.cmd.verboseInstFlag)
+		return
+	}
+
 	argsArr := self.createArgsArray()
 	frame := self.mainThread.NewFrame(mainMethod)
 	frame.LocalVars().SetRef(0, argsArr)
@@ -1,12 +1,21 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "jstack" {
+		os.Exit(jstack(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "jmap" {
+		os.Exit(jmap(os.Args[2:]))
+	}
 	cmd := parseCmd()
 	if cmd.versionFlag {
 		fmt.Println("version: v0.0.1")
-	} else if cmd.helpFlag || cmd.class == "" {
+	} else if cmd.helpFlag || cmd.class == "" && cmd.XshareOption != "dump" {
 		printUsage()
 	} else {
 		newJVM(cmd).start()
@@ -0,0 +1,27 @@
+package native
+
+import "sync"
+
+// 进程退出之前要做的清理 比如删除Attach Listener的socket文件
+// Shutdown.halt0()直接调用os.Exit 不会执行Go的defer 所以在这里登记
+var exitHooks struct {
+	sync.Mutex
+	hooks []func()
+}
+
+func AtExit(hook func()) {
+	exitHooks.Lock()
+	exitHooks.hooks = append(exitHooks.hooks, hook)
+	exitHooks.Unlock()
+}
+
+// 每个钩子只执行一次
+func RunExitHooks() {
+	exitHooks.Lock()
+	hooks := exitHooks.hooks
+	exitHooks.hooks = nil
+	exitHooks.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
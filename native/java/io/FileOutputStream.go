@@ -3,6 +3,7 @@ package io
 import (
 	"jvm/native"
 	"jvm/rtda"
+	"jvm/rtda/heap"
 	"os"
 	"unsafe"
 )
@@ -17,14 +18,28 @@ func init() {
 // ([BIIZ)V
 func writeBytes(frame *rtda.Frame) {
 	vars := frame.LocalVars()
+	this := vars.GetThis()
 	b := vars.GetRef(1)
 	off := vars.GetInt(2)
 	len := vars.GetInt(3)
 
-	jBytes := b.Data().([]int8)
+	jBytes := b.Bytes()
 	goBytes := castInt8sToUint8s(jBytes)
 	goBytes = goBytes[off : off+len]
-	os.Stdout.Write(goBytes)
+	if _getFd(this) == 2 {
+		os.Stderr.Write(goBytes)
+	} else {
+		os.Stdout.Write(goBytes)
+	}
+}
+
+// FileOutputStream.fd.fd 标准错误输出的文件描述符是2
+func _getFd(fosObj *heap.Object) int32 {
+	fdObj := fosObj.GetRefVar("fd", "Ljava/io/FileDescriptor;")
+	if fdObj == nil {
+		return -1
+	}
+	return fdObj.GetIntVar("fd", "I")
 }
 
 func castInt8sToUint8s(jBytes []int8) (goBytes []byte) {
@@ -18,6 +18,7 @@ func init() {
 	native.Register(jlClass, "forName0", "(Ljava/lang/String;ZLjava/lang/ClassLoader;Ljava/lang/Class;)Ljava/lang/Class;", forName0)
 	native.Register(jlClass, "getDeclaredConstructors0", "(Z)[Ljava/lang/reflect/Constructor;", getDeclaredConstructors0)
 	native.Register(jlClass, "getModifiers", "()I", getModifiers)
+	native.Register(jlClass, "getClassLoader0", "()Ljava/lang/ClassLoader;", getClassLoader0)
 	native.Register(jlClass, "getSuperclass", "()Ljava/lang/Class;", getSuperclass)
 	native.Register(jlClass, "getInterfaces0", "()[Ljava/lang/Class;", getInterfaces0)
 	native.Register(jlClass, "isArray", "()Z", isArray)
@@ -45,7 +46,7 @@ func getName0(frame *rtda.Frame) {
 	class := this.Extra().(*heap.Class)
 
 	name := class.JavaName()
-	nameObj := heap.JString(class.Loader(), name)
+	nameObj := heap.InternedJString(class.Loader(), name)
 
 	frame.OperandStack().PushRef(nameObj)
 }
@@ -88,23 +89,28 @@ func forName0(frame *rtda.Frame) {
 	vars := frame.LocalVars()
 	jName := vars.GetRef(0)
 	initialize := vars.GetBoolean(1)
-	//jLoader := vars.GetRef(2)
-
-	goName := heap.GoString(jName)
-	goName = strings.Replace(goName, ".", "/", -1)
-	goClass := frame.Method().Class().Loader().LoadClass(goName)
+	jLoader := vars.GetRef(2)
+
+	javaName := heap.GoString(jName)
+	goName := strings.Replace(javaName, ".", "/", -1)
+	loader := frame.Method().Class().Loader().UserLoader(jLoader)
+	var goClass *heap.Class
+	if loader.IsBootstrap() {
+		if goClass = loader.FindBootstrapClass(goName); goClass == nil {
+			panic(heap.NewJavaException("java/lang/ClassNotFoundException", javaName))
+		}
+	} else {
+		goClass = loader.LoadClass(goName)
+	}
 	jClass := goClass.JClass()
 
-	if initialize && !goClass.InitStarted() {
-		// undo forName0
-		thread := frame.Thread()
-		frame.SetNextPC(thread.PC())
-		// init class
-		base.InitClass(thread, goClass)
-	} else {
-		stack := frame.OperandStack()
-		stack.PushRef(jClass)
+	if initialize {
+		if ex := base.InitClassAndWait(frame.Thread(), goClass); ex != nil {
+			base.Rethrow(frame.Thread(), ex)
+			return
+		}
 	}
+	frame.OperandStack().PushRef(jClass)
 }
 
 // public native int getModifiers();
@@ -185,3 +191,11 @@ func isAssignableFrom(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	stack.PushBoolean(ok)
 }
+
+// ClassLoader getClassLoader0();
+// ()Ljava/lang/ClassLoader;
+func getClassLoader0(frame *rtda.Frame) {
+	this := frame.LocalVars().GetThis()
+	class := this.Extra().(*heap.Class)
+	frame.OperandStack().PushRef(class.Loader().JLoader())
+}
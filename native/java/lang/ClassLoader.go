@@ -0,0 +1,97 @@
+package lang
+
+import (
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"strings"
+)
+
+const jlClassLoader = "java/lang/ClassLoader"
+
+func init() {
+	native.Register(jlClassLoader, "defineClass0", "(Ljava/lang/String;[BIILjava/security/ProtectionDomain;)Ljava/lang/Class;", defineClass0)
+	native.Register(jlClassLoader, "defineClass1", "(Ljava/lang/String;[BIILjava/security/ProtectionDomain;Ljava/lang/String;)Ljava/lang/Class;", defineClass1)
+	native.Register(jlClassLoader, "findLoadedClass0", "(Ljava/lang/String;)Ljava/lang/Class;", findLoadedClass0)
+	native.Register(jlClassLoader, "findBootstrapClass", "(Ljava/lang/String;)Ljava/lang/Class;", findBootstrapClass)
+}
+
+// private native Class<?> defineClass0(String name, byte[] b, int off, int len,
+//                                      ProtectionDomain pd);
+// (Ljava/lang/String;[BIILjava/security/ProtectionDomain;)Ljava/lang/Class;
+func defineClass0(frame *rtda.Frame) {
+	_defineClass(frame, "__JVM_DefineClass__")
+}
+
+// private native Class<?> defineClass1(String name, byte[] b, int off, int len,
+//                                      ProtectionDomain pd, String source);
+// (Ljava/lang/String;[BIILjava/security/ProtectionDomain;Ljava/lang/String;)Ljava/lang/Class;
+func defineClass1(frame *rtda.Frame) {
+	source := "__JVM_DefineClass__"
+	if jSource := frame.LocalVars().GetRef(6); jSource != nil {
+		source = heap.GoString(jSource)
+	}
+	_defineClass(frame, source)
+}
+
+func _defineClass(frame *rtda.Frame, source string) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	jName := vars.GetRef(1)
+	byteArr := vars.GetRef(2)
+	off := vars.GetInt(3)
+	length := vars.GetInt(4)
+
+	if byteArr == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	if off < 0 || length < 0 || off+length > byteArr.ArrayLength() {
+		panic(heap.NewJavaException("java/lang/ArrayIndexOutOfBoundsException", ""))
+	}
+	name := ""
+	if jName != nil {
+		name = toInternalName(heap.GoString(jName))
+	}
+
+	data := make([]byte, length)
+	for i, b := range byteArr.Bytes()[off : off+length] {
+		data[i] = byte(b)
+	}
+
+	loader := frame.Method().Class().Loader().UserLoader(this)
+	class := loader.DefineClass(name, data, source)
+	frame.OperandStack().PushRef(class.JClass())
+}
+
+// private native final Class<?> findLoadedClass0(String name);
+// (Ljava/lang/String;)Ljava/lang/Class;
+func findLoadedClass0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	name := toInternalName(heap.GoString(vars.GetRef(1)))
+
+	loader := frame.Method().Class().Loader().UserLoader(this)
+	if class := loader.FindLoadedClass(name); class != nil {
+		frame.OperandStack().PushRef(class.JClass())
+	} else {
+		frame.OperandStack().PushRef(nil)
+	}
+}
+
+// private native Class<?> findBootstrapClass(String name);
+// (Ljava/lang/String;)Ljava/lang/Class;
+func findBootstrapClass(frame *rtda.Frame) {
+	name := toInternalName(heap.GoString(frame.LocalVars().GetRef(1)))
+
+	loader := frame.Method().Class().Loader()
+	if class := loader.FindBootstrapClass(name); class != nil {
+		frame.OperandStack().PushRef(class.JClass())
+	} else {
+		frame.OperandStack().PushRef(nil)
+	}
+}
+
+// java.lang.String -> java/lang/String
+func toInternalName(name string) string {
+	return strings.Replace(name, ".", "/", -1)
+}
@@ -0,0 +1,53 @@
+package lang
+
+import (
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"strings"
+)
+
+const jlNativeLibrary = "java/lang/ClassLoader$NativeLibrary"
+
+// 本地方法都静态链接在虚拟机里 System.loadLibrary("net")之类的调用
+// 按JEP 178的静态库处理 不去文件系统里找动态库
+func init() {
+	native.Register(jlClassLoader, "findBuiltinLib", "(Ljava/lang/String;)Ljava/lang/String;", findBuiltinLib)
+	native.Register(jlNativeLibrary, "load", "(Ljava/lang/String;Z)V", nativeLibraryLoad)
+	native.Register(jlNativeLibrary, "load", "(Ljava/lang/String;)V", nativeLibraryLoad)
+	native.Register(jlNativeLibrary, "find", "(Ljava/lang/String;)J", nativeLibraryFind)
+	native.Register(jlNativeLibrary, "unload", "(Ljava/lang/String;Z)V", nativeLibraryUnload)
+	native.Register(jlNativeLibrary, "unload", "(Ljava/lang/String;)V", nativeLibraryUnload)
+}
+
+// 参数是mapLibraryName之后的文件名 返回去掉前后缀的库名
+// private static native String findBuiltinLib(String name);
+// (Ljava/lang/String;)Ljava/lang/String;
+func findBuiltinLib(frame *rtda.Frame) {
+	jName := frame.LocalVars().GetRef(0)
+
+	name := heap.GoString(jName)
+	name = strings.TrimPrefix(name, "lib")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".so"), ".dylib")
+	loader := frame.Method().Class().Loader()
+	frame.OperandStack().PushRef(heap.JString(loader, name))
+}
+
+// native void load(String name, boolean isBuiltin);
+// (Ljava/lang/String;Z)V
+func nativeLibraryLoad(frame *rtda.Frame) {
+	this := frame.LocalVars().GetThis()
+	this.SetIntVar("loaded", "Z", 1)
+}
+
+// 没有真正的符号表 JNI查找都走native.Register注册的方法
+// native long find(String name);
+// (Ljava/lang/String;)J
+func nativeLibraryFind(frame *rtda.Frame) {
+	frame.OperandStack().PushLong(0)
+}
+
+// native void unload(String name, boolean isBuiltin);
+// (Ljava/lang/String;Z)V
+func nativeLibraryUnload(frame *rtda.Frame) {
+}
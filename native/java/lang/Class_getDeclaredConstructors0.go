@@ -62,7 +62,9 @@ func getDeclaredConstructors0(frame *rtda.Frame) {
 			ops.PushRef(toByteArr(classLoader, constructor.ParameterAnnotationData())) // parameterAnnotations
 
 			shimFrame := rtda.NewShimFrame(thread, ops)
-			thread.PushFrame(shimFrame)
+			if !base.PushShimFrame(thread, shimFrame) {
+				return
+			}
 
 			// init constructorObj
 			base.InvokeMethod(shimFrame, constructorInitMethod)
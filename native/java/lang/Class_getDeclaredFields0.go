@@ -52,7 +52,7 @@ func getDeclaredFields0(frame *rtda.Frame) {
 			ops := rtda.NewOperandStack(8)
 			ops.PushRef(fieldObj)                                          // this
 			ops.PushRef(classObj)                                          // declaringClass
-			ops.PushRef(heap.JString(classLoader, goField.Name()))         // name
+			ops.PushRef(heap.InternedJString(classLoader, goField.Name()))         // name
 			ops.PushRef(goField.Type().JClass())                           // type
 			ops.PushInt(int32(goField.AccessFlags()))                      // modifiers
 			ops.PushInt(int32(goField.SlotId()))                           // slot
@@ -60,7 +60,9 @@ func getDeclaredFields0(frame *rtda.Frame) {
 			ops.PushRef(toByteArr(classLoader, goField.AnnotationData()))  // annotations
 
 			shimFrame := rtda.NewShimFrame(thread, ops)
-			thread.PushFrame(shimFrame)
+			if !base.PushShimFrame(thread, shimFrame) {
+				return
+			}
 
 			// init fieldObj
 			base.InvokeMethod(shimFrame, fieldConstructor)
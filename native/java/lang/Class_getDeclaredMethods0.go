@@ -58,7 +58,7 @@ func getDeclaredMethods0(frame *rtda.Frame) {
 			ops := rtda.NewOperandStack(12)
 			ops.PushRef(methodObj)                                                // this
 			ops.PushRef(classObj)                                                 // declaringClass
-			ops.PushRef(heap.JString(classLoader, method.Name()))                 // name
+			ops.PushRef(heap.InternedJString(classLoader, method.Name()))                 // name
 			ops.PushRef(toClassArr(classLoader, method.ParameterTypes()))         // parameterTypes
 			ops.PushRef(method.ReturnType().JClass())                             // returnType
 			ops.PushRef(toClassArr(classLoader, method.ExceptionTypes()))         // checkedExceptions
@@ -70,7 +70,9 @@ func getDeclaredMethods0(frame *rtda.Frame) {
 			ops.PushRef(toByteArr(classLoader, method.AnnotationDefaultData()))   // annotationDefault
 
 			shimFrame := rtda.NewShimFrame(thread, ops)
-			thread.PushFrame(shimFrame)
+			if !base.PushShimFrame(thread, shimFrame) {
+				return
+			}
 
 			// init methodObj
 			base.InvokeMethod(shimFrame, methodConstructor)
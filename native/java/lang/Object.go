@@ -1,9 +1,10 @@
 package lang
 
 import (
-	"fmt"
 	"jvm/native"
 	"jvm/rtda"
+	"jvm/rtda/heap"
+	"time"
 	"unsafe"
 )
 
@@ -13,6 +14,8 @@ func init() {
 	native.Register(jlObject, "getClass", "()Ljava/lang/Class;", getClass)
 	native.Register(jlObject, "hashCode", "()I", hashCode)
 	native.Register(jlObject, "clone", "()Ljava/lang/Object;", clone)
+	native.Register(jlObject, "wait", "(J)V", wait)
+	native.Register(jlObject, "notify", "()V", notify)
 	native.Register(jlObject, "notifyAll", "()V", notifyAll)
 }
 
@@ -21,7 +24,6 @@ func init() {
 func getClass(frame *rtda.Frame) {
 	this := frame.LocalVars().GetThis()
 	class := this.Class().JClass() // 获取类对象引用 java.lang.Class
-	fmt.Println("114514 getClass", this.Class().Name())
 	frame.OperandStack().PushRef(class)
 }
 
@@ -46,8 +48,37 @@ func clone(frame *rtda.Frame) {
 	frame.OperandStack().PushRef(this.Clone())
 }
 
+// public final native void wait(long timeout) throws InterruptedException;
+// (J)V
+func wait(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	timeout := vars.GetLong(1)
+	if timeout < 0 {
+		panic(heap.NewJavaException("java/lang/IllegalArgumentException", "timeout value is negative"))
+	}
+
+	thread := frame.Thread()
+	if !this.Monitor().IsOwner(thread) {
+		panic(heap.NewJavaException("java/lang/IllegalMonitorStateException", ""))
+	}
+	thread.WaitMonitor(this, time.Duration(timeout)*time.Millisecond)
+}
+
+// public final native void notify();
+// ()V
+func notify(frame *rtda.Frame) {
+	this := frame.LocalVars().GetThis()
+	if !this.Monitor().Notify(frame.Thread()) {
+		panic(heap.NewJavaException("java/lang/IllegalMonitorStateException", ""))
+	}
+}
+
 // public final native void notifyAll();
 // ()V
 func notifyAll(frame *rtda.Frame) {
-  // todo
+	this := frame.LocalVars().GetThis()
+	if !this.Monitor().NotifyAll(frame.Thread()) {
+		panic(heap.NewJavaException("java/lang/IllegalMonitorStateException", ""))
+	}
 }
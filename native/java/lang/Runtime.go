@@ -8,6 +8,11 @@ const jlRuntime = "java/lang/Runtime"
 
 func init() {
 	native.Register(jlRuntime, "availableProcessors", "()I", availableProcessors)
+	native.Register(jlRuntime, "freeMemory", "()J", freeMemory)
+	native.Register(jlRuntime, "totalMemory", "()J", totalMemory)
+	native.Register(jlRuntime, "maxMemory", "()J", maxMemory)
+	native.Register(jlRuntime, "gc", "()V", gc)
+	native.Register(jlRuntime, "runFinalization0", "()V", runFinalization0)
 }
 
 // public native int availableProcessors();
@@ -18,3 +23,38 @@ func availableProcessors(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	stack.PushInt(int32(numCPU))
 }
+
+// public native long freeMemory();
+// ()J
+func freeMemory(frame *rtda.Frame) {
+	heap := frame.Method().Class().Loader().Heap()
+	frame.OperandStack().PushLong(heap.FreeMemory())
+}
+
+// public native long totalMemory();
+// ()J
+func totalMemory(frame *rtda.Frame) {
+	heap := frame.Method().Class().Loader().Heap()
+	frame.OperandStack().PushLong(heap.TotalMemory())
+}
+
+// public native long maxMemory();
+// ()J
+func maxMemory(frame *rtda.Frame) {
+	heap := frame.Method().Class().Loader().Heap()
+	frame.OperandStack().PushLong(heap.MaxMemory())
+}
+
+// public native void gc();
+// ()V
+func gc(frame *rtda.Frame) {
+	frame.Method().Class().Loader().Heap().GC()
+}
+
+// private static native void runFinalization0();
+// ()V
+func runFinalization0(frame *rtda.Frame) {
+	heap := frame.Method().Class().Loader().Heap()
+	heap.GC()
+	frame.Thread().Blocking(heap.RunFinalization)
+}
@@ -0,0 +1,32 @@
+package lang
+
+import (
+	"jvm/native"
+	"jvm/rtda"
+	"os"
+)
+
+const jlShutdown = "java/lang/Shutdown"
+
+func init() {
+	native.Register(jlShutdown, "halt0", "(I)V", halt0)
+	native.Register(jlShutdown, "runAllFinalizers", "()V", runAllFinalizers)
+}
+
+// 关闭钩子已经执行完了 直接结束进程
+// static native void halt0(int status);
+// (I)V
+func halt0(frame *rtda.Frame) {
+	status := frame.LocalVars().GetInt(0)
+	native.RunExitHooks()
+	os.Exit(int(status))
+}
+
+// 只在调用过Runtime.runFinalizersOnExit(true)时使用
+// private static native void runAllFinalizers();
+// ()V
+func runAllFinalizers(frame *rtda.Frame) {
+	heap := frame.Method().Class().Loader().Heap()
+	heap.GC()
+	frame.Thread().Blocking(heap.RunFinalization)
+}
@@ -5,8 +5,11 @@ import (
 	"jvm/native"
 	"jvm/rtda"
 	"jvm/rtda/heap"
+	"os"
+	"os/user"
 	"runtime"
 	"time"
+	"unsafe"
 )
 
 const jlSystem = "java/lang/System"
@@ -20,6 +23,8 @@ func init() {
   native.Register(jlSystem, "setOut0", "(Ljava/io/PrintStream;)V", setOut0)
   native.Register(jlSystem, "setErr0", "(Ljava/io/PrintStream;)V", setErr0)
   native.Register(jlSystem, "currentTimeMillis", "()J", currentTimeMillis)
+  native.Register(jlSystem, "identityHashCode", "(Ljava/lang/Object;)I", identityHashCode)
+  native.Register(jlSystem, "mapLibraryName", "(Ljava/lang/String;)Ljava/lang/String;", mapLibraryName)
 }
 
 func arraycopy(frame *rtda.Frame) {
@@ -31,7 +36,7 @@ func arraycopy(frame *rtda.Frame) {
 	length := vars.GetInt(4)
 
 	if src == nil || dst == nil {
-		panic("java.lang.NullPointerException")
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
 	}
 	if !checkArrayCopy(src, dst) {
 		panic("java.lang.ArrayStoreException")
@@ -65,23 +70,20 @@ func initProperties(frame *rtda.Frame) {
 	vars := frame.LocalVars()
 	props := vars.GetRef(0)
 
-	stack := frame.OperandStack()
-	stack.PushRef(props)
-
 	setPropMethod := props.Class().GetInstanceMethod("setProperty",
 		"(Ljava/lang/String;Ljava/lang/String;)Ljava/lang/Object;")
 	thread := frame.Thread()
 	for k, v := range _sysProps() {
 		jKey := heap.JString(frame.Method().Class().Loader(), k)
 		jVal := heap.JString(frame.Method().Class().Loader(), v)
-		ops := rtda.NewOperandStack(3)
-		ops.PushRef(props)
-		ops.PushRef(jKey)
-		ops.PushRef(jVal)
-		shimFrame := rtda.NewShimFrame(thread, ops)
-		thread.PushFrame(shimFrame)
-		base.InvokeMethod(shimFrame, setPropMethod)
+		args := []rtda.Slot{rtda.RefSlot(props), rtda.RefSlot(jKey), rtda.RefSlot(jVal)}
+		if _, ex := base.InvokeAndWait(thread, setPropMethod, args); ex != nil {
+			base.Rethrow(thread, ex)
+			return
+		}
 	}
+
+	frame.OperandStack().PushRef(props)
 }
 
 func _sysProps() map[string]string {
@@ -93,22 +95,58 @@ func _sysProps() map[string]string {
 		"java.class.version":   "52.0",
 		"java.class.path":      "todo",
 		"java.awt.graphicsenv": "sun.awt.CGraphicsEnvironment",
-		"os.name":              runtime.GOOS,   // todo
+		"os.name":              _osName(),
 		"os.arch":              runtime.GOARCH, // todo
 		"os.version":           "",             // todo
 		"file.separator":       "/",            // todo os.PathSeparator
 		"path.separator":       ":",            // todo os.PathListSeparator
 		"line.separator":       "\n",           // todo
-		"user.name":            "",             // todo
-		"user.home":            "",             // todo
-		"user.dir":             ".",            // todo
+		"user.name":            _userName(),
+		"user.home":            _userHome(),
+		"user.dir":             _userDir(), // sun.nio.fs要求是绝对路径
+		"java.io.tmpdir":       os.TempDir(),
 		"user.country":         "CN",           // todo
 		"file.encoding":        "UTF-8",
+		"sun.jnu.encoding":     "UTF-8", // 文件名的编码
 		"sun.stdout.encoding":  "UTF-8",
 		"sun.stderr.encoding":  "UTF-8",
 	}
 }
 
+// DefaultFileSystemProvider按os.name选择实现 比如Linux对应LinuxFileSystemProvider
+func _osName() string {
+	switch runtime.GOOS {
+	case "linux":
+		return "Linux"
+	case "darwin":
+		return "Mac OS X"
+	case "windows":
+		return "Windows"
+	}
+	return runtime.GOOS
+}
+
+func _userName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func _userHome() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return ""
+}
+
+func _userDir() string {
+	if dir, err := os.Getwd(); err == nil {
+		return dir
+	}
+	return "."
+}
+
 // private static native void setIn0(InputStream in);
 // (Ljava/io/InputStream;)V
 func setIn0(frame *rtda.Frame) {
@@ -146,3 +184,27 @@ func currentTimeMillis(frame *rtda.Frame) {
 	stack := frame.OperandStack()
 	stack.PushLong(millis)
 }
+
+// public static native int identityHashCode(Object x);
+// (Ljava/lang/Object;)I
+func identityHashCode(frame *rtda.Frame) {
+	ref := frame.LocalVars().GetRef(0)
+	hash := int32(uintptr(unsafe.Pointer(ref)))
+	frame.OperandStack().PushInt(hash)
+}
+
+// public static native String mapLibraryName(String libname);
+// (Ljava/lang/String;)Ljava/lang/String;
+func mapLibraryName(frame *rtda.Frame) {
+	libname := frame.LocalVars().GetRef(0)
+	if libname == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+
+	suffix := ".so"
+	if runtime.GOOS == "darwin" {
+		suffix = ".dylib"
+	}
+	loader := frame.Method().Class().Loader()
+	frame.OperandStack().PushRef(heap.JString(loader, "lib"+heap.GoString(libname)+suffix))
+}
@@ -1,19 +1,33 @@
 package lang
 
+import "jvm/instructions/base"
 import "jvm/native"
 import "jvm/rtda"
+import "jvm/rtda/heap"
+
+// Thread.threadStatus的取值和JVMTI的线程状态一样
+const (
+	JVMTI_THREAD_STATE_ALIVE      = 0x0001
+	JVMTI_THREAD_STATE_TERMINATED = 0x0002
+	JVMTI_THREAD_STATE_RUNNABLE   = 0x0004
+)
 
 func init() {
 	native.Register("java/lang/Thread", "currentThread", "()Ljava/lang/Thread;", currentThread)
 	native.Register("java/lang/Thread", "setPriority0", "(I)V", setPriority0)
 	native.Register("java/lang/Thread", "isAlive", "()Z", isAlive)
 	native.Register("java/lang/Thread", "start0", "()V", start0)
+	native.Register("java/lang/Thread", "holdsLock", "(Ljava/lang/Object;)Z", holdsLock)
 }
 
 // public static native Thread currentThread();
 // ()Ljava/lang/Thread;
 func currentThread(frame *rtda.Frame) {
-	//jThread := frame.Thread().JThread()
+	if jThread := frame.Thread().JThread(); jThread != nil {
+		frame.OperandStack().PushRef(jThread)
+		return
+	}
+
 	classLoader := frame.Method().Class().Loader()
 	threadClass := classLoader.LoadClass("java/lang/Thread")
 	jThread := threadClass.NewObject()
@@ -39,15 +53,56 @@ func setPriority0(frame *rtda.Frame) {
 // public final native boolean isAlive();
 // ()Z
 func isAlive(frame *rtda.Frame) {
-	//vars := frame.LocalVars()
-	//this := vars.GetThis()
+	vars := frame.LocalVars()
+	this := vars.GetThis()
 
 	stack := frame.OperandStack()
-	stack.PushBoolean(false)
+	stack.PushBoolean(this.GetIntVar("threadStatus", "I")&JVMTI_THREAD_STATE_ALIVE != 0)
 }
 
+// 每个Java线程对应一个goroutine 轮流持有全局解释器锁执行字节码
 // private native void start0();
 // ()V
 func start0(frame *rtda.Frame) {
-	// todo
+	this := frame.LocalVars().GetThis()
+
+	thread := rtda.NewThread()
+	thread.SetJThread(this)
+	thread.Started(this.GetIntVar("daemon", "Z") != 0)
+	this.SetIntVar("threadStatus", "I", JVMTI_THREAD_STATE_ALIVE|JVMTI_THREAD_STATE_RUNNABLE)
+	go runThread(thread)
+}
+
+// 和HotSpot的JavaThread::run一样 执行run() 未捕获的异常交给dispatchUncaughtException()
+// 然后调用Thread.exit()把自己从线程组里移除 最后唤醒在join()里等待的线程
+func runThread(thread *rtda.Thread) {
+	thread.Acquire()
+	defer thread.Release()
+
+	jThread := thread.JThread()
+	class := jThread.Class()
+	args := []rtda.Slot{rtda.RefSlot(jThread)}
+	run := heap.LookupMethodInClass(class, "run", "()V")
+	if _, ex := base.InvokeAndWait(thread, run, args); ex != nil {
+		dispatch := class.GetInstanceMethod("dispatchUncaughtException", "(Ljava/lang/Throwable;)V")
+		base.InvokeAndWait(thread, dispatch, append(args, rtda.RefSlot(ex))) // 它自己抛出的异常被忽略
+	}
+	exit := class.GetInstanceMethod("exit", "()V")
+	base.InvokeAndWait(thread, exit, args)
+
+	jThread.SetIntVar("threadStatus", "I", JVMTI_THREAD_STATE_TERMINATED)
+	thread.EnterMonitor(jThread)
+	jThread.Monitor().NotifyAll(thread)
+	thread.ExitMonitor(jThread)
+	thread.Exited()
+}
+
+// public static native boolean holdsLock(Object obj);
+// (Ljava/lang/Object;)Z
+func holdsLock(frame *rtda.Frame) {
+	obj := frame.LocalVars().GetRef(0)
+	if obj == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	frame.OperandStack().PushBoolean(obj.Monitor().IsOwner(frame.Thread()))
 }
@@ -25,6 +25,9 @@ func (self *StackTraceElement) String() string {
 func init() {
 	native.Register(jlThrowable, "fillInStackTrace",
 		"(I)Ljava/lang/Throwable;", fillInStackTrace)
+	native.Register(jlThrowable, "getStackTraceDepth", "()I", getStackTraceDepth)
+	native.Register(jlThrowable, "getStackTraceElement",
+		"(I)Ljava/lang/StackTraceElement;", getStackTraceElement)
 }
 
 // (I)Ljava/lang/Throwable;
@@ -37,23 +40,34 @@ func fillInStackTrace(frame *rtda.Frame) {
 }
 
 func createStackTraceElements(tObj *heap.Object, thread *rtda.Thread) []*StackTraceElement{
-	// 由于栈顶两帧正在执行fillInStackTrace(int)和fillInStackTrace()方法,
-	// 所以需要跳过这两帧
-	skip := distanceToObject(tObj.Class()) + 2
-	frames := thread.GetFrames()[skip:]
-	stes := make([]*StackTraceElement, len(frames))
-	for i, frame := range frames {
-		stes[i] = createStackTraceElement(frame)
+	frames := thread.GetFrames()
+	skip := framesToSkip(tObj.Class(), frames)
+	frames = frames[skip:]
+	stes := make([]*StackTraceElement, 0, len(frames))
+	for _, frame := range frames {
+		if frame.Method().Class().Name() == "~shim" {
+			continue // 虚拟机内部的过渡帧不出现在栈轨迹中
+		}
+		stes = append(stes, createStackTraceElement(frame))
 	}
 	return stes
 }
 
-func distanceToObject(class *heap.Class) int {
-	distance := 0
-	for c := class.SuperClass(); c != nil; c = c.SuperClass() {
-		distance++
+// 栈顶的几帧正在执行fillInStackTrace(int)、fillInStackTrace()
+// 以及异常类及其超类的构造函数 这些帧都需要跳过
+func framesToSkip(exClass *heap.Class, frames []*rtda.Frame) int {
+	skip := 0
+	for skip < len(frames) && frames[skip].Method().Name() == "fillInStackTrace" {
+		skip++
 	}
-	return distance
+	for skip < len(frames) {
+		method := frames[skip].Method()
+		if method.Name() != "<init>" || !method.Class().IsAssignableFrom(exClass) {
+			break
+		}
+		skip++
+	}
+	return skip
 }
 
 func createStackTraceElement(frame *rtda.Frame)  *StackTraceElement {
@@ -66,3 +80,42 @@ func createStackTraceElement(frame *rtda.Frame)  *StackTraceElement {
 		lineNumber: method.GetLineNumber(frame.NextPC() - 1),
 	}
 }
+
+// private native int getStackTraceDepth();
+// ()I
+func getStackTraceDepth(frame *rtda.Frame) {
+	this := frame.LocalVars().GetThis()
+	stes, _ := this.Extra().([]*StackTraceElement)
+	frame.OperandStack().PushInt(int32(len(stes)))
+}
+
+// native StackTraceElement getStackTraceElement(int index);
+// (I)Ljava/lang/StackTraceElement;
+func getStackTraceElement(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	index := vars.GetInt(1)
+
+	stes, _ := this.Extra().([]*StackTraceElement)
+	if index < 0 || int(index) >= len(stes) {
+		panic("java.lang.IndexOutOfBoundsException")
+	}
+	loader := frame.Method().Class().Loader()
+	frame.OperandStack().PushRef(stes[index].toObject(loader))
+}
+
+// StackTraceElement => java.lang.StackTraceElement
+func (self *StackTraceElement) toObject(loader *heap.ClassLoader) *heap.Object {
+	steClass := loader.LoadClass("java/lang/StackTraceElement")
+	steObj := steClass.NewObject()
+	steObj.SetRefVar("declaringClass", "Ljava/lang/String;",
+		heap.JString(loader, self.className))
+	steObj.SetRefVar("methodName", "Ljava/lang/String;",
+		heap.JString(loader, self.methodName))
+	if self.fileName != "" {
+		steObj.SetRefVar("fileName", "Ljava/lang/String;",
+			heap.JString(loader, self.fileName))
+	}
+	steObj.SetIntVar("lineNumber", "I", int32(self.lineNumber))
+	return steObj
+}
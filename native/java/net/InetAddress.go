@@ -0,0 +1,159 @@
+package net
+
+import (
+	"jvm/instructions/base"
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	gonet "net"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+const (
+	inetAddress      = "java/net/InetAddress"
+	inet4Address     = "java/net/Inet4Address"
+	inet4AddressImpl = "java/net/Inet4AddressImpl"
+)
+
+func init() {
+	_noop := func(frame *rtda.Frame) {}
+	native.Register(inetAddress, "init", "()V", _noop)
+	native.Register(inet4Address, "init", "()V", _noop)
+	native.Register("java/net/Inet6Address", "init", "()V", _noop)
+	native.Register("java/net/InetAddressImplFactory", "isIPv6Supported", "()Z", isIPv6Supported)
+	native.Register(inet4AddressImpl, "getLocalHostName", "()Ljava/lang/String;", getLocalHostName)
+	native.Register(inet4AddressImpl, "lookupAllHostAddr", "(Ljava/lang/String;)[Ljava/net/InetAddress;", lookupAllHostAddr)
+	native.Register(inet4AddressImpl, "getHostByAddr", "([B)Ljava/lang/String;", getHostByAddr)
+	native.Register(inet4AddressImpl, "isReachable0", "([BI[BI)Z", isReachable0)
+}
+
+// 只支持IPv4 InetAddressImplFactory据此选择Inet4AddressImpl
+// static native boolean isIPv6Supported();
+// ()Z
+func isIPv6Supported(frame *rtda.Frame) {
+	frame.OperandStack().PushBoolean(false)
+}
+
+// public native String getLocalHostName() throws UnknownHostException;
+// ()Ljava/lang/String;
+func getLocalHostName(frame *rtda.Frame) {
+	name, err := os.Hostname()
+	if err != nil {
+		name = "localhost"
+	}
+	loader := frame.Method().Class().Loader()
+	frame.OperandStack().PushRef(heap.JString(loader, name))
+}
+
+// public native InetAddress[] lookupAllHostAddr(String hostname) throws UnknownHostException;
+// (Ljava/lang/String;)[Ljava/net/InetAddress;
+func lookupAllHostAddr(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	jHost := vars.GetRef(1)
+	if jHost == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", "host is null"))
+	}
+
+	host := heap.GoString(jHost)
+	var ips []gonet.IP
+	var err error
+	frame.Thread().Blocking(func() {
+		ips, err = gonet.LookupIP(host)
+	})
+	var ip4s []gonet.IP
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip4s = append(ip4s, ip4)
+		}
+	}
+	if err != nil || len(ip4s) == 0 {
+		panic(heap.NewJavaException("java/net/UnknownHostException", host))
+	}
+
+	loader := frame.Method().Class().Loader()
+	addrs := loader.LoadClass("[Ljava/net/InetAddress;").NewArray(uint(len(ip4s)))
+	for i, ip := range ip4s {
+		addrs.Refs()[i] = newInet4Address(frame.Thread(), loader, jHost, ip)
+	}
+	frame.OperandStack().PushRef(addrs)
+}
+
+// public native String getHostByAddr(byte[] addr) throws UnknownHostException;
+// ([B)Ljava/lang/String;
+func getHostByAddr(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	addr := vars.GetRef(1)
+
+	ip := gonet.IP(_bytes(addr)).String()
+	var names []string
+	var err error
+	frame.Thread().Blocking(func() {
+		names, err = gonet.LookupAddr(ip)
+	})
+	if err != nil || len(names) == 0 {
+		panic(heap.NewJavaException("java/net/UnknownHostException", ip))
+	}
+	loader := frame.Method().Class().Loader()
+	frame.OperandStack().PushRef(heap.JString(loader, strings.TrimSuffix(names[0], ".")))
+}
+
+// 没有权限发ICMP 和JDK的退路一样尝试连接echo端口 连上或者被拒绝都说明主机可达
+// private native boolean isReachable0(byte[] addr, int timeout, byte[] ifaddr, int ttl) throws IOException;
+// ([BI[BI)Z
+func isReachable0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	addr := vars.GetRef(1)
+	timeout := vars.GetInt(2)
+
+	target := gonet.JoinHostPort(gonet.IP(_bytes(addr)).String(), "7")
+	var err error
+	frame.Thread().Blocking(func() {
+		var conn gonet.Conn
+		conn, err = gonet.DialTimeout("tcp", target, time.Duration(timeout)*time.Millisecond)
+		if err == nil {
+			conn.Close()
+		}
+	})
+	frame.OperandStack().PushBoolean(err == nil || isConnectionRefused(err))
+}
+
+// Inet4Address(String hostName, byte addr[])
+func newInet4Address(thread *rtda.Thread, loader *heap.ClassLoader, jHost *heap.Object, ip gonet.IP) *heap.Object {
+	class := loader.LoadClass(inet4Address)
+	if thrown := base.InitClassAndWait(thread, class); thrown != nil {
+		panic(heap.NewThrownException(thrown))
+	}
+	obj := class.NewObject()
+	jBytes := make([]int8, gonet.IPv4len)
+	copy(castInt8sToUint8s(jBytes), ip.To4())
+	ctor := class.GetConstructor("(Ljava/lang/String;[B)V")
+	args := []rtda.Slot{rtda.RefSlot(obj), rtda.RefSlot(jHost), rtda.RefSlot(heap.NewByteArray(loader, jBytes))}
+	if _, thrown := base.InvokeAndWait(thread, ctor, args); thrown != nil {
+		panic(heap.NewThrownException(thrown))
+	}
+	return obj
+}
+
+// InetAddress.holder.address 按网络字节序存放的IPv4地址
+func inetAddressIP(addrObj *heap.Object) gonet.IP {
+	if addrObj == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", "inet address argument is null."))
+	}
+	holder := addrObj.GetRefVar("holder", "Ljava/net/InetAddress$InetAddressHolder;")
+	address := uint32(holder.GetIntVar("address", "I"))
+	return gonet.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address))
+}
+
+func _bytes(arr *heap.Object) []byte {
+	if arr == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	return castInt8sToUint8s(arr.Bytes())
+}
+
+func castInt8sToUint8s(jBytes []int8) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(jBytes))), len(jBytes))
+}
@@ -0,0 +1,371 @@
+package net
+
+import (
+	"errors"
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	gonet "net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const plainSocketImpl = "java/net/PlainSocketImpl"
+
+func init() {
+	_psi(socketCreate, "socketCreate", "(Z)V")
+	_psi(socketConnect, "socketConnect", "(Ljava/net/InetAddress;II)V")
+	_psi(socketBind, "socketBind", "(Ljava/net/InetAddress;I)V")
+	_psi(socketListen, "socketListen", "(I)V")
+	_psi(socketAccept, "socketAccept", "(Ljava/net/SocketImpl;)V")
+	_psi(socketAvailable, "socketAvailable", "()I")
+	_psi(socketClose0, "socketClose0", "(Z)V")
+	_psi(socketShutdown, "socketShutdown", "(I)V")
+	_psi(socketSetOption, "socketSetOption0", "(IZLjava/lang/Object;)V")
+	_psi(socketSetOption, "socketSetOption", "(IZLjava/lang/Object;)V")
+	_psi(socketGetOption, "socketGetOption", "(ILjava/lang/Object;)I")
+	_psi(socketSendUrgentData, "socketSendUrgentData", "(I)V")
+	_psi(func(frame *rtda.Frame) {}, "initProto", "()V")
+}
+
+func _psi(method func(frame *rtda.Frame), name, desc string) {
+	native.Register(plainSocketImpl, name, desc, method)
+}
+
+// native void socketCreate(boolean isServer) throws IOException;
+// (Z)V
+func socketCreate(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+
+	fdObj := this.GetRefVar("fd", "Ljava/io/FileDescriptor;")
+	if fdObj == nil {
+		panic(heap.NewJavaException("java/net/SocketException", "null fd object"))
+	}
+	fdObj.SetIntVar("fd", "I", _sockets.add(newSocket()))
+}
+
+// native void socketConnect(InetAddress address, int port, int timeout) throws IOException;
+// (Ljava/net/InetAddress;II)V
+func socketConnect(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	address := vars.GetRef(1)
+	port := vars.GetInt(2)
+	timeout := vars.GetInt(3)
+
+	sock := _socketOf(this)
+	raddr := &gonet.TCPAddr{IP: inetAddressIP(address), Port: int(port)}
+	dialer := gonet.Dialer{Timeout: time.Duration(timeout) * time.Millisecond}
+	if sock.listener != nil {
+		// bind过的socket 用同一个本地地址发起连接
+		dialer.LocalAddr = sock.listener.Addr()
+		sock.listener.Close()
+		sock.listener = nil
+	}
+	var conn gonet.Conn
+	var err error
+	frame.Thread().Blocking(func() {
+		conn, err = dialer.Dial("tcp4", raddr.String())
+	})
+	if err != nil {
+		if isTimeout(err) {
+			panic(heap.NewJavaException("java/net/SocketTimeoutException", "connect timed out"))
+		}
+		panic(socketException(err))
+	}
+	sock.setConn(conn.(*gonet.TCPConn))
+
+	this.SetRefVar("address", "Ljava/net/InetAddress;", address)
+	this.SetIntVar("port", "I", port)
+	this.SetIntVar("localport", "I", int32(conn.LocalAddr().(*gonet.TCPAddr).Port))
+}
+
+// Go的net包没有单独的bind 这里直接开始监听 端口为0时才能马上知道系统分配的端口
+// native void socketBind(InetAddress address, int port) throws IOException;
+// (Ljava/net/InetAddress;I)V
+func socketBind(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	address := vars.GetRef(1)
+	port := vars.GetInt(2)
+
+	sock := _socketOf(this)
+	laddr := &gonet.TCPAddr{IP: inetAddressIP(address), Port: int(port)}
+	listener, err := gonet.ListenTCP("tcp4", laddr)
+	if err != nil {
+		panic(socketException(err))
+	}
+	sock.listener = listener
+
+	this.SetRefVar("address", "Ljava/net/InetAddress;", address)
+	this.SetIntVar("localport", "I", int32(listener.Addr().(*gonet.TCPAddr).Port))
+}
+
+// 积压队列的长度由Go的运行时决定
+// native void socketListen(int count) throws IOException;
+// (I)V
+func socketListen(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+
+	sock := _socketOf(this)
+	if sock.listener == nil {
+		listener, err := gonet.ListenTCP("tcp4", nil)
+		if err != nil {
+			panic(socketException(err))
+		}
+		sock.listener = listener
+		this.SetIntVar("localport", "I", int32(listener.Addr().(*gonet.TCPAddr).Port))
+	}
+}
+
+// 超时时间是AbstractPlainSocketImpl.timeout 也就是SO_TIMEOUT
+// native void socketAccept(SocketImpl s) throws IOException;
+// (Ljava/net/SocketImpl;)V
+func socketAccept(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	s := vars.GetRef(1)
+	if s == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", "socket is null"))
+	}
+
+	sock := _socketOf(this)
+	if sock.listener == nil {
+		panic(heap.NewJavaException("java/net/SocketException", "Socket is not bound yet"))
+	}
+	timeout := this.GetIntVar("timeout", "I")
+	var conn *gonet.TCPConn
+	var err error
+	frame.Thread().Blocking(func() {
+		sock.listener.SetDeadline(deadline(timeout))
+		conn, err = sock.listener.AcceptTCP()
+	})
+	if err != nil {
+		if isTimeout(err) {
+			panic(heap.NewJavaException("java/net/SocketTimeoutException", "Accept timed out"))
+		}
+		panic(socketException(err))
+	}
+
+	accepted := newSocket()
+	accepted.setConn(conn)
+	fdObj := s.GetRefVar("fd", "Ljava/io/FileDescriptor;")
+	fdObj.SetIntVar("fd", "I", _sockets.add(accepted))
+
+	remote := conn.RemoteAddr().(*gonet.TCPAddr)
+	loader := frame.Method().Class().Loader()
+	s.SetRefVar("address", "Ljava/net/InetAddress;", newInet4Address(frame.Thread(), loader, nil, remote.IP))
+	s.SetIntVar("port", "I", int32(remote.Port))
+	s.SetIntVar("localport", "I", int32(conn.LocalAddr().(*gonet.TCPAddr).Port))
+}
+
+// Go不提供FIONREAD 返回0表示不知道 调用者会退回到阻塞读
+// native int socketAvailable() throws IOException;
+// ()I
+func socketAvailable(frame *rtda.Frame) {
+	_socketOf(frame.LocalVars().GetThis())
+	frame.OperandStack().PushInt(0)
+}
+
+// useDeferredClose为true时别的线程可能正阻塞在这个socket上
+// 关闭Go的连接会让它们的Read/Accept马上返回net.ErrClosed
+// native void socketClose0(boolean useDeferredClose) throws IOException;
+// (Z)V
+func socketClose0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+
+	fdObj := this.GetRefVar("fd", "Ljava/io/FileDescriptor;")
+	if fdObj == nil {
+		panic(heap.NewJavaException("java/net/SocketException", "socket already closed"))
+	}
+	if fd := fdObj.GetIntVar("fd", "I"); fd != -1 {
+		fdObj.SetIntVar("fd", "I", -1)
+		if sock := _sockets.remove(fd); sock != nil {
+			sock.close()
+		}
+	}
+}
+
+// AbstractPlainSocketImpl.SHUT_RD/SHUT_WR
+const (
+	SHUT_RD = 0
+	SHUT_WR = 1
+)
+
+// native void socketShutdown(int howto) throws IOException;
+// (I)V
+func socketShutdown(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	howto := vars.GetInt(1)
+
+	conn := _socketOf(this).connected()
+	var err error
+	if howto == SHUT_RD {
+		err = conn.CloseRead()
+	} else {
+		err = conn.CloseWrite()
+	}
+	if err != nil {
+		panic(socketException(err))
+	}
+}
+
+// native void socketSetOption0(int cmd, boolean on, Object value) throws SocketException;
+// (IZLjava/lang/Object;)V
+func socketSetOption(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	cmd := vars.GetInt(1)
+	on := vars.GetBoolean(2)
+	value := vars.GetRef(3)
+
+	if cmd == SO_TIMEOUT {
+		return // Java代码已经记在timeout字段里了
+	}
+	val := int32(-1)
+	if on {
+		val = 1
+		if value != nil && value.Class().Name() == "java/lang/Integer" {
+			val = value.GetIntVar("value", "I")
+		}
+	}
+	if err := _socketOf(this).setOption(cmd, val); err != nil {
+		panic(socketException(err))
+	}
+}
+
+// 布尔选项关闭时返回-1 SO_BINDADDR把本地地址放进InetAddressContainer
+// native int socketGetOption(int opt, Object iaContainerObj) throws SocketException;
+// (ILjava/lang/Object;)I
+func socketGetOption(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	opt := vars.GetInt(1)
+	iaContainerObj := vars.GetRef(2)
+
+	sock := _socketOf(this)
+	if opt == SO_BINDADDR {
+		ip := gonet.IPv4zero
+		if addr := sock.localAddr(); addr != nil {
+			ip = addr.IP
+		}
+		loader := frame.Method().Class().Loader()
+		iaContainerObj.SetRefVar("addr", "Ljava/net/InetAddress;", newInet4Address(frame.Thread(), loader, nil, ip))
+		frame.OperandStack().PushInt(0)
+		return
+	}
+	val, err := sock.getOption(opt)
+	if err != nil {
+		panic(socketException(err))
+	}
+	frame.OperandStack().PushInt(val)
+}
+
+// native void socketSendUrgentData(int data) throws IOException;
+// (I)V
+func socketSendUrgentData(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	data := vars.GetInt(1)
+
+	raw, err := _socketOf(this).connected().SyscallConn()
+	if err == nil {
+		err = sendUrgentData(raw, byte(data))
+	}
+	if err != nil {
+		panic(socketException(err))
+	}
+}
+
+// SocketImpl.fd
+func _socketOf(socketImpl *heap.Object) *socket {
+	return _socket(socketImpl.GetRefVar("fd", "Ljava/io/FileDescriptor;"))
+}
+
+// FileDescriptor.fd里放的是_sockets的句柄 不是操作系统的文件描述符
+func _socket(fdObj *heap.Object) *socket {
+	if fdObj != nil {
+		if sock := _sockets.get(fdObj.GetIntVar("fd", "I")); sock != nil {
+			return sock
+		}
+	}
+	panic(heap.NewJavaException("java/net/SocketException", "Socket closed"))
+}
+
+func deadline(timeout int32) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(timeout) * time.Millisecond)
+}
+
+func isTimeout(err error) bool {
+	var netErr gonet.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isConnectionRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// 和NET_ThrowByNameWithLastError一样按errno选择异常类
+func socketException(err error) *heap.JavaException {
+	switch {
+	case errors.Is(err, gonet.ErrClosed):
+		return heap.NewJavaException("java/net/SocketException", "Socket closed")
+	case isConnectionRefused(err):
+		return heap.NewJavaException("java/net/ConnectException", "Connection refused")
+	case errors.Is(err, syscall.EADDRINUSE):
+		return heap.NewJavaException("java/net/BindException", "Address already in use")
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return heap.NewJavaException("java/net/BindException", "Cannot assign requested address")
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return heap.NewJavaException("java/net/NoRouteToHostException", "No route to host")
+	case errors.Is(err, syscall.ECONNRESET):
+		return heap.NewJavaException("java/net/SocketException", "Connection reset")
+	case errors.Is(err, syscall.EPIPE):
+		return heap.NewJavaException("java/net/SocketException", "Broken pipe")
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return heap.NewJavaException("java/net/SocketException", errno.Error())
+	}
+	return heap.NewJavaException("java/net/SocketException", err.Error())
+}
+
+// 打开的socket 用整数句柄交给Java代码保存
+var _sockets = &socketTable{sockets: map[int32]*socket{}, next: 3}
+
+type socketTable struct {
+	sync.Mutex
+	sockets map[int32]*socket
+	next    int32
+}
+
+func (self *socketTable) add(sock *socket) int32 {
+	self.Lock()
+	defer self.Unlock()
+	fd := self.next
+	self.next++
+	self.sockets[fd] = sock
+	return fd
+}
+
+func (self *socketTable) get(fd int32) *socket {
+	self.Lock()
+	defer self.Unlock()
+	return self.sockets[fd]
+}
+
+func (self *socketTable) remove(fd int32) *socket {
+	self.Lock()
+	defer self.Unlock()
+	sock := self.sockets[fd]
+	delete(self.sockets, fd)
+	return sock
+}
@@ -0,0 +1,52 @@
+package net
+
+import (
+	"errors"
+	"io"
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"syscall"
+)
+
+const socketInputStream = "java/net/SocketInputStream"
+
+func init() {
+	native.Register(socketInputStream, "socketRead0", "(Ljava/io/FileDescriptor;[BIII)I", socketRead0)
+	native.Register(socketInputStream, "init", "()V", func(frame *rtda.Frame) {})
+}
+
+// 连接被对方关闭时返回-1 读超时抛SocketTimeoutException
+// private native int socketRead0(FileDescriptor fd, byte b[], int off, int len, int timeout) throws IOException;
+// (Ljava/io/FileDescriptor;[BIII)I
+func socketRead0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fdObj := vars.GetRef(1)
+	b := vars.GetRef(2)
+	off := vars.GetInt(3)
+	length := vars.GetInt(4)
+	timeout := vars.GetInt(5)
+
+	conn := _socket(fdObj).connected()
+	buf := _bytes(b)[off : off+length]
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		conn.SetReadDeadline(deadline(timeout))
+		n, err = conn.Read(buf)
+	})
+
+	switch {
+	case n > 0:
+		frame.OperandStack().PushInt(int32(n))
+	case err == nil, err == io.EOF:
+		frame.OperandStack().PushInt(-1)
+	case isTimeout(err):
+		panic(heap.NewJavaException("java/net/SocketTimeoutException", "Read timed out"))
+	case errors.Is(err, syscall.ECONNRESET):
+		// SocketInputStream记下连接已重置 再转换成SocketException
+		panic(heap.NewJavaException("sun/net/ConnectionResetException", "Connection reset"))
+	default:
+		panic(socketException(err))
+	}
+}
@@ -0,0 +1,34 @@
+package net
+
+import (
+	"jvm/native"
+	"jvm/rtda"
+)
+
+const socketOutputStream = "java/net/SocketOutputStream"
+
+func init() {
+	native.Register(socketOutputStream, "socketWrite0", "(Ljava/io/FileDescriptor;[BII)V", socketWrite0)
+	native.Register(socketOutputStream, "init", "()V", func(frame *rtda.Frame) {})
+}
+
+// Go的Write会一直写到全部写完或者出错
+// private native void socketWrite0(FileDescriptor fd, byte[] b, int off, int len) throws IOException;
+// (Ljava/io/FileDescriptor;[BII)V
+func socketWrite0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fdObj := vars.GetRef(1)
+	b := vars.GetRef(2)
+	off := vars.GetInt(3)
+	length := vars.GetInt(4)
+
+	conn := _socket(fdObj).connected()
+	buf := _bytes(b)[off : off+length]
+	var err error
+	frame.Thread().Blocking(func() {
+		_, err = conn.Write(buf)
+	})
+	if err != nil {
+		panic(socketException(err))
+	}
+}
@@ -0,0 +1,136 @@
+package net
+
+import (
+	gonet "net"
+	"syscall"
+)
+
+// java.net.SocketOptions
+const (
+	TCP_NODELAY  = 0x0001
+	IP_TOS       = 0x0003
+	SO_REUSEADDR = 0x0004
+	SO_KEEPALIVE = 0x0008
+	SO_BINDADDR  = 0x000F
+	SO_LINGER    = 0x0080
+	SO_SNDBUF    = 0x1001
+	SO_RCVBUF    = 0x1002
+	SO_OOBINLINE = 0x1003
+	SO_TIMEOUT   = 0x1006
+)
+
+// 一个java.net.Socket或者ServerSocket
+// connect或bind之前Go还没有创建真正的socket 选项先记在options里
+type socket struct {
+	conn     *gonet.TCPConn
+	listener *gonet.TCPListener
+	options  map[int32]int32
+}
+
+func newSocket() *socket {
+	// Go默认打开TCP_NODELAY Java默认是关闭的
+	return &socket{options: map[int32]int32{TCP_NODELAY: -1}}
+}
+
+func (self *socket) setConn(conn *gonet.TCPConn) {
+	self.conn = conn
+	for opt, val := range self.options {
+		self.applyOption(opt, val)
+	}
+}
+
+func (self *socket) connected() *gonet.TCPConn {
+	if self.conn == nil {
+		panic(socketException(syscall.ENOTCONN))
+	}
+	return self.conn
+}
+
+func (self *socket) localAddr() *gonet.TCPAddr {
+	switch {
+	case self.conn != nil:
+		return self.conn.LocalAddr().(*gonet.TCPAddr)
+	case self.listener != nil:
+		return self.listener.Addr().(*gonet.TCPAddr)
+	}
+	return nil
+}
+
+func (self *socket) rawConn() syscall.RawConn {
+	var raw syscall.RawConn
+	switch {
+	case self.conn != nil:
+		raw, _ = self.conn.SyscallConn()
+	case self.listener != nil:
+		raw, _ = self.listener.SyscallConn()
+	}
+	return raw
+}
+
+// val为-1表示关闭选项
+func (self *socket) setOption(opt, val int32) error {
+	self.options[opt] = val
+	return self.applyOption(opt, val)
+}
+
+func (self *socket) applyOption(opt, val int32) error {
+	raw := self.rawConn()
+	if raw == nil {
+		return nil
+	}
+	var err error
+	ctrlErr := raw.Control(func(fd uintptr) {
+		if opt == SO_LINGER {
+			linger := &syscall.Linger{}
+			if val >= 0 {
+				linger.Onoff, linger.Linger = 1, val
+			}
+			err = setsockoptLinger(fd, linger)
+		} else if sockopt, ok := _sockopts[opt]; ok {
+			if val == -1 {
+				val = 0
+			}
+			err = setsockoptInt(fd, sockopt[0], sockopt[1], int(val))
+		}
+	})
+	if ctrlErr != nil {
+		return ctrlErr
+	}
+	return err
+}
+
+// 有真正的socket时读系统的值 否则返回记下来的值
+func (self *socket) getOption(opt int32) (int32, error) {
+	sockopt, ok := _sockopts[opt]
+	raw := self.rawConn()
+	if !ok || raw == nil {
+		if val, ok := self.options[opt]; ok {
+			return val, nil
+		}
+		return -1, nil
+	}
+	var val int
+	var err error
+	ctrlErr := raw.Control(func(fd uintptr) {
+		val, err = getsockoptInt(fd, sockopt[0], sockopt[1])
+	})
+	if ctrlErr != nil {
+		return 0, ctrlErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if val == 0 && opt != IP_TOS && opt != SO_SNDBUF && opt != SO_RCVBUF {
+		return -1, nil
+	}
+	return int32(val), nil
+}
+
+func (self *socket) close() {
+	if self.conn != nil {
+		self.conn.Close()
+	}
+	if self.listener != nil {
+		self.listener.Close()
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package net
+
+import "syscall"
+
+// 选项对应的setsockopt参数 SO_LINGER的结构体不一样 单独处理
+var _sockopts = map[int32][2]int{
+	TCP_NODELAY:  {syscall.IPPROTO_TCP, syscall.TCP_NODELAY},
+	IP_TOS:       {syscall.IPPROTO_IP, syscall.IP_TOS},
+	SO_REUSEADDR: {syscall.SOL_SOCKET, syscall.SO_REUSEADDR},
+	SO_KEEPALIVE: {syscall.SOL_SOCKET, syscall.SO_KEEPALIVE},
+	SO_SNDBUF:    {syscall.SOL_SOCKET, syscall.SO_SNDBUF},
+	SO_RCVBUF:    {syscall.SOL_SOCKET, syscall.SO_RCVBUF},
+	SO_OOBINLINE: {syscall.SOL_SOCKET, syscall.SO_OOBINLINE},
+}
+
+func setsockoptLinger(fd uintptr, linger *syscall.Linger) error {
+	return syscall.SetsockoptLinger(int(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, linger)
+}
+
+func setsockoptInt(fd uintptr, level, opt, val int) error {
+	return syscall.SetsockoptInt(int(fd), level, opt, val)
+}
+
+func getsockoptInt(fd uintptr, level, opt int) (int, error) {
+	return syscall.GetsockoptInt(int(fd), level, opt)
+}
+
+// 发一个字节的带外数据 socket是非阻塞的 EAGAIN时等可写了再发
+func sendUrgentData(raw syscall.RawConn, data byte) error {
+	var err error
+	ctrlErr := raw.Write(func(fd uintptr) bool {
+		err = syscall.Sendto(int(fd), []byte{data}, syscall.MSG_OOB, nil)
+		return err != syscall.EAGAIN
+	})
+	if ctrlErr != nil {
+		return ctrlErr
+	}
+	return err
+}
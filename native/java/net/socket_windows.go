@@ -0,0 +1,39 @@
+//go:build windows
+
+package net
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// 选项对应的setsockopt参数 SO_LINGER的结构体不一样 单独处理
+// Go的syscall在Windows上没有SO_OOBINLINE 这个选项只记在socket.options里
+var _sockopts = map[int32][2]int{
+	TCP_NODELAY:  {syscall.IPPROTO_TCP, syscall.TCP_NODELAY},
+	IP_TOS:       {syscall.IPPROTO_IP, syscall.IP_TOS},
+	SO_REUSEADDR: {syscall.SOL_SOCKET, syscall.SO_REUSEADDR},
+	SO_KEEPALIVE: {syscall.SOL_SOCKET, syscall.SO_KEEPALIVE},
+	SO_SNDBUF:    {syscall.SOL_SOCKET, syscall.SO_SNDBUF},
+	SO_RCVBUF:    {syscall.SOL_SOCKET, syscall.SO_RCVBUF},
+}
+
+func setsockoptLinger(fd uintptr, linger *syscall.Linger) error {
+	return syscall.SetsockoptLinger(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, linger)
+}
+
+func setsockoptInt(fd uintptr, level, opt, val int) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), level, opt, val)
+}
+
+func getsockoptInt(fd uintptr, level, opt int) (int, error) {
+	var val int32
+	size := int32(unsafe.Sizeof(val))
+	err := syscall.Getsockopt(syscall.Handle(fd), int32(level), int32(opt), (*byte)(unsafe.Pointer(&val)), &size)
+	return int(val), err
+}
+
+// syscall包在Windows上没有MSG_OOB
+func sendUrgentData(raw syscall.RawConn, data byte) error {
+	return syscall.EWINDOWS
+}
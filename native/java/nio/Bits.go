@@ -0,0 +1,56 @@
+package nio
+
+import (
+	"jvm/native"
+	"jvm/native/sun/misc"
+	"jvm/rtda"
+)
+
+const bits = "java/nio/Bits"
+
+// 缓冲区的字节序和本机字节序不同时 批量读写数组要逐个元素交换字节
+func init() {
+	for _, t := range []struct {
+		name string
+		size int
+	}{{"Char", 2}, {"Short", 2}, {"Int", 4}, {"Long", 8}} {
+		native.Register(bits, "copyFrom"+t.name+"Array", "(Ljava/lang/Object;JJJ)V", copyFromArray(t.size))
+		native.Register(bits, "copyTo"+t.name+"Array", "(JLjava/lang/Object;JJ)V", copyToArray(t.size))
+	}
+}
+
+// static native void copyFromShortArray(Object src, long srcPos, long dstAddr, long length);
+// (Ljava/lang/Object;JJJ)V
+func copyFromArray(size int) func(frame *rtda.Frame) {
+	return func(frame *rtda.Frame) {
+		vars := frame.LocalVars()
+		src := vars.GetRef(0)
+		srcPos := vars.GetLong(1)
+		dstAddr := vars.GetLong(3)
+		length := vars.GetLong(5)
+
+		swapCopy(misc.Memory(nil, dstAddr, length), misc.Memory(src, srcPos, length), size)
+	}
+}
+
+// static native void copyToShortArray(long srcAddr, Object dst, long dstPos, long length);
+// (JLjava/lang/Object;JJ)V
+func copyToArray(size int) func(frame *rtda.Frame) {
+	return func(frame *rtda.Frame) {
+		vars := frame.LocalVars()
+		srcAddr := vars.GetLong(0)
+		dst := vars.GetRef(2)
+		dstPos := vars.GetLong(3)
+		length := vars.GetLong(5)
+
+		swapCopy(misc.Memory(dst, dstPos, length), misc.Memory(nil, srcAddr, length), size)
+	}
+}
+
+func swapCopy(dst, src []byte, size int) {
+	for i := 0; i+size <= len(src); i += size {
+		for j := 0; j < size; j++ {
+			dst[i+j] = src[i+size-1-j]
+		}
+	}
+}
@@ -1,37 +1,113 @@
 package misc
 
-import "jvm/native"
-import "jvm/rtda"
+import (
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
 
 func init() {
 	_signal(findSignal, "findSignal", "(Ljava/lang/String;)I")
 	_signal(handle0, "handle0", "(IJ)J")
+	_signal(raise0, "raise0", "(I)V")
 }
 
 func _signal(method func(frame *rtda.Frame), name, desc string) {
 	native.Register("sun/misc/Signal", name, desc, method)
 }
 
+// handle0的nativeH参数和返回值
+// 0和1分别是SIG_DFL和SIG_IGN 2表示Java代码里的SignalHandler 由Signal.dispatch()调用
+const (
+	SIG_DFL      = 0
+	SIG_IGN      = 1
+	JAVA_HANDLER = 2
+)
+
+// 收到的信号先放进signals 再由Signal Dispatcher线程交给Signal.dispatch()
+var _signals = struct {
+	sync.Mutex
+	handlers map[syscall.Signal]int64
+	ch       chan os.Signal
+}{
+	handlers: map[syscall.Signal]int64{},
+	ch:       make(chan os.Signal, 16),
+}
+
+// 阻塞到收到一个注册了Java处理器的信号 供Signal Dispatcher线程调用
+func WaitSignal() int32 {
+	return int32((<-_signals.ch).(syscall.Signal))
+}
+
+// 收到QUIT时WaitSignal也会返回 由Signal Dispatcher线程打印线程转储
+func NotifyQuit() {
+	signal.Notify(_signals.ch, syscall.SIGQUIT)
+}
+
+func IsQuit(number int32) bool {
+	return syscall.Signal(number) == syscall.SIGQUIT
+}
+
+// 没有这个信号时返回-1 Signal的构造函数据此抛出IllegalArgumentException
 // private static native int findSignal(String string);
 // (Ljava/lang/String;)I
 func findSignal(frame *rtda.Frame) {
 	vars := frame.LocalVars()
-	vars.GetRef(0) // name
+	name := vars.GetRef(0)
 
-	stack := frame.OperandStack()
-	stack.PushInt(0) // todo
+	number := int32(-1)
+	if sig, ok := _signalNames[heap.GoString(name)]; ok {
+		number = int32(sig)
+	}
+	frame.OperandStack().PushInt(number)
 }
 
-// private static native long handle0(int i, long l);
+// 返回原来的处理方式 -1表示信号被虚拟机占用了
+// 和JVM_RegisterSignal一样 启动时就被忽略的HUP、INT、TERM(比如nohup)不安装Java处理器
+// private static native long handle0(int sig, long nativeH);
 // (IJ)J
 func handle0(frame *rtda.Frame) {
-	// todo
 	vars := frame.LocalVars()
-	vars.GetInt(0)
-	vars.GetLong(1)
+	sig := syscall.Signal(vars.GetInt(0))
+	nativeH := vars.GetLong(1)
+
+	frame.OperandStack().PushLong(setSignalHandler(sig, nativeH))
+}
+
+func setSignalHandler(sig syscall.Signal, nativeH int64) int64 {
+	if _reservedSignals[sig] || nativeH > JAVA_HANDLER {
+		return -1 // 不支持NativeSignalHandler
+	}
 
-	stack := frame.OperandStack()
-	stack.PushLong(0)
+	_signals.Lock()
+	defer _signals.Unlock()
+	oldH := _signals.handlers[sig]
+	switch nativeH {
+	case SIG_DFL:
+		signal.Reset(sig)
+	case SIG_IGN:
+		signal.Ignore(sig)
+	case JAVA_HANDLER:
+		if (sig == syscall.SIGHUP || sig == syscall.SIGINT || sig == syscall.SIGTERM) &&
+			oldH == SIG_DFL && signal.Ignored(sig) {
+			return SIG_IGN
+		}
+		signal.Notify(_signals.ch, sig)
+	}
+	_signals.handlers[sig] = nativeH
+	return oldH
 }
 
-// private static native void raise0(int i);
+// 处理方式是JAVA_HANDLER时 信号由Signal Dispatcher线程异步处理
+// private static native void raise0(int sig);
+// (I)V
+func raise0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	sig := syscall.Signal(vars.GetInt(0))
+
+	raise(sig)
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package misc
+
+import "syscall"
+
+var _signalNames = map[string]syscall.Signal{
+	"HUP":    syscall.SIGHUP,
+	"INT":    syscall.SIGINT,
+	"QUIT":   syscall.SIGQUIT,
+	"ILL":    syscall.SIGILL,
+	"TRAP":   syscall.SIGTRAP,
+	"ABRT":   syscall.SIGABRT,
+	"IOT":    syscall.SIGIOT,
+	"BUS":    syscall.SIGBUS,
+	"FPE":    syscall.SIGFPE,
+	"KILL":   syscall.SIGKILL,
+	"USR1":   syscall.SIGUSR1,
+	"SEGV":   syscall.SIGSEGV,
+	"USR2":   syscall.SIGUSR2,
+	"PIPE":   syscall.SIGPIPE,
+	"ALRM":   syscall.SIGALRM,
+	"TERM":   syscall.SIGTERM,
+	"CHLD":   syscall.SIGCHLD,
+	"CONT":   syscall.SIGCONT,
+	"STOP":   syscall.SIGSTOP,
+	"TSTP":   syscall.SIGTSTP,
+	"TTIN":   syscall.SIGTTIN,
+	"TTOU":   syscall.SIGTTOU,
+	"URG":    syscall.SIGURG,
+	"XCPU":   syscall.SIGXCPU,
+	"XFSZ":   syscall.SIGXFSZ,
+	"VTALRM": syscall.SIGVTALRM,
+	"PROF":   syscall.SIGPROF,
+	"WINCH":  syscall.SIGWINCH,
+	"IO":     syscall.SIGIO,
+	"SYS":    syscall.SIGSYS,
+}
+
+// 不能捕获 或者被Go运行时用来实现虚拟机自身
+// 和HotSpot一样 QUIT留给虚拟机打印线程转储
+var _reservedSignals = map[syscall.Signal]bool{
+	syscall.SIGQUIT: true,
+	syscall.SIGKILL: true,
+	syscall.SIGSTOP: true,
+	syscall.SIGSEGV: true,
+	syscall.SIGBUS:  true,
+	syscall.SIGFPE:  true,
+	syscall.SIGILL:  true,
+}
+
+func raise(sig syscall.Signal) {
+	syscall.Kill(syscall.Getpid(), sig)
+}
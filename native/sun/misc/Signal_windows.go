@@ -0,0 +1,33 @@
+//go:build windows
+
+package misc
+
+import (
+	"os"
+	"syscall"
+)
+
+// Windows上Go只能把Ctrl+C、Ctrl+Break和关闭控制台转成INT和TERM
+var _signalNames = map[string]syscall.Signal{
+	"INT":  syscall.SIGINT,
+	"TERM": syscall.SIGTERM,
+}
+
+var _reservedSignals = map[syscall.Signal]bool{}
+
+// os.Process.Signal在Windows上只能发Kill
+// 所以装了Java处理器的信号直接交给Signal Dispatcher线程 SIG_DFL时和收到信号一样结束进程
+func raise(sig syscall.Signal) {
+	_signals.Lock()
+	handler := _signals.handlers[sig]
+	_signals.Unlock()
+
+	switch handler {
+	case JAVA_HANDLER:
+		_signals.ch <- sig
+	case SIG_DFL:
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(os.Kill)
+		}
+	}
+}
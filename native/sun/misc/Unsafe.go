@@ -12,9 +12,7 @@ func init() {
 	native.Register(miscUnsafe, "addressSize", "()I", addressSize)
 	native.Register(miscUnsafe, "objectFieldOffset", "(Ljava/lang/reflect/Field;)J", objectFieldOffset)
 	native.Register(miscUnsafe, "compareAndSwapObject", "(Ljava/lang/Object;JLjava/lang/Object;Ljava/lang/Object;)Z", compareAndSwapObject)
-	native.Register(miscUnsafe, "getIntVolatile", "(Ljava/lang/Object;J)I", getInt)
 	native.Register(miscUnsafe, "compareAndSwapInt", "(Ljava/lang/Object;JII)Z", compareAndSwapInt)
-	native.Register(miscUnsafe, "getObjectVolatile", "(Ljava/lang/Object;J)Ljava/lang/Object;", getObject)
 	native.Register(miscUnsafe, "compareAndSwapLong", "(Ljava/lang/Object;JJJ)Z", compareAndSwapLong)
 
 }
@@ -29,8 +27,17 @@ func arrayBaseOffset(frame *rtda.Frame) {
 // public native int arrayIndexScale(Class<?> type);
 // (Ljava/lang/Class;)I
 func arrayIndexScale(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	arrClass := vars.GetRef(1).Extra().(*heap.Class)
+
 	stack := frame.OperandStack()
-	stack.PushInt(1) // todo
+	stack.PushInt(int32(arrClass.ElementSize()))
+}
+
+// 数组的offset是arrayBaseOffset + index*arrayIndexScale 换算回下标
+// copyMemory等按字节访问数组的方法也是用同样的offset
+func arrayIndex(arr *heap.Object, offset int64) int64 {
+	return offset / arr.Class().ElementSize()
 }
 
 // public native int addressSize();
@@ -60,113 +67,68 @@ func objectFieldOffset(frame *rtda.Frame) {
 func compareAndSwapObject(frame *rtda.Frame) {
 	vars := frame.LocalVars()
 	obj := vars.GetRef(1)
-	fields := obj.Data()
 	offset := vars.GetLong(2)
 	expected := vars.GetRef(4)
 	newVal := vars.GetRef(5)
 
-	// todo
-	if anys, ok := fields.(heap.Slots); ok {
-		// object
-		swapped := _casObj(obj, anys, offset, expected, newVal)
-		frame.OperandStack().PushBoolean(swapped)
-	} else if objs, ok := fields.([]*heap.Object); ok {
+	if obj.Class().IsArray() {
 		// ref[]
-		swapped := _casArr(objs, offset, expected, newVal)
+		swapped := _casArr(obj.Refs(), arrayIndex(obj, offset), expected, newVal)
 		frame.OperandStack().PushBoolean(swapped)
 	} else {
-		// todo
-		panic("todo: compareAndSwapObject!")
+		// object
+		swapped := _casObj(obj, offset, expected, newVal)
+		frame.OperandStack().PushBoolean(swapped)
 	}
 }
-func _casObj(obj *heap.Object, fields heap.Slots, offset int64, expected, newVal *heap.Object) bool {
-	current := fields.GetRef(uint(offset))
+func _casObj(obj *heap.Object, offset int64, expected, newVal *heap.Object) bool {
+	current := obj.GetRefField(uint(offset))
 	if current == expected {
-		fields.SetRef(uint(offset), newVal)
+		obj.SetRefField(uint(offset), newVal)
 		return true
 	} else {
 		return false
 	}
 }
-func _casArr(objs []*heap.Object, offset int64, expected, newVal *heap.Object) bool {
-	current := objs[offset]
+func _casArr(objs []*heap.Object, index int64, expected, newVal *heap.Object) bool {
+	current := objs[index]
 	if current == expected {
-		objs[offset] = newVal
+		objs[index] = newVal
 		return true
 	} else {
 		return false
 	}
 }
 
-// public native boolean getInt(Object o, long offset);
-// (Ljava/lang/Object;J)I
-func getInt(frame *rtda.Frame) {
-	vars := frame.LocalVars()
-	fields := vars.GetRef(1).Data()
-	offset := vars.GetLong(2)
-
-	stack := frame.OperandStack()
-	if slots, ok := fields.(heap.Slots); ok {
-		// object
-		stack.PushInt(slots.GetInt(uint(offset)))
-	} else if shorts, ok := fields.([]int32); ok {
-		// int[]
-		stack.PushInt(int32(shorts[offset]))
-	} else {
-		panic("getInt!")
-	}
-}
-
 // public final native boolean compareAndSwapInt(Object o, long offset, int expected, int x);
 // (Ljava/lang/Object;JII)Z
 func compareAndSwapInt(frame *rtda.Frame) {
 	vars := frame.LocalVars()
-	fields := vars.GetRef(1).Data()
+	obj := vars.GetRef(1)
 	offset := vars.GetLong(2)
 	expected := vars.GetInt(4)
 	newVal := vars.GetInt(5)
 
-	if slots, ok := fields.(heap.Slots); ok {
-		// object
-		oldVal := slots.GetInt(uint(offset))
+	if obj.Class().IsArray() {
+		// int[]
+		ints := obj.Ints()
+		index := arrayIndex(obj, offset)
+		oldVal := ints[index]
 		if oldVal == expected {
-			slots.SetInt(uint(offset), newVal)
+			ints[index] = newVal
 			frame.OperandStack().PushBoolean(true)
 		} else {
 			frame.OperandStack().PushBoolean(false)
 		}
-	} else if ints, ok := fields.([]int32); ok {
-		// int[]
-		oldVal := ints[offset]
+	} else {
+		// object
+		oldVal := obj.GetIntField(uint(offset))
 		if oldVal == expected {
-			ints[offset] = newVal
+			obj.SetIntField(uint(offset), newVal)
 			frame.OperandStack().PushBoolean(true)
 		} else {
 			frame.OperandStack().PushBoolean(false)
 		}
-	} else {
-		// todo
-		panic("todo: compareAndSwapInt!")
-	}
-}
-
-// public native Object getObject(Object o, long offset);
-// (Ljava/lang/Object;J)Ljava/lang/Object;
-func getObject(frame *rtda.Frame) {
-	vars := frame.LocalVars()
-	fields := vars.GetRef(1).Data()
-	offset := vars.GetLong(2)
-
-	if anys, ok := fields.(heap.Slots); ok {
-		// object
-		x := anys.GetRef(uint(offset))
-		frame.OperandStack().PushRef(x)
-	} else if objs, ok := fields.([]*heap.Object); ok {
-		// ref[]
-		x := objs[offset]
-		frame.OperandStack().PushRef(x)
-	} else {
-		panic("getObject!")
 	}
 }
 
@@ -174,31 +136,30 @@ func getObject(frame *rtda.Frame) {
 // (Ljava/lang/Object;JJJ)Z
 func compareAndSwapLong(frame *rtda.Frame) {
 	vars := frame.LocalVars()
-	fields := vars.GetRef(1).Data()
+	obj := vars.GetRef(1)
 	offset := vars.GetLong(2)
 	expected := vars.GetLong(4)
 	newVal := vars.GetLong(6)
 
-	if slots, ok := fields.(heap.Slots); ok {
-		// object
-		oldVal := slots.GetLong(uint(offset))
+	if obj.Class().IsArray() {
+		// long[]
+		longs := obj.Longs()
+		index := arrayIndex(obj, offset)
+		oldVal := longs[index]
 		if oldVal == expected {
-			slots.SetLong(uint(offset), newVal)
+			longs[index] = newVal
 			frame.OperandStack().PushBoolean(true)
 		} else {
 			frame.OperandStack().PushBoolean(false)
 		}
-	} else if longs, ok := fields.([]int64); ok {
-		// long[]
-		oldVal := longs[offset]
+	} else {
+		// object
+		oldVal := obj.GetLongField(uint(offset))
 		if oldVal == expected {
-			longs[offset] = newVal
+			obj.SetLongField(uint(offset), newVal)
 			frame.OperandStack().PushBoolean(true)
 		} else {
 			frame.OperandStack().PushBoolean(false)
 		}
-	} else {
-		// todo
-		panic("todo: compareAndSwapLong!")
 	}
 }
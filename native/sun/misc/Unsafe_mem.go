@@ -4,28 +4,32 @@ import "math"
 import "encoding/binary"
 import "jvm/native"
 import "jvm/rtda"
+import "jvm/rtda/heap"
 
 func init() {
 	_unsafe(allocateMemory, "allocateMemory", "(J)J")
 	_unsafe(reallocateMemory, "reallocateMemory", "(JJ)J")
 	_unsafe(freeMemory, "freeMemory", "(J)V")
 	_unsafe(addressSize, "addressSize", "()I")
-	//	_unsafe(putAddress, "putAddress", "(JJ)V")
-	//	_unsafe(getAddress, "getAddress", "(J)J")
-	//	_unsafe(mem_putByte, "putByte", "(JB)V")
+	_unsafe(pageSize, "pageSize", "()I")
+	_unsafe(setMemory, "setMemory", "(Ljava/lang/Object;JJB)V")
+	_unsafe(copyMemory, "copyMemory", "(Ljava/lang/Object;JLjava/lang/Object;JJ)V")
+	_unsafe(putAddress, "putAddress", "(JJ)V")
+	_unsafe(getAddress, "getAddress", "(J)J")
+	_unsafe(mem_putByte, "putByte", "(JB)V")
 	_unsafe(mem_getByte, "getByte", "(J)B")
-	//	_unsafe(mem_putShort, "putShort", "(JS)V")
-	//	_unsafe(mem_getShort, "getShort", "(J)S")
-	//	_unsafe(mem_putChar, "putChar", "(JC)V")
-	//	_unsafe(mem_getChar, "getChar", "(J)C")
-	//	_unsafe(mem_putInt, "putInt", "(JI)V")
-	//	_unsafe(mem_getInt, "getInt", "(J)I")
+	_unsafe(mem_putShort, "putShort", "(JS)V")
+	_unsafe(mem_getShort, "getShort", "(J)S")
+	_unsafe(mem_putChar, "putChar", "(JC)V")
+	_unsafe(mem_getChar, "getChar", "(J)C")
+	_unsafe(mem_putInt, "putInt", "(JI)V")
+	_unsafe(mem_getInt, "getInt", "(J)I")
 	_unsafe(mem_putLong, "putLong", "(JJ)V")
-	//	_unsafe(mem_getLong, "getLong", "(J)J")
-	//	_unsafe(mem_putFloat, "putFloat", "(JF)V")
-	//	_unsafe(mem_getFloat, "getFloat", "(J)F")
-	//	_unsafe(mem_putDouble, "putDouble", "(JD)V")
-	//	_unsafe(mem_getDouble, "getDouble", "(J)D")
+	_unsafe(mem_getLong, "getLong", "(J)J")
+	_unsafe(mem_putFloat, "putFloat", "(JF)V")
+	_unsafe(mem_getFloat, "getFloat", "(J)F")
+	_unsafe(mem_putDouble, "putDouble", "(JD)V")
+	_unsafe(mem_getDouble, "getDouble", "(J)D")
 }
 
 func _unsafe(method func(frame *rtda.Frame), name, desc string) {
@@ -66,145 +70,199 @@ func freeMemory(frame *rtda.Frame) {
 	free(address)
 }
 
-//// public native void putAddress(long address, long x);
-//// (JJ)V
-//func putAddress(frame *rtda.Frame) {
-//	mem_putLong(frame)
-//}
-//
-//// public native long getAddress(long address);
-//// (J)J
-//func getAddress(frame *rtda.Frame) {
-//	mem_getLong(frame)
-//}
-//
-//// public native void putByte(long address, byte x);
-//// (JB)V
-//func mem_putByte(frame *rtda.Frame) {
-//	mem, value := _put(frame)
-//	PutInt8(mem, int8(value.(int32)))
-//}
-//
+// public native int pageSize();
+// ()I
+func pageSize(frame *rtda.Frame) {
+	stack := frame.OperandStack()
+	stack.PushInt(4096)
+}
+
+// public native void setMemory(Object o, long offset, long bytes, byte value);
+// (Ljava/lang/Object;JJB)V
+func setMemory(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	// vars.GetRef(0) // this
+	base := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	bytes := vars.GetLong(4)
+	value := byte(vars.GetInt(6))
+
+	mem := memoryOf(base, offset, bytes)
+	for i := range mem {
+		mem[i] = value
+	}
+}
+
+// public native void copyMemory(Object srcBase, long srcOffset, Object destBase, long destOffset, long bytes);
+// (Ljava/lang/Object;JLjava/lang/Object;JJ)V
+func copyMemory(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	// vars.GetRef(0) // this
+	srcBase := vars.GetRef(1)
+	srcOffset := vars.GetLong(2)
+	destBase := vars.GetRef(4)
+	destOffset := vars.GetLong(5)
+	bytes := vars.GetLong(7)
+
+	src := memoryOf(srcBase, srcOffset, bytes)
+	dest := memoryOf(destBase, destOffset, bytes)
+	copy(dest, src) // copy可以处理重叠的情况
+}
+
+// base为null时offset是allocateMemory得到的地址
+// 否则base是基本类型数组 offset是相对于arrayBaseOffset的字节偏移
+func memoryOf(base *heap.Object, offset, bytes int64) []byte {
+	if base == nil {
+		return memoryAt(offset, bytes)
+	}
+	mem := arrayMemory(base)
+	if offset < 0 || bytes < 0 || offset+bytes > int64(len(mem)) {
+		panic(heap.NewJavaException("java/lang/ArrayIndexOutOfBoundsException", ""))
+	}
+	return mem[offset : offset+bytes]
+}
+
+// 把基本类型数组按本机字节序看作一段内存 和堆外内存的读写方式一致
+func arrayMemory(arr *heap.Object) []byte {
+	mem, ok := arr.ArrayMemory()
+	if !ok {
+		panic(heap.NewJavaException("java/lang/IllegalArgumentException", "not a primitive array"))
+	}
+	return mem
+}
+
+// public native void putAddress(long address, long x);
+// (JJ)V
+func putAddress(frame *rtda.Frame) {
+	mem_putLong(frame)
+}
+
+// public native long getAddress(long address);
+// (J)J
+func getAddress(frame *rtda.Frame) {
+	mem_getLong(frame)
+}
+
+// public native void putByte(long address, byte x);
+// (JB)V
+func mem_putByte(frame *rtda.Frame) {
+	mem, vars := _put(frame, 1)
+	PutInt8(mem, int8(vars.GetInt(3)))
+}
+
 // public native byte getByte(long address);
 // (J)B
 func mem_getByte(frame *rtda.Frame) {
-	stack, mem := _get(frame)
+	stack, mem := _get(frame, 1)
 	stack.PushInt(int32(Int8(mem)))
 }
 
-//
-//// public native void putShort(long address, short x);
-//// (JS)V
-//func mem_putShort(frame *rtda.Frame) {
-//	mem, value := _put(frame)
-//	PutInt16(mem, int16(value.(int32)))
-//}
-//
-//// public native short getShort(long address);
-//// (J)S
-//func mem_getShort(frame *rtda.Frame) {
-//	stack, mem := _get(frame)
-//	stack.PushInt(int32(Int16(mem)))
-//}
-//
-//// public native void putChar(long address, char x);
-//// (JC)V
-//func mem_putChar(frame *rtda.Frame) {
-//	mem, value := _put(frame)
-//	PutUint16(mem, uint16(value.(int32)))
-//}
-//
-//// public native char getChar(long address);
-//// (J)C
-//func mem_getChar(frame *rtda.Frame) {
-//	stack, mem := _get(frame)
-//	stack.PushInt(int32(Uint16(mem)))
-//}
-//
-//// public native void putInt(long address, int x);
-//// (JI)V
-//func mem_putInt(frame *rtda.Frame) {
-//	mem, value := _put(frame)
-//	PutInt32(mem, value.(int32))
-//}
-//
-//// public native int getInt(long address);
-//// (J)I
-//func mem_getInt(frame *rtda.Frame) {
-//	stack, mem := _get(frame)
-//	stack.PushInt(Int32(mem))
-//}
-//
+// public native void putShort(long address, short x);
+// (JS)V
+func mem_putShort(frame *rtda.Frame) {
+	mem, vars := _put(frame, 2)
+	PutInt16(mem, int16(vars.GetInt(3)))
+}
+
+// public native short getShort(long address);
+// (J)S
+func mem_getShort(frame *rtda.Frame) {
+	stack, mem := _get(frame, 2)
+	stack.PushInt(int32(Int16(mem)))
+}
+
+// public native void putChar(long address, char x);
+// (JC)V
+func mem_putChar(frame *rtda.Frame) {
+	mem, vars := _put(frame, 2)
+	PutUint16(mem, uint16(vars.GetInt(3)))
+}
+
+// public native char getChar(long address);
+// (J)C
+func mem_getChar(frame *rtda.Frame) {
+	stack, mem := _get(frame, 2)
+	stack.PushInt(int32(Uint16(mem)))
+}
+
+// public native void putInt(long address, int x);
+// (JI)V
+func mem_putInt(frame *rtda.Frame) {
+	mem, vars := _put(frame, 4)
+	PutInt32(mem, vars.GetInt(3))
+}
+
+// public native int getInt(long address);
+// (J)I
+func mem_getInt(frame *rtda.Frame) {
+	stack, mem := _get(frame, 4)
+	stack.PushInt(Int32(mem))
+}
+
 // public native void putLong(long address, long x);
 // (JJ)V
 func mem_putLong(frame *rtda.Frame) {
+	mem, vars := _put(frame, 8)
+	PutInt64(mem, vars.GetLong(3))
+}
+
+// public native long getLong(long address);
+// (J)J
+func mem_getLong(frame *rtda.Frame) {
+	stack, mem := _get(frame, 8)
+	stack.PushLong(Int64(mem))
+}
+
+// public native void putFloat(long address, float x);
+// (JF)V
+func mem_putFloat(frame *rtda.Frame) {
+	mem, vars := _put(frame, 4)
+	PutFloat32(mem, vars.GetFloat(3))
+}
+
+// public native float getFloat(long address);
+// (J)F
+func mem_getFloat(frame *rtda.Frame) {
+	stack, mem := _get(frame, 4)
+	stack.PushFloat(Float32(mem))
+}
+
+// public native void putDouble(long address, double x);
+// (JD)V
+func mem_putDouble(frame *rtda.Frame) {
+	mem, vars := _put(frame, 8)
+	PutFloat64(mem, vars.GetDouble(3))
+}
+
+// public native double getDouble(long address);
+// (J)D
+func mem_getDouble(frame *rtda.Frame) {
+	stack, mem := _get(frame, 8)
+	stack.PushDouble(Float64(mem))
+}
+
+// 值从局部变量3开始 由调用者按类型读取
+func _put(frame *rtda.Frame, size int64) ([]byte, rtda.LocalVars) {
 	vars := frame.LocalVars()
 	// vars.GetRef(0) // this
 	address := vars.GetLong(1)
-	value := vars.GetLong(3)
-
-	mem := memoryAt(address)
-
-	PutInt64(mem, value)
-}
-
-//
-//// public native long getLong(long address);
-//// (J)J
-//func mem_getLong(frame *rtda.Frame) {
-//	stack, mem := _get(frame)
-//	stack.PushLong(Int64(mem))
-//}
-//
-//// public native void putFloat(long address, float x);
-//// (JJ)V
-//func mem_putFloat(frame *rtda.Frame) {
-//	mem, value := _put(frame)
-//	PutFloat32(mem, value.(float32))
-//}
-//
-//// public native float getFloat(long address);
-//// (J)J
-//func mem_getFloat(frame *rtda.Frame) {
-//	stack, mem := _get(frame)
-//	stack.PushFloat(Float32(mem))
-//}
-//
-//// public native void putDouble(long address, double x);
-//// (JJ)V
-//func mem_putDouble(frame *rtda.Frame) {
-//	mem, value := _put(frame)
-//	PutFloat64(mem, value.(float64))
-//}
-//
-//// public native double getDouble(long address);
-//// (J)J
-//func mem_getDouble(frame *rtda.Frame) {
-//	stack, mem := _get(frame)
-//	stack.PushDouble(Float64(mem))
-//}
-//
-//func _put(frame *rtda.Frame) ([]byte, interface{}) {
-//	vars := frame.LocalVars()
-//	// vars.GetRef(0) // this
-//	address := vars.GetLong(1)
-//	value := vars.Get(3)
-//
-//	mem := memoryAt(address)
-//	return mem, value
-//}
-//
-func _get(frame *rtda.Frame) (*rtda.OperandStack, []byte) {
+
+	mem := memoryAt(address, size)
+	return mem, vars
+}
+
+func _get(frame *rtda.Frame, size int64) (*rtda.OperandStack, []byte) {
 	vars := frame.LocalVars()
 	// vars.GetRef(0) // this
 	address := vars.GetLong(1)
 
 	stack := frame.OperandStack()
-	mem := memoryAt(address)
+	mem := memoryAt(address, size)
 	return stack, mem
 }
 
-var _bigEndian = binary.BigEndian
+// 和真正的Unsafe一样使用本机字节序 Bits.byteOrder()就是这样探测出来的
+var _nativeEndian = binary.NativeEndian
 
 func PutInt8(s []byte, val int8) {
 	s[0] = uint8(val)
@@ -214,43 +272,43 @@ func Int8(s []byte) int8 {
 }
 
 func PutUint16(s []byte, val uint16) {
-	_bigEndian.PutUint16(s, val)
+	_nativeEndian.PutUint16(s, val)
 }
 func Uint16(s []byte) uint16 {
-	return _bigEndian.Uint16(s)
+	return _nativeEndian.Uint16(s)
 }
 
 func PutInt16(s []byte, val int16) {
-	_bigEndian.PutUint16(s, uint16(val))
+	_nativeEndian.PutUint16(s, uint16(val))
 }
 func Int16(s []byte) int16 {
-	return int16(_bigEndian.Uint16(s))
+	return int16(_nativeEndian.Uint16(s))
 }
 
 func PutInt32(s []byte, val int32) {
-	_bigEndian.PutUint32(s, uint32(val))
+	_nativeEndian.PutUint32(s, uint32(val))
 }
 func Int32(s []byte) int32 {
-	return int32(_bigEndian.Uint32(s))
+	return int32(_nativeEndian.Uint32(s))
 }
 
 func PutInt64(s []byte, val int64) {
-	_bigEndian.PutUint64(s, uint64(val))
+	_nativeEndian.PutUint64(s, uint64(val))
 }
 func Int64(s []byte) int64 {
-	return int64(_bigEndian.Uint64(s))
+	return int64(_nativeEndian.Uint64(s))
 }
 
 func PutFloat32(s []byte, val float32) {
-	_bigEndian.PutUint32(s, math.Float32bits(val))
+	_nativeEndian.PutUint32(s, math.Float32bits(val))
 }
 func Float32(s []byte) float32 {
-	return math.Float32frombits(_bigEndian.Uint32(s))
+	return math.Float32frombits(_nativeEndian.Uint32(s))
 }
 
 func PutFloat64(s []byte, val float64) {
-	_bigEndian.PutUint64(s, math.Float64bits(val))
+	_nativeEndian.PutUint64(s, math.Float64bits(val))
 }
 func Float64(s []byte) float64 {
-	return math.Float64frombits(_bigEndian.Uint64(s))
+	return math.Float64frombits(_nativeEndian.Uint64(s))
 }
@@ -0,0 +1,326 @@
+package misc
+
+import "jvm/rtda"
+import "jvm/rtda/heap"
+
+// 按(Object o, long offset)读写 UnsafeFieldAccessorImpl、AtomicXxx等都用这一组方法
+func init() {
+	_unsafeObj(obj_getBoolean, "getBoolean", "(Ljava/lang/Object;J)Z")
+	_unsafeObj(obj_putBoolean, "putBoolean", "(Ljava/lang/Object;JZ)V")
+	_unsafeObj(obj_getByte, "getByte", "(Ljava/lang/Object;J)B")
+	_unsafeObj(obj_putByte, "putByte", "(Ljava/lang/Object;JB)V")
+	_unsafeObj(obj_getShort, "getShort", "(Ljava/lang/Object;J)S")
+	_unsafeObj(obj_putShort, "putShort", "(Ljava/lang/Object;JS)V")
+	_unsafeObj(obj_getChar, "getChar", "(Ljava/lang/Object;J)C")
+	_unsafeObj(obj_putChar, "putChar", "(Ljava/lang/Object;JC)V")
+	_unsafeObj(obj_getInt, "getInt", "(Ljava/lang/Object;J)I")
+	_unsafeObj(obj_putInt, "putInt", "(Ljava/lang/Object;JI)V")
+	_unsafeObj(obj_getLong, "getLong", "(Ljava/lang/Object;J)J")
+	_unsafeObj(obj_putLong, "putLong", "(Ljava/lang/Object;JJ)V")
+	_unsafeObj(obj_getFloat, "getFloat", "(Ljava/lang/Object;J)F")
+	_unsafeObj(obj_putFloat, "putFloat", "(Ljava/lang/Object;JF)V")
+	_unsafeObj(obj_getDouble, "getDouble", "(Ljava/lang/Object;J)D")
+	_unsafeObj(obj_putDouble, "putDouble", "(Ljava/lang/Object;JD)V")
+	_unsafeObj(obj_getObject, "getObject", "(Ljava/lang/Object;J)Ljava/lang/Object;")
+	_unsafeObj(obj_putObject, "putObject", "(Ljava/lang/Object;JLjava/lang/Object;)V")
+	_unsafe(obj_putInt, "putOrderedInt", "(Ljava/lang/Object;JI)V")
+	_unsafe(obj_putLong, "putOrderedLong", "(Ljava/lang/Object;JJ)V")
+	_unsafe(obj_putObject, "putOrderedObject", "(Ljava/lang/Object;JLjava/lang/Object;)V")
+}
+
+// 同一时刻只有一个线程执行Java代码 volatile读写和普通读写一样
+func _unsafeObj(method func(frame *rtda.Frame), name, desc string) {
+	_unsafe(method, name, desc)
+	_unsafe(method, name+"Volatile", desc)
+}
+
+// o是普通对象时offset是objectFieldOffset得到的字段偏移 按字段读写
+// boolean、byte、short和char字段和int字段一样占4个字节
+// 否则o是null或基本类型数组 和copyMemory一样按内存读写
+func isField(o *heap.Object) bool {
+	return o != nil && !o.Class().IsArray()
+}
+
+// public native boolean getBoolean(Object o, long offset);
+// (Ljava/lang/Object;J)Z
+func obj_getBoolean(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushBoolean(o.GetIntField(uint(offset)) != 0)
+	} else {
+		stack.PushBoolean(Int8(memoryOf(o, offset, 1)) != 0)
+	}
+}
+
+// public native void putBoolean(Object o, long offset, boolean x);
+// (Ljava/lang/Object;JZ)V
+func obj_putBoolean(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := int32(0)
+	if vars.GetBoolean(4) {
+		x = 1
+	}
+
+	if isField(o) {
+		o.SetIntField(uint(offset), x)
+	} else {
+		PutInt8(memoryOf(o, offset, 1), int8(x))
+	}
+}
+
+// public native byte getByte(Object o, long offset);
+// (Ljava/lang/Object;J)B
+func obj_getByte(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushInt(int32(int8(o.GetIntField(uint(offset)))))
+	} else {
+		stack.PushInt(int32(Int8(memoryOf(o, offset, 1))))
+	}
+}
+
+// public native void putByte(Object o, long offset, byte x);
+// (Ljava/lang/Object;JB)V
+func obj_putByte(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := int8(vars.GetInt(4))
+
+	if isField(o) {
+		o.SetIntField(uint(offset), int32(x))
+	} else {
+		PutInt8(memoryOf(o, offset, 1), x)
+	}
+}
+
+// public native short getShort(Object o, long offset);
+// (Ljava/lang/Object;J)S
+func obj_getShort(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushInt(int32(int16(o.GetIntField(uint(offset)))))
+	} else {
+		stack.PushInt(int32(Int16(memoryOf(o, offset, 2))))
+	}
+}
+
+// public native void putShort(Object o, long offset, short x);
+// (Ljava/lang/Object;JS)V
+func obj_putShort(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := int16(vars.GetInt(4))
+
+	if isField(o) {
+		o.SetIntField(uint(offset), int32(x))
+	} else {
+		PutInt16(memoryOf(o, offset, 2), x)
+	}
+}
+
+// public native char getChar(Object o, long offset);
+// (Ljava/lang/Object;J)C
+func obj_getChar(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushInt(int32(uint16(o.GetIntField(uint(offset)))))
+	} else {
+		stack.PushInt(int32(Uint16(memoryOf(o, offset, 2))))
+	}
+}
+
+// public native void putChar(Object o, long offset, char x);
+// (Ljava/lang/Object;JC)V
+func obj_putChar(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := uint16(vars.GetInt(4))
+
+	if isField(o) {
+		o.SetIntField(uint(offset), int32(x))
+	} else {
+		PutUint16(memoryOf(o, offset, 2), x)
+	}
+}
+
+// public native int getInt(Object o, long offset);
+// (Ljava/lang/Object;J)I
+func obj_getInt(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushInt(o.GetIntField(uint(offset)))
+	} else {
+		stack.PushInt(Int32(memoryOf(o, offset, 4)))
+	}
+}
+
+// public native void putInt(Object o, long offset, int x);
+// (Ljava/lang/Object;JI)V
+func obj_putInt(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := vars.GetInt(4)
+
+	if isField(o) {
+		o.SetIntField(uint(offset), x)
+	} else {
+		PutInt32(memoryOf(o, offset, 4), x)
+	}
+}
+
+// public native long getLong(Object o, long offset);
+// (Ljava/lang/Object;J)J
+func obj_getLong(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushLong(o.GetLongField(uint(offset)))
+	} else {
+		stack.PushLong(Int64(memoryOf(o, offset, 8)))
+	}
+}
+
+// public native void putLong(Object o, long offset, long x);
+// (Ljava/lang/Object;JJ)V
+func obj_putLong(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := vars.GetLong(4)
+
+	if isField(o) {
+		o.SetLongField(uint(offset), x)
+	} else {
+		PutInt64(memoryOf(o, offset, 8), x)
+	}
+}
+
+// public native float getFloat(Object o, long offset);
+// (Ljava/lang/Object;J)F
+func obj_getFloat(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushFloat(o.GetFloatField(uint(offset)))
+	} else {
+		stack.PushFloat(Float32(memoryOf(o, offset, 4)))
+	}
+}
+
+// public native void putFloat(Object o, long offset, float x);
+// (Ljava/lang/Object;JF)V
+func obj_putFloat(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := vars.GetFloat(4)
+
+	if isField(o) {
+		o.SetFloatField(uint(offset), x)
+	} else {
+		PutFloat32(memoryOf(o, offset, 4), x)
+	}
+}
+
+// public native double getDouble(Object o, long offset);
+// (Ljava/lang/Object;J)D
+func obj_getDouble(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushDouble(o.GetDoubleField(uint(offset)))
+	} else {
+		stack.PushDouble(Float64(memoryOf(o, offset, 8)))
+	}
+}
+
+// public native void putDouble(Object o, long offset, double x);
+// (Ljava/lang/Object;JD)V
+func obj_putDouble(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := vars.GetDouble(4)
+
+	if isField(o) {
+		o.SetDoubleField(uint(offset), x)
+	} else {
+		PutFloat64(memoryOf(o, offset, 8), x)
+	}
+}
+
+// 引用只能存在普通对象或引用数组里
+// public native Object getObject(Object o, long offset);
+// (Ljava/lang/Object;J)Ljava/lang/Object;
+func obj_getObject(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+
+	stack := frame.OperandStack()
+	if isField(o) {
+		stack.PushRef(o.GetRefField(uint(offset)))
+	} else {
+		stack.PushRef(refsOf(o)[arrayIndex(o, offset)])
+	}
+}
+
+// public native void putObject(Object o, long offset, Object x);
+// (Ljava/lang/Object;JLjava/lang/Object;)V
+func obj_putObject(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	o := vars.GetRef(1)
+	offset := vars.GetLong(2)
+	x := vars.GetRef(4)
+
+	if isField(o) {
+		o.SetRefField(uint(offset), x)
+	} else {
+		refsOf(o)[arrayIndex(o, offset)] = x
+	}
+}
+
+func refsOf(arr *heap.Object) []*heap.Object {
+	if arr == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	if _, ok := arr.ArrayMemory(); ok {
+		panic(heap.NewJavaException("java/lang/IllegalArgumentException", "not a reference array"))
+	}
+	return arr.Refs()
+}
@@ -16,5 +16,7 @@ func initialize(frame *rtda.Frame) {
 	classLoader := frame.Method().Class().Loader()
 	jlSysClass := classLoader.LoadClass("java/lang/System")
 	initSysClass := jlSysClass.GetStaticMethod("initializeSystemClass", "()V")
-	base.InvokeMethod(frame, initSysClass)
+	if _, ex := base.InvokeAndWait(frame.Thread(), initSysClass, nil); ex != nil {
+		base.Rethrow(frame.Thread(), ex)
+	}
 }
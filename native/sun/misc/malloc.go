@@ -1,51 +1,163 @@
 package misc
 
-var _allocated = map[int64][]byte{}
-var _nextAddress = int64(64) // not zero!
+import (
+	"jvm/rtda/heap"
+	"sort"
+	"sync"
+)
 
-func allocate(size int64) (address int64) {
-	mem := make([]byte, size)
-	address = _nextAddress
-	_allocated[address] = mem
-	_nextAddress += size
-	return
+// Unsafe.allocateMemory分配的堆外内存
+// 地址只增不减 所以按起始地址排好序的块表只需要在末尾追加 查找地址时二分
+// DirectByteBuffer的Cleaner在Reference Handler线程里释放内存 所以要加锁
+type memoryBlock struct {
+	address int64
+	mem     []byte
 }
 
-func reallocate(address, size int64) int64 {
+var _malloc = struct {
+	sync.Mutex
+	blocks      []memoryBlock
+	nextAddress int64
+}{nextAddress: 64} // not zero!
+
+const memoryAlignment = 8 // 每块的起始地址按8字节对齐
+
+func allocate(size int64) int64 {
+	if size < 0 {
+		panic(heap.NewJavaException("java/lang/IllegalArgumentException", ""))
+	}
 	if size == 0 {
 		return 0
-	} else if address == 0 {
+	}
+	_malloc.Lock()
+	defer _malloc.Unlock()
+	return allocateLocked(size)
+}
+
+func allocateLocked(size int64) int64 {
+	return addBlockLocked(make([]byte, size))
+}
+
+func addBlockLocked(mem []byte) int64 {
+	address := _malloc.nextAddress
+	_malloc.blocks = append(_malloc.blocks, memoryBlock{address, mem})
+	_malloc.nextAddress += (int64(len(mem)) + memoryAlignment - 1) &^ (memoryAlignment - 1)
+	return address
+}
+
+func reallocate(address, size int64) int64 {
+	if size < 0 {
+		panic(heap.NewJavaException("java/lang/IllegalArgumentException", ""))
+	}
+	if address == 0 {
 		return allocate(size)
-	} else {
-		mem := memoryAt(address)
-		if len(mem) >= int(size) {
-			return address
-		} else {
-			delete(_allocated, address)
-			newAddress := allocate(size)
-			newMem := memoryAt(newAddress)
-			copy(newMem, mem)
-			return newAddress
-		}
 	}
+	if size == 0 {
+		free(address)
+		return 0
+	}
+
+	_malloc.Lock()
+	defer _malloc.Unlock()
+	i := blockStartingAt(address)
+	mem := _malloc.blocks[i].mem
+	if int64(len(mem)) == size {
+		return address
+	}
+	newAddress := allocateLocked(size)
+	copy(_malloc.blocks[len(_malloc.blocks)-1].mem, mem)
+	_malloc.blocks = append(_malloc.blocks[:i], _malloc.blocks[i+1:]...)
+	return newAddress
 }
 
 func free(address int64) {
-	if _, ok := _allocated[address]; ok {
-		delete(_allocated, address)
-	} else {
-		panic("memory was not allocated!")
+	if address == 0 {
+		return
 	}
+	_malloc.Lock()
+	defer _malloc.Unlock()
+	i := blockStartingAt(address)
+	_malloc.blocks = append(_malloc.blocks[:i], _malloc.blocks[i+1:]...)
 }
 
-func memoryAt(address int64) []byte {
-	for startAddress, mem := range _allocated {
-		endAddress := startAddress + int64(len(mem))
-		if address >= startAddress && address < endAddress {
-			offset := address - startAddress
-			return mem[offset:]
+// 从address开始的n个字节 不能跨越两个块
+func memoryAt(address, n int64) []byte {
+	_malloc.Lock()
+	defer _malloc.Unlock()
+	i := blockContaining(address)
+	if i < 0 {
+		panic(badAddress())
+	}
+	block := _malloc.blocks[i]
+	offset := address - block.address
+	if n < 0 || offset+n > int64(len(block.mem)) {
+		panic(badAddress())
+	}
+	return block.mem[offset : offset+n]
+}
+
+// 起始地址不超过address的最后一块
+func blockContaining(address int64) int {
+	blocks := _malloc.blocks
+	return sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].address > address
+	}) - 1
+}
+
+func blockStartingAt(address int64) int {
+	i := blockContaining(address)
+	if i < 0 || _malloc.blocks[i].address != address {
+		panic(heap.NewJavaException("java/lang/InternalError",
+			"memory was not allocated by Unsafe.allocateMemory"))
+	}
+	return i
+}
+
+// HotSpot访问非法地址时同样抛出InternalError
+func badAddress() *heap.JavaException {
+	return heap.NewJavaException("java/lang/InternalError",
+		"a fault occurred in an unsafe memory access operation")
+}
+
+// 给java.nio的本地方法用
+// base为null时offset是堆外内存的地址 否则是基本类型数组中的字节偏移
+func Memory(base *heap.Object, offset, bytes int64) []byte {
+	return memoryOf(base, offset, bytes)
+}
+
+// 以0结尾的字符串 比如NativeBuffer里的路径
+func CString(address int64) []byte {
+	_malloc.Lock()
+	i := blockContaining(address)
+	if i < 0 || address-_malloc.blocks[i].address >= int64(len(_malloc.blocks[i].mem)) {
+		_malloc.Unlock()
+		panic(badAddress())
+	}
+	block := _malloc.blocks[i]
+	_malloc.Unlock()
+
+	mem := block.mem[address-block.address:]
+	for n, b := range mem {
+		if b == 0 {
+			return mem[:n]
 		}
 	}
-	panic("invalid address!")
-	return nil
+	panic(badAddress())
+}
+
+// FileChannel.map()映射的内存也放进块表 这样Unsafe可以直接读写
+func MapMemory(mem []byte) int64 {
+	_malloc.Lock()
+	defer _malloc.Unlock()
+	return addBlockLocked(mem)
+}
+
+// 返回映射的内存 由调用者munmap
+func UnmapMemory(address int64) []byte {
+	_malloc.Lock()
+	defer _malloc.Unlock()
+	i := blockStartingAt(address)
+	mem := _malloc.blocks[i].mem
+	_malloc.blocks = append(_malloc.blocks[:i], _malloc.blocks[i+1:]...)
+	return mem
 }
@@ -0,0 +1,121 @@
+//go:build unix
+
+package ch
+
+import (
+	"jvm/native"
+	"jvm/native/sun/misc"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"os"
+	"syscall"
+)
+
+const fileChannel = "sun/nio/ch/FileChannelImpl"
+
+func init() {
+	native.Register(fileChannel, "initIDs", "()J", fc_initIDs)
+	native.Register(fileChannel, "position0", "(Ljava/io/FileDescriptor;J)J", position0)
+	native.Register(fileChannel, "transferTo0", "(Ljava/io/FileDescriptor;JJLjava/io/FileDescriptor;)J", transferTo0)
+	native.Register(fileChannel, "map0", "(IJJ)J", map0)
+	native.Register(fileChannel, "unmap0", "(JJ)I", unmap0)
+	native.Register("java/nio/MappedByteBuffer", "isLoaded0", "(JJI)Z", isLoaded0)
+	native.Register("java/nio/MappedByteBuffer", "load0", "(JJ)V", load0)
+	native.Register("java/nio/MappedByteBuffer", "force0", "(Ljava/io/FileDescriptor;JJ)V", mbb_force0)
+}
+
+// 返回map()的地址对齐粒度
+// private static native long initIDs();
+// ()J
+func fc_initIDs(frame *rtda.Frame) {
+	frame.OperandStack().PushLong(int64(os.Getpagesize()))
+}
+
+// private native long position0(FileDescriptor fd, long offset);
+// (Ljava/io/FileDescriptor;J)J
+func position0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(1))
+	offset := vars.GetLong(2)
+
+	frame.OperandStack().PushLong(seek(fd, offset))
+}
+
+// 让Java代码退回到用map()或者循环读写的方式
+// private native long transferTo0(FileDescriptor src, long position, long count, FileDescriptor dst);
+// (Ljava/io/FileDescriptor;JJLjava/io/FileDescriptor;)J
+func transferTo0(frame *rtda.Frame) {
+	frame.OperandStack().PushLong(IOS_UNSUPPORTED_CASE)
+}
+
+// FileChannelImpl.MAP_RO MAP_RW MAP_PV
+const (
+	MAP_RO = 0
+	MAP_RW = 1
+	MAP_PV = 2
+)
+
+// 映射的内存登记到Unsafe的块表里 DirectByteBuffer按地址读写
+// private native long map0(int prot, long position, long length) throws IOException;
+// (IJJ)J
+func map0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	this := vars.GetThis()
+	prot := vars.GetInt(1)
+	position := vars.GetLong(2)
+	length := vars.GetLong(4)
+
+	fd := _fd(this.GetRefVar("fd", "Ljava/io/FileDescriptor;"))
+	mmapProt, flags := syscall.PROT_READ, syscall.MAP_SHARED
+	switch prot {
+	case MAP_RW:
+		mmapProt |= syscall.PROT_WRITE
+	case MAP_PV:
+		mmapProt |= syscall.PROT_WRITE
+		flags = syscall.MAP_PRIVATE
+	}
+	mem, err := syscall.Mmap(fd, position, int(length), mmapProt, flags)
+	if err == syscall.ENOMEM {
+		// FileChannelImpl.map()捕获之后会先GC再重试一次
+		panic(heap.NewJavaException("java/lang/OutOfMemoryError", "Map failed"))
+	}
+	if err != nil {
+		panic(ioException("Map failed", err))
+	}
+	frame.OperandStack().PushLong(misc.MapMemory(mem))
+}
+
+// private static native int unmap0(long address, long length);
+// (JJ)I
+func unmap0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	address := vars.GetLong(0)
+
+	mem := misc.UnmapMemory(address)
+	if err := syscall.Munmap(mem); err != nil {
+		panic(ioException("Unmap failed", err))
+	}
+	frame.OperandStack().PushInt(0)
+}
+
+// private native boolean isLoaded0(long address, long length, int pageCount);
+// (JJI)Z
+func isLoaded0(frame *rtda.Frame) {
+	frame.OperandStack().PushBoolean(true)
+}
+
+// private native void load0(long address, long length);
+// (JJ)V
+func load0(frame *rtda.Frame) {
+}
+
+// private native void force0(FileDescriptor fd, long address, long length);
+// (Ljava/io/FileDescriptor;JJ)V
+func mbb_force0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(1))
+
+	if err := syscall.Fsync(fd); err != nil {
+		panic(ioException("Force failed", err))
+	}
+}
@@ -0,0 +1,366 @@
+//go:build unix
+
+package ch
+
+import (
+	"io"
+	"jvm/native"
+	"jvm/native/sun/misc"
+	"jvm/rtda"
+	"math"
+	"syscall"
+)
+
+const fileDispatcher = "sun/nio/ch/FileDispatcherImpl"
+
+func init() {
+	_fdi(read0, "read0", "(Ljava/io/FileDescriptor;JI)I")
+	_fdi(pread0, "pread0", "(Ljava/io/FileDescriptor;JIJ)I")
+	_fdi(readv0, "readv0", "(Ljava/io/FileDescriptor;JI)J")
+	_fdi(write0, "write0", "(Ljava/io/FileDescriptor;JI)I")
+	_fdi(pwrite0, "pwrite0", "(Ljava/io/FileDescriptor;JIJ)I")
+	_fdi(writev0, "writev0", "(Ljava/io/FileDescriptor;JI)J")
+	_fdi(seek0, "seek0", "(Ljava/io/FileDescriptor;J)J")
+	_fdi(force0, "force0", "(Ljava/io/FileDescriptor;Z)I")
+	_fdi(truncate0, "truncate0", "(Ljava/io/FileDescriptor;J)I")
+	_fdi(size0, "size0", "(Ljava/io/FileDescriptor;)J")
+	_fdi(lock0, "lock0", "(Ljava/io/FileDescriptor;ZJJZ)I")
+	_fdi(release0, "release0", "(Ljava/io/FileDescriptor;JJ)V")
+	_fdi(close0, "close0", "(Ljava/io/FileDescriptor;)V")
+	_fdi(preClose0, "preClose0", "(Ljava/io/FileDescriptor;)V")
+	_fdi(closeIntFD, "closeIntFD", "(I)V")
+	_fdi(func(frame *rtda.Frame) {}, "init", "()V")
+}
+
+func _fdi(method func(frame *rtda.Frame), name, desc string) {
+	native.Register(fileDispatcher, name, desc, method)
+}
+
+// static native int read0(FileDescriptor fd, long address, int len) throws IOException;
+// (Ljava/io/FileDescriptor;JI)I
+func read0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	address := vars.GetLong(1)
+	length := vars.GetInt(3)
+
+	buf := misc.Memory(nil, address, int64(length))
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		n, err = syscall.Read(fd, buf)
+	})
+	frame.OperandStack().PushInt(convertReturnVal(n, err, true))
+}
+
+// static native int pread0(FileDescriptor fd, long address, int len, long position) throws IOException;
+// (Ljava/io/FileDescriptor;JIJ)I
+func pread0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	address := vars.GetLong(1)
+	length := vars.GetInt(3)
+	position := vars.GetLong(4)
+
+	buf := misc.Memory(nil, address, int64(length))
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		n, err = syscall.Pread(fd, buf, position)
+	})
+	frame.OperandStack().PushInt(convertReturnVal(n, err, true))
+}
+
+// 依次读入每个iovec 某一个没有读满就停下
+// static native long readv0(FileDescriptor fd, long address, int len) throws IOException;
+// (Ljava/io/FileDescriptor;JI)J
+func readv0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	address := vars.GetLong(1)
+	length := vars.GetInt(3)
+
+	iovecs := iovecsAt(address, length)
+	var total int64
+	var err error
+	frame.Thread().Blocking(func() {
+		for _, buf := range iovecs {
+			var n int
+			n, err = syscall.Read(fd, buf)
+			if n > 0 {
+				total += int64(n)
+			}
+			if err != nil || n < len(buf) {
+				break
+			}
+		}
+	})
+	frame.OperandStack().PushLong(convertLongReturnVal(total, err, true))
+}
+
+// static native int write0(FileDescriptor fd, long address, int len) throws IOException;
+// (Ljava/io/FileDescriptor;JI)I
+func write0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	address := vars.GetLong(1)
+	length := vars.GetInt(3)
+
+	buf := misc.Memory(nil, address, int64(length))
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		n, err = syscall.Write(fd, buf)
+	})
+	frame.OperandStack().PushInt(convertReturnVal(n, err, false))
+}
+
+// static native int pwrite0(FileDescriptor fd, long address, int len, long position) throws IOException;
+// (Ljava/io/FileDescriptor;JIJ)I
+func pwrite0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	address := vars.GetLong(1)
+	length := vars.GetInt(3)
+	position := vars.GetLong(4)
+
+	buf := misc.Memory(nil, address, int64(length))
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		n, err = syscall.Pwrite(fd, buf, position)
+	})
+	frame.OperandStack().PushInt(convertReturnVal(n, err, false))
+}
+
+// static native long writev0(FileDescriptor fd, long address, int len) throws IOException;
+// (Ljava/io/FileDescriptor;JI)J
+func writev0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	address := vars.GetLong(1)
+	length := vars.GetInt(3)
+
+	iovecs := iovecsAt(address, length)
+	var total int64
+	var err error
+	frame.Thread().Blocking(func() {
+		for _, buf := range iovecs {
+			var n int
+			n, err = syscall.Write(fd, buf)
+			if n > 0 {
+				total += int64(n)
+			}
+			if err != nil || n < len(buf) {
+				break
+			}
+		}
+	})
+	frame.OperandStack().PushLong(convertLongReturnVal(total, err, false))
+}
+
+// offset小于0时只返回当前位置
+// static native long seek0(FileDescriptor fd, long offset) throws IOException;
+// (Ljava/io/FileDescriptor;J)J
+func seek0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	offset := vars.GetLong(1)
+
+	frame.OperandStack().PushLong(seek(fd, offset))
+}
+
+func seek(fd int, offset int64) int64 {
+	var pos int64
+	var err error
+	if offset < 0 {
+		pos, err = syscall.Seek(fd, 0, io.SeekCurrent)
+	} else {
+		pos, err = syscall.Seek(fd, offset, io.SeekStart)
+	}
+	return convertLongReturnVal(pos, err, true)
+}
+
+// static native int force0(FileDescriptor fd, boolean metaData) throws IOException;
+// (Ljava/io/FileDescriptor;Z)I
+func force0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+
+	var err error
+	frame.Thread().Blocking(func() {
+		err = syscall.Fsync(fd)
+	})
+	if err != nil {
+		panic(ioException("Force failed", err))
+	}
+	frame.OperandStack().PushInt(0)
+}
+
+// static native int truncate0(FileDescriptor fd, long size) throws IOException;
+// (Ljava/io/FileDescriptor;J)I
+func truncate0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	size := vars.GetLong(1)
+
+	var err error
+	frame.Thread().Blocking(func() {
+		err = syscall.Ftruncate(fd, size)
+	})
+	if err != nil {
+		panic(ioException("Truncation failed", err))
+	}
+	frame.OperandStack().PushInt(0)
+}
+
+// static native long size0(FileDescriptor fd) throws IOException;
+// (Ljava/io/FileDescriptor;)J
+func size0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+
+	var stat syscall.Stat_t
+	var err error
+	frame.Thread().Blocking(func() {
+		err = syscall.Fstat(fd, &stat)
+	})
+	if err != nil {
+		panic(ioException("Size failed", err))
+	}
+	frame.OperandStack().PushLong(stat.Size)
+}
+
+// sun.nio.ch.FileDispatcher
+const (
+	NO_LOCK     = -1
+	LOCKED      = 0
+	RET_EX_LOCK = 1
+	INTERRUPTED = 2
+)
+
+// static native int lock0(FileDescriptor fd, boolean blocking, long pos, long size, boolean shared) throws IOException;
+// (Ljava/io/FileDescriptor;ZJJZ)I
+func lock0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	blocking := vars.GetBoolean(1)
+	pos := vars.GetLong(2)
+	size := vars.GetLong(4)
+	shared := vars.GetBoolean(6)
+
+	lock := fileLock(pos, size, syscall.F_WRLCK)
+	if shared {
+		lock.Type = syscall.F_RDLCK
+	}
+	cmd := syscall.F_SETLK
+	if blocking {
+		cmd = syscall.F_SETLKW
+	}
+	var err error
+	frame.Thread().Blocking(func() {
+		err = syscall.FcntlFlock(uintptr(fd), cmd, lock)
+	})
+
+	stack := frame.OperandStack()
+	switch err {
+	case nil:
+		stack.PushInt(LOCKED)
+	case syscall.EAGAIN, syscall.EACCES:
+		stack.PushInt(NO_LOCK)
+	case syscall.EINTR:
+		stack.PushInt(INTERRUPTED)
+	default:
+		panic(ioException("Lock failed", err))
+	}
+}
+
+// static native void release0(FileDescriptor fd, long pos, long size) throws IOException;
+// (Ljava/io/FileDescriptor;JJ)V
+func release0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := _fd(vars.GetRef(0))
+	pos := vars.GetLong(1)
+	size := vars.GetLong(3)
+
+	lock := fileLock(pos, size, syscall.F_UNLCK)
+	if err := syscall.FcntlFlock(uintptr(fd), syscall.F_SETLK, lock); err != nil {
+		panic(ioException("Release failed", err))
+	}
+}
+
+// size为Long.MAX_VALUE表示锁到文件末尾
+func fileLock(pos, size int64, lockType int16) *syscall.Flock_t {
+	lock := &syscall.Flock_t{Type: lockType, Whence: io.SeekStart, Start: pos, Len: size}
+	if size == math.MaxInt64 {
+		lock.Len = 0
+	}
+	return lock
+}
+
+// static native void close0(FileDescriptor fd) throws IOException;
+// (Ljava/io/FileDescriptor;)V
+func close0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fdObj := vars.GetRef(0)
+
+	if fd := _fd(fdObj); fd != -1 {
+		fdObj.SetIntVar("fd", "I", -1)
+		if err := syscall.Close(fd); err != nil {
+			panic(ioException("Close failed", err))
+		}
+	}
+}
+
+// 用来唤醒阻塞在这个fd上的线程 普通文件不需要
+// static native void preClose0(FileDescriptor fd) throws IOException;
+// (Ljava/io/FileDescriptor;)V
+func preClose0(frame *rtda.Frame) {
+}
+
+// static native void closeIntFD(int fd) throws IOException;
+// (I)V
+func closeIntFD(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+
+	if err := syscall.Close(fd); err != nil {
+		panic(ioException("Close failed", err))
+	}
+}
+
+// IOVecWrapper在堆外内存里构造的struct iovec数组 每项是base和len两个long
+func iovecsAt(address int64, count int32) [][]byte {
+	iovecs := make([][]byte, count)
+	for i := range iovecs {
+		iovec := misc.Memory(nil, address+int64(i)*16, 16)
+		base := misc.Int64(iovec[:8])
+		length := misc.Int64(iovec[8:])
+		iovecs[i] = misc.Memory(nil, base, length)
+	}
+	return iovecs
+}
+
+// 和JDK的convertReturnVal一样 把系统调用的结果换成IOStatus
+func convertReturnVal(n int, err error, reading bool) int32 {
+	return int32(convertLongReturnVal(int64(n), err, reading))
+}
+
+func convertLongReturnVal(n int64, err error, reading bool) int64 {
+	switch {
+	case err == nil && n == 0 && reading:
+		return IOS_EOF
+	case err == nil:
+		return n
+	case n > 0:
+		return n // 已经读写了一部分 错误留给下一次调用
+	case err == syscall.EAGAIN:
+		return IOS_UNAVAILABLE
+	case err == syscall.EINTR:
+		return IOS_INTERRUPTED
+	case reading:
+		panic(ioException("Read failed", err))
+	default:
+		panic(ioException("Write failed", err))
+	}
+}
@@ -0,0 +1,149 @@
+//go:build unix
+
+package ch
+
+import (
+	"jvm/native"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"syscall"
+)
+
+const ioUtil = "sun/nio/ch/IOUtil"
+
+// sun.nio.ch.IOStatus 本地方法用这些返回值表示没有读写任何字节的原因
+const (
+	IOS_EOF              = -1
+	IOS_UNAVAILABLE      = -2
+	IOS_INTERRUPTED      = -3
+	IOS_UNSUPPORTED      = -4
+	IOS_THROWN           = -5
+	IOS_UNSUPPORTED_CASE = -6
+)
+
+func init() {
+	native.Register(ioUtil, "fdVal", "(Ljava/io/FileDescriptor;)I", fdVal)
+	native.Register(ioUtil, "setfdVal", "(Ljava/io/FileDescriptor;I)V", setfdVal)
+	native.Register(ioUtil, "iovMax", "()I", iovMax)
+	native.Register(ioUtil, "fdLimit", "()I", fdLimit)
+	native.Register(ioUtil, "configureBlocking", "(Ljava/io/FileDescriptor;Z)V", configureBlocking)
+	native.Register(ioUtil, "makePipe", "(Z)J", makePipe)
+	native.Register(ioUtil, "drain", "(I)Z", drain)
+	native.Register(ioUtil, "randomBytes", "([B)Z", randomBytes)
+}
+
+// static native int fdVal(FileDescriptor fd);
+// (Ljava/io/FileDescriptor;)I
+func fdVal(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fdObj := vars.GetRef(0)
+
+	frame.OperandStack().PushInt(int32(_fd(fdObj)))
+}
+
+// static native void setfdVal(FileDescriptor fd, int value);
+// (Ljava/io/FileDescriptor;I)V
+func setfdVal(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fdObj := vars.GetRef(0)
+	value := vars.GetInt(1)
+
+	fdObj.SetIntVar("fd", "I", value)
+}
+
+// static native int iovMax();
+// ()I
+func iovMax(frame *rtda.Frame) {
+	frame.OperandStack().PushInt(1024) // IOV_MAX
+}
+
+// static native int fdLimit();
+// ()I
+func fdLimit(frame *rtda.Frame) {
+	limit := int32(1024)
+	var rlimit syscall.Rlimit
+	if syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit) == nil && rlimit.Cur < 1<<31 {
+		limit = int32(rlimit.Cur)
+	}
+	frame.OperandStack().PushInt(limit)
+}
+
+// static native void configureBlocking(FileDescriptor fd, boolean blocking) throws IOException;
+// (Ljava/io/FileDescriptor;Z)V
+func configureBlocking(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fdObj := vars.GetRef(0)
+	blocking := vars.GetBoolean(1)
+
+	if err := syscall.SetNonblock(_fd(fdObj), !blocking); err != nil {
+		panic(ioException("Configure blocking failed", err))
+	}
+}
+
+// 高32位是读端 低32位是写端
+// static native long makePipe(boolean blocking);
+// (Z)J
+func makePipe(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	blocking := vars.GetBoolean(0)
+
+	fds := make([]int, 2)
+	if err := syscall.Pipe(fds); err != nil {
+		panic(ioException("Pipe failed", err))
+	}
+	if !blocking {
+		syscall.SetNonblock(fds[0], true)
+		syscall.SetNonblock(fds[1], true)
+	}
+	frame.OperandStack().PushLong(int64(fds[0])<<32 | int64(fds[1]))
+}
+
+// 读空非阻塞的管道 返回是否读到了数据
+// static native boolean drain(int fd) throws IOException;
+// (I)Z
+func drain(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+
+	buf := make([]byte, 128)
+	drained := false
+	for {
+		n, err := syscall.Read(fd, buf)
+		if n > 0 {
+			drained = true
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == syscall.EAGAIN {
+			break
+		}
+		if err != nil {
+			panic(ioException("Drain", err))
+		}
+		if n < len(buf) {
+			break
+		}
+	}
+	frame.OperandStack().PushBoolean(drained)
+}
+
+// 和JDK在Linux上的实现一样不提供 由调用者改用SecureRandom
+// static native boolean randomBytes(byte[] someBytes);
+// ([B)Z
+func randomBytes(frame *rtda.Frame) {
+	frame.OperandStack().PushBoolean(false)
+}
+
+// FileDescriptor.fd
+func _fd(fdObj *heap.Object) int {
+	if fdObj == nil {
+		panic(heap.NewJavaException("java/lang/NullPointerException", ""))
+	}
+	return int(fdObj.GetIntVar("fd", "I"))
+}
+
+// 和JNU_ThrowIOExceptionWithLastError一样 消息里带上系统的错误描述
+func ioException(msg string, err error) *heap.JavaException {
+	return heap.NewJavaException("java/io/IOException", msg+": "+err.Error())
+}
@@ -0,0 +1,26 @@
+package ch
+
+import (
+	"jvm/native"
+	"jvm/rtda"
+)
+
+const nativeThread = "sun/nio/ch/NativeThread"
+
+func init() {
+	native.Register(nativeThread, "current", "()J", current)
+	native.Register(nativeThread, "signal", "(J)V", signal)
+	native.Register(nativeThread, "init", "()V", func(frame *rtda.Frame) {})
+}
+
+// 0表示没有可以发信号唤醒的本地线程 NativeThreadSet会把它当成占位符
+// public static native long current();
+// ()J
+func current(frame *rtda.Frame) {
+	frame.OperandStack().PushLong(0)
+}
+
+// public static native void signal(long nt) throws IOException;
+// (J)V
+func signal(frame *rtda.Frame) {
+}
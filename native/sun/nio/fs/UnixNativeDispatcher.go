@@ -0,0 +1,573 @@
+//go:build linux || darwin
+
+package fs
+
+import (
+	"errors"
+	"io"
+	"jvm/instructions/base"
+	"jvm/native"
+	"jvm/native/sun/misc"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const unixNativeDispatcher = "sun/nio/fs/UnixNativeDispatcher"
+
+// 路径都是NativeBuffer的地址 指向以0结尾的字节串
+func init() {
+	_und(und_init, "init", "()I")
+	_und(getcwd, "getcwd", "()[B")
+	_und(dup, "dup", "(I)I")
+	_und(open0, "open0", "(JII)I")
+	_und(und_close, "close", "(I)V")
+	_und(link0, "link0", "(JJ)V")
+	_und(unlink0, "unlink0", "(J)V")
+	_und(mknod0, "mknod0", "(JIJ)V")
+	_und(rename0, "rename0", "(JJ)V")
+	_und(mkdir0, "mkdir0", "(JI)V")
+	_und(rmdir0, "rmdir0", "(J)V")
+	_und(readlink0, "readlink0", "(J)[B")
+	_und(realpath0, "realpath0", "(J)[B")
+	_und(symlink0, "symlink0", "(JJ)V")
+	_und(stat0, "stat0", "(JLsun/nio/fs/UnixFileAttributes;)V")
+	_und(lstat0, "lstat0", "(JLsun/nio/fs/UnixFileAttributes;)V")
+	_und(fstat, "fstat", "(ILsun/nio/fs/UnixFileAttributes;)V")
+	_und(chown0, "chown0", "(JII)V")
+	_und(lchown0, "lchown0", "(JII)V")
+	_und(fchown, "fchown", "(III)V")
+	_und(chmod0, "chmod0", "(JI)V")
+	_und(fchmod, "fchmod", "(II)V")
+	_und(utimes0, "utimes0", "(JJJ)V")
+	_und(futimes, "futimes", "(IJJ)V")
+	_und(opendir0, "opendir0", "(J)J")
+	_und(closedir, "closedir", "(J)V")
+	_und(readdir, "readdir", "(J)[B")
+	_und(und_read, "read", "(IJI)I")
+	_und(und_write, "write", "(IJI)I")
+	_und(access0, "access0", "(JI)V")
+	_und(getpwuid, "getpwuid", "(I)[B")
+	_und(getgrgid, "getgrgid", "(I)[B")
+	_und(getpwnam0, "getpwnam0", "(J)I")
+	_und(getgrnam0, "getgrnam0", "(J)I")
+	_und(statvfs0, "statvfs0", "(JLsun/nio/fs/UnixFileStoreAttributes;)V")
+	_und(strerror, "strerror", "(I)[B")
+}
+
+func _und(method func(frame *rtda.Frame), name, desc string) {
+	native.Register(unixNativeDispatcher, name, desc, method)
+}
+
+// 返回的标志里没有HAS_AT_SYSCALLS 这样Java代码不会用openat、fstatat这些系统调用
+// private static native int init();
+// ()I
+func und_init(frame *rtda.Frame) {
+	frame.OperandStack().PushInt(0)
+}
+
+// static native byte[] getcwd();
+// ()[B
+func getcwd(frame *rtda.Frame) {
+	dir, err := os.Getwd()
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	pushBytes(frame, []byte(dir))
+}
+
+// static native int dup(int filedes) throws UnixException;
+// (I)I
+func dup(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+
+	newFd, err := syscall.Dup(fd)
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	frame.OperandStack().PushInt(int32(newFd))
+}
+
+// flags和mode就是UnixConstants里的O_*和权限位 原样交给open(2)
+// private static native int open0(long pathAddress, int flags, int mode) throws UnixException;
+// (JII)I
+func open0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	flags := int(vars.GetInt(2))
+	mode := uint32(vars.GetInt(3))
+
+	var fd int
+	var err error
+	frame.Thread().Blocking(func() { // 打开FIFO会阻塞
+		fd, err = syscall.Open(path, flags, mode)
+	})
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	frame.OperandStack().PushInt(int32(fd))
+}
+
+// static native void close(int fd);
+// (I)V
+func und_close(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+
+	syscall.Close(fd) // 和JDK一样忽略错误
+}
+
+// private static native void link0(long existingAddress, long newAddress) throws UnixException;
+// (JJ)V
+func link0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	existing := _path(vars.GetLong(0))
+	newPath := _path(vars.GetLong(2))
+
+	check(frame, syscall.Link(existing, newPath))
+}
+
+// private static native void unlink0(long pathAddress) throws UnixException;
+// (J)V
+func unlink0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	check(frame, syscall.Unlink(path))
+}
+
+// private static native void mknod0(long pathAddress, int mode, long dev) throws UnixException;
+// (JIJ)V
+func mknod0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	mode := uint32(vars.GetInt(2))
+	dev := vars.GetLong(3)
+
+	check(frame, syscall.Mknod(path, mode, int(dev)))
+}
+
+// private static native void rename0(long fromAddress, long toAddress) throws UnixException;
+// (JJ)V
+func rename0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	from := _path(vars.GetLong(0))
+	to := _path(vars.GetLong(2))
+
+	check(frame, syscall.Rename(from, to))
+}
+
+// private static native void mkdir0(long pathAddress, int mode) throws UnixException;
+// (JI)V
+func mkdir0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	mode := uint32(vars.GetInt(2))
+
+	check(frame, syscall.Mkdir(path, mode))
+}
+
+// private static native void rmdir0(long pathAddress) throws UnixException;
+// (J)V
+func rmdir0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	check(frame, syscall.Rmdir(path))
+}
+
+// private static native byte[] readlink0(long pathAddress) throws UnixException;
+// (J)[B
+func readlink0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	pushBytes(frame, []byte(target))
+}
+
+// private static native byte[] realpath0(long pathAddress) throws UnixException;
+// (J)[B
+func realpath0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		resolved, err = filepath.Abs(resolved)
+	}
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	pushBytes(frame, []byte(resolved))
+}
+
+// private static native void symlink0(long name1, long name2) throws UnixException;
+// (JJ)V
+func symlink0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	target := _path(vars.GetLong(0))
+	link := _path(vars.GetLong(2))
+
+	check(frame, syscall.Symlink(target, link))
+}
+
+// private static native void stat0(long pathAddress, UnixFileAttributes attrs) throws UnixException;
+// (JLsun/nio/fs/UnixFileAttributes;)V
+func stat0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	attrs := vars.GetRef(2)
+
+	var stat syscall.Stat_t
+	check(frame, syscall.Stat(path, &stat))
+	fillAttributes(attrs, &stat)
+}
+
+// private static native void lstat0(long pathAddress, UnixFileAttributes attrs) throws UnixException;
+// (JLsun/nio/fs/UnixFileAttributes;)V
+func lstat0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	attrs := vars.GetRef(2)
+
+	var stat syscall.Stat_t
+	check(frame, syscall.Lstat(path, &stat))
+	fillAttributes(attrs, &stat)
+}
+
+// static native void fstat(int fd, UnixFileAttributes attrs) throws UnixException;
+// (ILsun/nio/fs/UnixFileAttributes;)V
+func fstat(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+	attrs := vars.GetRef(1)
+
+	var stat syscall.Stat_t
+	check(frame, syscall.Fstat(fd, &stat))
+	fillAttributes(attrs, &stat)
+}
+
+// private static native void chown0(long pathAddress, int uid, int gid) throws UnixException;
+// (JII)V
+func chown0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	check(frame, syscall.Chown(path, int(vars.GetInt(2)), int(vars.GetInt(3))))
+}
+
+// private static native void lchown0(long pathAddress, int uid, int gid) throws UnixException;
+// (JII)V
+func lchown0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	check(frame, syscall.Lchown(path, int(vars.GetInt(2)), int(vars.GetInt(3))))
+}
+
+// static native void fchown(int fd, int uid, int gid) throws UnixException;
+// (III)V
+func fchown(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+
+	check(frame, syscall.Fchown(int(vars.GetInt(0)), int(vars.GetInt(1)), int(vars.GetInt(2))))
+}
+
+// private static native void chmod0(long pathAddress, int mode) throws UnixException;
+// (JI)V
+func chmod0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	check(frame, syscall.Chmod(path, uint32(vars.GetInt(2))))
+}
+
+// static native void fchmod(int fd, int mode) throws UnixException;
+// (II)V
+func fchmod(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+
+	check(frame, syscall.Fchmod(int(vars.GetInt(0)), uint32(vars.GetInt(1))))
+}
+
+// 时间以微秒为单位
+// private static native void utimes0(long pathAddress, long times0, long times1) throws UnixException;
+// (JJJ)V
+func utimes0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	times := []syscall.Timeval{
+		syscall.NsecToTimeval(vars.GetLong(2) * 1000),
+		syscall.NsecToTimeval(vars.GetLong(4) * 1000),
+	}
+
+	check(frame, syscall.Utimes(path, times))
+}
+
+// static native void futimes(int fd, long times0, long times1) throws UnixException;
+// (IJJ)V
+func futimes(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+	times := []syscall.Timeval{
+		syscall.NsecToTimeval(vars.GetLong(1) * 1000),
+		syscall.NsecToTimeval(vars.GetLong(3) * 1000),
+	}
+
+	check(frame, syscall.Futimes(fd, times))
+}
+
+// opendir返回的DIR*用一个句柄代替
+var _dirs = struct {
+	sync.Mutex
+	next int64
+	open map[int64]*os.File
+}{next: 1, open: map[int64]*os.File{}}
+
+// private static native long opendir0(long pathAddress) throws UnixException;
+// (J)J
+func opendir0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+
+	dir, err := os.Open(path)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = dir.Stat(); err == nil && !info.IsDir() {
+			dir.Close()
+			err = syscall.ENOTDIR
+		}
+	}
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+
+	_dirs.Lock()
+	handle := _dirs.next
+	_dirs.next++
+	_dirs.open[handle] = dir
+	_dirs.Unlock()
+	frame.OperandStack().PushLong(handle)
+}
+
+// static native void closedir(long dir) throws UnixException;
+// (J)V
+func closedir(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	handle := vars.GetLong(0)
+
+	_dirs.Lock()
+	dir := _dirs.open[handle]
+	delete(_dirs.open, handle)
+	_dirs.Unlock()
+	if dir != nil {
+		check(frame, dir.Close())
+	}
+}
+
+// 读完时返回null Readdirnames不会返回.和..
+// static native byte[] readdir(long dir) throws UnixException;
+// (J)[B
+func readdir(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	handle := vars.GetLong(0)
+
+	_dirs.Lock()
+	dir := _dirs.open[handle]
+	_dirs.Unlock()
+	if dir == nil {
+		throwUnixException(frame, syscall.EBADF)
+	}
+	names, err := dir.Readdirnames(1)
+	if err == io.EOF {
+		frame.OperandStack().PushRef(nil)
+		return
+	}
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	pushBytes(frame, []byte(names[0]))
+}
+
+// static native int read(int fildes, long buf, int nbyte) throws UnixException;
+// (IJI)I
+func und_read(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+	buf := misc.Memory(nil, vars.GetLong(1), int64(vars.GetInt(3)))
+
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		n, err = syscall.Read(fd, buf)
+	})
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	frame.OperandStack().PushInt(int32(n))
+}
+
+// static native int write(int fildes, long buf, int nbyte) throws UnixException;
+// (IJI)I
+func und_write(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	fd := int(vars.GetInt(0))
+	buf := misc.Memory(nil, vars.GetLong(1), int64(vars.GetInt(3)))
+
+	var n int
+	var err error
+	frame.Thread().Blocking(func() {
+		n, err = syscall.Write(fd, buf)
+	})
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+	frame.OperandStack().PushInt(int32(n))
+}
+
+// private static native void access0(long pathAddress, int amode) throws UnixException;
+// (JI)V
+func access0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	mode := uint32(vars.GetInt(2))
+
+	check(frame, syscall.Access(path, mode))
+}
+
+// static native byte[] getpwuid(int uid) throws UnixException;
+// (I)[B
+func getpwuid(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	uid := vars.GetInt(0)
+
+	var u *user.User
+	var err error
+	frame.Thread().Blocking(func() { // 可能要查询NSS 比如LDAP
+		u, err = user.LookupId(strconv.Itoa(int(uid)))
+	})
+	if err != nil {
+		throwUnixException(frame, syscall.ENOENT)
+	}
+	pushBytes(frame, []byte(u.Username))
+}
+
+// static native byte[] getgrgid(int gid) throws UnixException;
+// (I)[B
+func getgrgid(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	gid := vars.GetInt(0)
+
+	var g *user.Group
+	var err error
+	frame.Thread().Blocking(func() {
+		g, err = user.LookupGroupId(strconv.Itoa(int(gid)))
+	})
+	if err != nil {
+		throwUnixException(frame, syscall.ENOENT)
+	}
+	pushBytes(frame, []byte(g.Name))
+}
+
+// 找不到时返回-1
+// private static native int getpwnam0(long nameAddress) throws UnixException;
+// (J)I
+func getpwnam0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	name := _path(vars.GetLong(0))
+
+	uid := int64(-1)
+	var u *user.User
+	var err error
+	frame.Thread().Blocking(func() {
+		u, err = user.Lookup(name)
+	})
+	if err == nil {
+		uid, _ = strconv.ParseInt(u.Uid, 10, 32)
+	}
+	frame.OperandStack().PushInt(int32(uid))
+}
+
+// private static native int getgrnam0(long nameAddress) throws UnixException;
+// (J)I
+func getgrnam0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	name := _path(vars.GetLong(0))
+
+	gid := int64(-1)
+	var g *user.Group
+	var err error
+	frame.Thread().Blocking(func() {
+		g, err = user.LookupGroup(name)
+	})
+	if err == nil {
+		gid, _ = strconv.ParseInt(g.Gid, 10, 32)
+	}
+	frame.OperandStack().PushInt(int32(gid))
+}
+
+// private static native void statvfs0(long pathAddress, UnixFileStoreAttributes attrs) throws UnixException;
+// (JLsun/nio/fs/UnixFileStoreAttributes;)V
+func statvfs0(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	path := _path(vars.GetLong(0))
+	attrs := vars.GetRef(2)
+
+	var stat syscall.Statfs_t
+	check(frame, syscall.Statfs(path, &stat))
+	fillStoreAttributes(attrs, &stat)
+}
+
+// static native byte[] strerror(int errnum);
+// (I)[B
+func strerror(frame *rtda.Frame) {
+	vars := frame.LocalVars()
+	errnum := vars.GetInt(0)
+
+	pushBytes(frame, []byte(syscall.Errno(errnum).Error()))
+}
+
+func _path(address int64) string {
+	return string(misc.CString(address))
+}
+
+func pushBytes(frame *rtda.Frame, goBytes []byte) {
+	loader := frame.Method().Class().Loader()
+	jBytes := make([]int8, len(goBytes))
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(jBytes))), len(jBytes)), goBytes)
+	frame.OperandStack().PushRef(heap.NewByteArray(loader, jBytes))
+}
+
+func check(frame *rtda.Frame, err error) {
+	if err != nil {
+		throwUnixException(frame, err)
+	}
+}
+
+// Java代码按errno把UnixException转换成NoSuchFileException等具体的异常
+func throwUnixException(frame *rtda.Frame, err error) {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		errno = syscall.EIO
+	}
+	thread := frame.Thread()
+	exClass := frame.Method().Class().Loader().LoadClass("sun/nio/fs/UnixException")
+	ex := exClass.NewObject()
+	ctor := exClass.GetConstructor("(I)V")
+	args := []rtda.Slot{rtda.RefSlot(ex), rtda.IntSlot(int32(errno))}
+	if _, thrown := base.InvokeAndWait(thread, ctor, args); thrown != nil {
+		ex = thrown
+	}
+	panic(heap.NewThrownException(ex))
+}
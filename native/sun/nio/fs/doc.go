@@ -0,0 +1,3 @@
+// sun.nio.fs的本地方法
+// UnixNativeDispatcher只在linux和darwin上实现 其他系统上这个包是空的
+package fs
@@ -0,0 +1,31 @@
+package fs
+
+import (
+	"jvm/rtda/heap"
+	"syscall"
+)
+
+// 和JDK的prepAttributes一样 把struct stat的字段拷到UnixFileAttributes里
+func fillAttributes(attrs *heap.Object, stat *syscall.Stat_t) {
+	attrs.SetIntVar("st_mode", "I", int32(stat.Mode))
+	attrs.SetLongVar("st_ino", "J", int64(stat.Ino))
+	attrs.SetLongVar("st_dev", "J", int64(stat.Dev))
+	attrs.SetLongVar("st_rdev", "J", int64(stat.Rdev))
+	attrs.SetIntVar("st_nlink", "I", int32(stat.Nlink))
+	attrs.SetIntVar("st_uid", "I", int32(stat.Uid))
+	attrs.SetIntVar("st_gid", "I", int32(stat.Gid))
+	attrs.SetLongVar("st_size", "J", int64(stat.Size))
+	attrs.SetLongVar("st_atime_sec", "J", int64(stat.Atim.Sec))
+	attrs.SetLongVar("st_atime_nsec", "J", int64(stat.Atim.Nsec))
+	attrs.SetLongVar("st_mtime_sec", "J", int64(stat.Mtim.Sec))
+	attrs.SetLongVar("st_mtime_nsec", "J", int64(stat.Mtim.Nsec))
+	attrs.SetLongVar("st_ctime_sec", "J", int64(stat.Ctim.Sec))
+	attrs.SetLongVar("st_ctime_nsec", "J", int64(stat.Ctim.Nsec))
+}
+
+func fillStoreAttributes(attrs *heap.Object, stat *syscall.Statfs_t) {
+	attrs.SetLongVar("f_frsize", "J", int64(stat.Frsize))
+	attrs.SetLongVar("f_blocks", "J", int64(stat.Blocks))
+	attrs.SetLongVar("f_bfree", "J", int64(stat.Bfree))
+	attrs.SetLongVar("f_bavail", "J", int64(stat.Bavail))
+}
@@ -19,10 +19,8 @@ func newInstance0(frame *rtda.Frame) {
 
 	goConstructor := getGoConstructor(constructorObj)
 	goClass := goConstructor.Class()
-	if !goClass.InitStarted() {
-		frame.RevertNextPC()
+	if !goClass.IsInitialized() {
 		base.InitClass(frame.Thread(), goClass)
-		return
 	}
 
 	obj := goClass.NewObject()
@@ -32,7 +30,9 @@ func newInstance0(frame *rtda.Frame) {
 	// call <init>
 	ops := convertArgs(obj, argArrObj, goConstructor)
 	shimFrame := rtda.NewShimFrame(frame.Thread(), ops)
-	frame.Thread().PushFrame(shimFrame)
+	if !base.PushShimFrame(frame.Thread(), shimFrame) {
+		return
+	}
 
 	base.InvokeMethod(shimFrame, goConstructor)
 }
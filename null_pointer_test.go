@@ -0,0 +1,122 @@
+package main
+
+import (
+	"jvm/classpath"
+	"jvm/instructions/base"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"testing"
+)
+
+// 对null执行的指令要抛出可以捕获的NullPointerException 不能让虚拟机崩溃
+// 不管这条指令是第一次执行 还是已经被改写成了*_QUICK
+
+// 异常类只有构造函数
+func newExceptionTestClasses(t testing.TB) []*testClass {
+	var classes []*testClass
+	superName := "java/lang/Object"
+	for _, name := range []string{"java/lang/Throwable", "java/lang/Exception",
+		"java/lang/RuntimeException", "java/lang/NullPointerException"} {
+		cp := newTestConstantPool()
+		init := newBytecode().op(op_aload_0).u2(op_invokespecial, cp.methodRef(superName, "<init>", "()V")).op(op_return)
+		classes = append(classes, &testClass{flags: 0x21, name: name, superName: superName, cp: cp,
+			methods: []testMethod{{0x1, "<init>", "()V", 1, 1, init.bytes(t)}}})
+		superName = name
+	}
+	return classes
+}
+
+// 接口I有一个方法get() 类N实现了I
+// npe(I)I按参数选择一条对null执行的指令 fields(LN;)I对参数执行getfield、putfield和三种调用
+func newNullPointerTestClasses(t testing.TB) []*testClass {
+	iface := &testClass{flags: 0x601, name: "I", superName: "java/lang/Object",
+		methods: []testMethod{{0x401, "get", "()I", 0, 0, nil}}}
+
+	cp := newTestConstantPool()
+	x := cp.fieldRef("N", "x", "I")
+	get := cp.methodRef("N", "get", "()I")
+	iget := cp.interfaceMethodRef("I", "get", "()I")
+	npe := newBytecode().op(op_iload_0).
+		tableswitch("default", 0, "getfield", "putfield", "invokevirtual", "invokespecial",
+			"invokeinterface", "arraylength", "iaload", "athrow", "monitorenter").
+		mark("getfield").op(op_aconst_null).u2(op_getfield, x).op(op_ireturn).
+		mark("putfield").op(op_aconst_null, op_iconst_1).u2(op_putfield, x).op(op_iconst_0, op_ireturn).
+		mark("invokevirtual").op(op_aconst_null).u2(op_invokevirtual, get).op(op_ireturn).
+		mark("invokespecial").op(op_aconst_null).u2(op_invokespecial, get).op(op_ireturn).
+		mark("invokeinterface").op(op_aconst_null).u2(op_invokeinterface, iget).op(1, 0, op_ireturn).
+		mark("arraylength").op(op_aconst_null, op_arraylength, op_ireturn).
+		mark("iaload").op(op_aconst_null, op_iconst_0, op_iaload, op_ireturn).
+		mark("athrow").op(op_aconst_null, op_athrow).
+		mark("monitorenter").op(op_aconst_null, op_monitorenter, op_iconst_0, op_ireturn).
+		mark("default").op(op_iconst_m1, op_ireturn)
+	fields := newBytecode().
+		op(op_aload_0, op_iconst_2).u2(op_putfield, x).
+		op(op_aload_0).u2(op_getfield, x).
+		op(op_aload_0).u2(op_invokevirtual, get).op(op_iadd).
+		op(op_aload_0).u2(op_invokespecial, get).op(op_iadd).
+		op(op_aload_0).u2(op_invokeinterface, iget).op(1, 0, op_iadd, op_ireturn)
+	getCode := newBytecode().op(op_aload_0).u2(op_getfield, x).op(op_ireturn)
+
+	class := &testClass{flags: 0x21, name: "N", superName: "java/lang/Object", interfaces: []string{"I"}, cp: cp,
+		fields: []testField{{0x0, "x", "I"}},
+		methods: []testMethod{
+			{0x1, "get", "()I", 1, 1, getCode.bytes(t)},
+			{0x9, "npe", "(I)I", 2, 1, npe.bytes(t)},
+			{0x9, "fields", "(LN;)I", 2, 1, fields.bytes(t)},
+		},
+	}
+	return append(newExceptionTestClasses(t), iface, class)
+}
+
+func runNullPointerTest(t *testing.T, jre, mode string, fast, jit bool) {
+	fastInterpreter, useJIT = fast, jit
+	defer func() { fastInterpreter, useJIT = false, false }()
+	base.SetInterpreter(func(thread *rtda.Thread, barrier *rtda.Frame) {
+		run(thread, false, barrier)
+	})
+	if jit {
+		base.SetInvocationCounter(countInvocation)
+		defer base.SetInvocationCounter(nil)
+	}
+
+	loader := heap.NewClassLoader(classpath.Parse(jre, jre), heap.NewHeap(0), false, false, nil)
+	thread := rtda.NewThread()
+	thread.Acquire()
+	defer thread.Release()
+	class := loader.LoadClass("N")
+	expectNPE := func(what string, method *heap.Method, arg rtda.Slot) {
+		_, thrown := base.InvokeAndWait(thread, method, []rtda.Slot{arg})
+		if thrown == nil || thrown.Class().Name() != "java/lang/NullPointerException" {
+			t.Errorf("%s: %s threw %v, want NullPointerException", mode, what, thrown)
+		}
+	}
+
+	// 第一次执行就是null 指令还没有被改写
+	npe := class.GetStaticMethod("npe", "(I)I")
+	names := []string{"getfield", "putfield", "invokevirtual", "invokespecial",
+		"invokeinterface", "arraylength", "iaload", "athrow", "monitorenter"}
+	for i, name := range names {
+		expectNPE(name, npe, rtda.IntSlot(int32(i)))
+	}
+
+	// 先用对象执行 指令被改写成*_QUICK(-XX:+UseJIT时方法也被编译)以后再传null
+	fields := class.GetStaticMethod("fields", "(LN;)I")
+	obj := class.NewObject()
+	for i := 0; i < invocationThreshold+1; i++ {
+		result, thrown := base.InvokeAndWait(thread, fields, []rtda.Slot{rtda.RefSlot(obj)})
+		if thrown != nil || result.Int() != 8 {
+			t.Fatalf("%s: fields(obj) = %d, %v, want 8", mode, result.Int(), thrown)
+		}
+	}
+	if jit && jitted(fields) == nil {
+		t.Errorf("%s: fields was not compiled", mode)
+	}
+	expectNPE("quickened fields", fields, rtda.RefSlot(nil))
+}
+
+func TestNullPointerException(t *testing.T) {
+	jre := writeTestJre(t, newNullPointerTestClasses(t)...)
+	runNullPointerTest(t, jre, "-Xint", false, false)
+	runNullPointerTest(t, jre, "-Xint:fast", true, false)
+	runNullPointerTest(t, jre, "-XX:+UseJIT", false, true)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"jvm/instructions/base"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+)
+
+// 虚拟机内部的Reference Handler线程
+// referent被Go的GC回收之后 把引用对象加入它的ReferenceQueue
+// sun.misc.Cleaner不入队 直接执行clean()
+func (self *JVM) startReferenceHandler() {
+	thread := rtda.NewThread()
+	thread.SetJThread(self.newJThread("Reference Handler", self.systemGroup, 10, true)) // Thread.MAX_PRIORITY
+	go func() {
+		for {
+			pending := self.classLoader.Heap().WaitPendingReferences()
+			thread.Acquire()
+			for _, p := range pending {
+				if ref := p.Reference(); ref != nil {
+					enqueueReference(thread, ref)
+				}
+			}
+			thread.Release()
+		}
+	}()
+}
+
+func enqueueReference(thread *rtda.Thread, ref *heap.Object) {
+	name, descriptor := "enqueue", "()Z"
+	if ref.Class().Name() == "sun/misc/Cleaner" {
+		name, descriptor = "clean", "()V"
+	}
+	if method := heap.LookupMethodInClass(ref.Class(), name, descriptor); method != nil {
+		base.InvokeAndWait(thread, method, []rtda.Slot{rtda.RefSlot(ref)})
+	}
+}
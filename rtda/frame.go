@@ -9,6 +9,7 @@ type Frame struct {
 	thread *Thread
 	method *heap.Method
 	nextPC int // the next instruction after the call
+	lockedObj *heap.Object // synchronized方法进入的monitor 栈帧弹出时释放
 }
 
 func newFrame(thread *Thread, method *heap.Method) *Frame {
@@ -44,7 +45,15 @@ func (self *Frame) SetNextPC(nextPC int) {
 	self.nextPC = nextPC
 }
 
-// 指令执行到一半 PC已经指向下一条指令 这里使之重新指回来
-func (self *Frame) RevertNextPC() {
-	self.nextPC = self.thread.pc
+func (self *Frame) SetLockedObject(obj *heap.Object) {
+	self.lockedObj = obj
+}
+
+// 每个栈帧至少占用的字节数
+const frameOverhead = 64
+
+// 估算栈帧占用的字节数 供-Xss按字节限制栈大小时使用
+func (self *Frame) size() uint {
+	const slotSize = 16
+	return frameOverhead + (self.method.MaxLocals()+self.method.MaxStack())*slotSize
 }
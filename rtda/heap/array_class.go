@@ -13,28 +13,53 @@ func (self *Class) NewArray(count uint) *Object {
 	if !self.IsArray() {
 		panic("Not array class: " + self.name)
 	}
-	switch self.Name() {
-	case "[Z":
-		return &Object{self, make([]int8, count), nil}
-	case "[B":
-		return &Object{self, make([]int8, count), nil}
-	case "[C":
-		return &Object{self, make([]uint16, count), nil}
-	case "[S":
-		return &Object{self, make([]int16, count), nil}
-	case "[I":
-		return &Object{self, make([]int32, count), nil}
-	case "[J":
-		return &Object{self, make([]int64, count), nil}
-	case "[F":
-		return &Object{self, make([]float32, count), nil}
-	case "[D":
-		return &Object{self, make([]float64, count), nil}
+	heap := self.loader.heap
+	size := arraySize(count, self.ElementSize())
+	heap.charge(size) // 先记账 再分配 避免巨大的数组直接撑爆宿主机
+	if self.isRefArray() {
+		return heap.newObject(self, nil, newRefs(count))
+	}
+	return heap.newObject(self, newPrims(count*uint(self.ElementSize())), nil)
+}
+
+// 数组元素占用的字节数
+func (self *Class) ElementSize() int64 {
+	if !self.IsArray() {
+		return refSize
+	}
+	switch self.name[1] {
+	case 'Z', 'B':
+		return 1
+	case 'C', 'S':
+		return 2
+	case 'I', 'F':
+		return 4
+	case 'J', 'D':
+		return 8
 	default:
-		return &Object{self, make([]*Object, count), nil}
+		return refSize
 	}
 }
 
+// 元素是引用类型的数组
+func (self *Class) isRefArray() bool {
+	return self.IsArray() && (self.name[1] == 'L' || self.name[1] == '[')
+}
+
 func NewByteArray(loader *ClassLoader, bytes []int8) *Object {
-	return &Object{loader.LoadClass("[B"), bytes, nil}
+	return newArrayWithData(loader.LoadClass("[B"), bytes)
+}
+
+func NewCharArray(loader *ClassLoader, chars []uint16) *Object {
+	return newArrayWithData(loader.LoadClass("[C"), chars)
+}
+
+// 数组数据已经由Go代码分配好了 这里只负责记账 元素复制到对齐的内存里
+func newArrayWithData[T PrimitiveElement](arrClass *Class, data []T) *Object {
+	heap := arrClass.loader.heap
+	size := arraySize(uint(len(data)), arrClass.ElementSize())
+	heap.charge(size)
+	arr := heap.newObject(arrClass, newPrims(uint(len(data))*uint(arrClass.ElementSize())), nil)
+	copy(ArrayElements[T](arr), data)
+	return arr
 }
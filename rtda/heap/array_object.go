@@ -1,95 +1,80 @@
 package heap
 
+import "unsafe"
+
+// 基本类型数组元素的Go类型 boolean数组和byte数组一样用int8
+type PrimitiveElement interface {
+	int8 | int16 | uint16 | int32 | int64 | float32 | float64
+}
+
+// 把prims看作元素数组 不复制
+// prims按8字节对齐分配 元素类型和数组类型不符时长度按字节数截断 不会越界
+func ArrayElements[T PrimitiveElement](arr *Object) []T {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	return unsafe.Slice((*T)(unsafe.Pointer(unsafe.SliceData(arr.prims))), len(arr.prims)/size)
+}
+
 func (self *Object) Bytes() []int8 {
-	return self.data.([]int8)
+	return ArrayElements[int8](self)
 }
 
 func (self *Object) Shorts() []int16 {
-	return self.data.([]int16)
+	return ArrayElements[int16](self)
 }
 
 func (self *Object) Ints() []int32 {
-	return self.data.([]int32)
+	return ArrayElements[int32](self)
 }
 
 func (self *Object) Longs() []int64 {
-	return self.data.([]int64)
+	return ArrayElements[int64](self)
 }
 
 func (self *Object) Chars() []uint16 {
-	return self.data.([]uint16)
+	return ArrayElements[uint16](self)
 }
 
 func (self *Object) Floats() []float32 {
-	return self.data.([]float32)
+	return ArrayElements[float32](self)
 }
 
 func (self *Object) Doubles() []float64 {
-	return self.data.([]float64)
+	return ArrayElements[float64](self)
 }
 
 func (self *Object) Refs() []*Object {
-	return self.data.([]*Object)
+	return self.refs
+}
+
+// 基本类型数组的元素按本机字节序看作一段内存 sun.misc.Unsafe按字节访问数组时使用
+// 不是基本类型数组时ok为false
+func (self *Object) ArrayMemory() (mem []byte, ok bool) {
+	if !self.class.IsArray() || self.class.isRefArray() {
+		return nil, false
+	}
+	return self.prims, true
 }
 
 func (self *Object) ArrayLength() int32 {
-	switch self.data.(type) {
-	case []int8:
-		return int32(len(self.data.([]int8)))
-	case []int16:
-		return int32(len(self.data.([]int16)))
-	case []int32:
-		return int32(len(self.data.([]int32)))
-	case []int64:
-		return int32(len(self.data.([]int64)))
-	case []uint16:
-		return int32(len(self.data.([]uint16)))
-	case []float32:
-		return int32(len(self.data.([]float32)))
-	case []float64:
-		return int32(len(self.data.([]float64)))
-	case []*Object:
-		return int32(len(self.data.([]*Object)))
-	default:
+	if !self.class.IsArray() {
 		panic("Not array!")
 	}
+	if self.class.isRefArray() {
+		return int32(len(self.refs))
+	}
+	return int32(int64(len(self.prims)) / self.class.ElementSize())
 }
 
+// 调用者已经检查过数组类型和下标范围
 func ArrayCopy(src, dst *Object, srcPos, dstPos, length int32) {
-	switch src.data.(type) {
-	case []int8:
-		_src := src.data.([]int8)[srcPos : srcPos+length]
-		_dst := dst.data.([]int8)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []int16:
-		_src := src.data.([]int16)[srcPos : srcPos+length]
-		_dst := dst.data.([]int16)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []int32:
-		_src := src.data.([]int32)[srcPos : srcPos+length]
-		_dst := dst.data.([]int32)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []int64:
-		_src := src.data.([]int64)[srcPos : srcPos+length]
-		_dst := dst.data.([]int64)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []uint16:
-		_src := src.data.([]uint16)[srcPos : srcPos+length]
-		_dst := dst.data.([]uint16)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []float32:
-		_src := src.data.([]float32)[srcPos : srcPos+length]
-		_dst := dst.data.([]float32)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []float64:
-		_src := src.data.([]float64)[srcPos : srcPos+length]
-		_dst := dst.data.([]float64)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	case []*Object:
-		_src := src.data.([]*Object)[srcPos : srcPos+length]
-		_dst := dst.data.([]*Object)[dstPos : dstPos+length]
-		copy(_dst, _src)
-	default:
+	if !src.class.IsArray() {
 		panic("Not array!")
 	}
+	if src.class.isRefArray() {
+		copy(dst.refs[dstPos:dstPos+length], src.refs[srcPos:srcPos+length])
+		return
+	}
+	size := int32(src.class.ElementSize())
+	copy(dst.prims[dstPos*size:(dstPos+length)*size], src.prims[srcPos*size:(srcPos+length)*size])
 }
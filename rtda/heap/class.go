@@ -17,11 +17,21 @@ type Class struct {
 	loader            *ClassLoader // 类加载器
 	superClass        *Class // 父类指针
 	interfaces        []*Class // 实现的接口表
-	instanceSlotCount uint // 运行时数据占用的槽量
+	instancePrimBytes uint // 实例的基本类型字段占用的字节数
+	instanceRefCount  uint // 实例的引用类型字段个数
 	staticSlotCount   uint // 静态数据占用的槽量
 	staticVars        Slots // 静态数据
-	initStarted       bool // 表示类的<clinit>方法是否已经开始执行
+	initState         uint8 // 初始化状态 见class_init.go
+	initThread        interface{} // 正在执行初始化的线程(*rtda.Thread)
+	initDone          chan struct{} // 初始化结束(成功或失败)时关闭
 	jClass            *Object // java.lang.Class的变量引用
+	refKind           uint8 // 是否是java.lang.ref.Reference的子类
+	finalizable       bool // 是否重写了finalize()
+	stringFields      *stringFields // java.lang.String的字段 见string_pool.go
+	shared            bool // 是否来自-Xshare的归档 字段槽已经算好了
+	vtable            []*Method // 虚方法表 见method_table.go
+	itable            map[*Class][]*Method // 接口方法表 超接口 -> 按itableIndex排列的实现
+	itableSize        int // 接口的方法表长度
 }
 
 func newClass(cf *classfile.ClassFile) *Class {
@@ -117,22 +127,19 @@ func (self *Class) StaticVars() Slots {
 	return self.staticVars
 }
 
-func (self *Class) InitStarted() bool {
-	return self.initStarted
-}
-
 func (self *Class) JClass() *Object {
 	return self.jClass
 }
 
-func (self *Class) StartInit() {
-	self.initStarted = true
-}
-
 // JVM 5.4.4
 // 检测是否可以被某个类访问:
 func (self *Class) isAccessibleTo(other *Class) bool {
-	return self.IsPublic() ||
+	return self.IsPublic() || self.isSameRuntimePackage(other)
+}
+
+// JVM 5.3 运行时包由包名和定义类加载器共同决定
+func (self *Class) isSameRuntimePackage(other *Class) bool {
+	return self.loader == other.loader &&
 		self.GetPackageName() == other.GetPackageName()
 }
 
@@ -63,7 +63,12 @@ func (self *Class) IsSubClassOf(other *Class) bool {
 }
 
 // self implements iface
+// prepare之后的类直接查itable 数组类没有itable
 func (self *Class) IsImplements(iface *Class) bool {
+	if self.itable != nil {
+		_, ok := self.itable[iface]
+		return ok
+	}
 	for c := self;c != nil; c = c.superClass {
 		for _, i := range c.interfaces {
 			if i == iface || i.isSubInterfaceOf(iface) {
@@ -89,7 +94,7 @@ func (self *Class) IsSuperClassOf(other *Class) bool {
 	return other.IsSubClassOf(self)
 }
 
-// c extends self
+// iface extends self
 func (self *Class) IsSuperInterfaceOf(iface *Class) bool {
-	return iface.IsSubClassOf(self)
+	return iface.isSubInterfaceOf(self)
 }
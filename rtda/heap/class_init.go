@@ -0,0 +1,58 @@
+package heap
+
+// JVMS 5.5 类的初始化状态
+// 状态只在持有全局解释器锁时修改 等待其他线程完成初始化时用initDone
+const (
+	CLASS_LINKED              = iota // 已经链接 还没有初始化
+	CLASS_BEING_INITIALIZED          // 某个线程正在执行<clinit>
+	CLASS_INITIALIZED                // 初始化成功
+	CLASS_ERRONEOUS                  // 初始化失败 不能再使用
+)
+
+func (self *Class) InitState() uint8 {
+	return self.initState
+}
+
+func (self *Class) IsInitialized() bool {
+	return self.initState == CLASS_INITIALIZED
+}
+
+// 正在初始化这个类的线程
+func (self *Class) InitThread() interface{} {
+	return self.initThread
+}
+
+// 其他线程正在初始化时 等待这个channel关闭
+func (self *Class) InitDone() <-chan struct{} {
+	return self.initDone
+}
+
+func (self *Class) StartInit(thread interface{}) {
+	self.initState = CLASS_BEING_INITIALIZED
+	self.initThread = thread
+	self.initDone = make(chan struct{})
+}
+
+func (self *Class) FinishInit() {
+	self.endInit(CLASS_INITIALIZED)
+}
+
+func (self *Class) FailInit() {
+	self.endInit(CLASS_ERRONEOUS)
+}
+
+func (self *Class) endInit(state uint8) {
+	self.initState = state
+	self.initThread = nil
+	close(self.initDone)
+}
+
+// 接口声明了非抽象的实例方法(default方法)时 实现类初始化之前要先初始化这个接口
+func (self *Class) DeclaresDefaultMethods() bool {
+	for _, method := range self.methods {
+		if !method.IsAbstract() && !method.IsStatic() {
+			return true
+		}
+	}
+	return false
+}
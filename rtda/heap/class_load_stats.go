@@ -0,0 +1,80 @@
+package heap
+
+import (
+	"fmt"
+	"time"
+)
+
+// -verbose:class 按阶段统计加载的类的个数和耗时
+// load是读取和解析class文件的时间 link是准备阶段的时间
+// resolve是-Xlink:eager时解析常量池的时间 其中包含了因此加载其他类的时间
+type loadStats struct {
+	phases  []*phaseStats
+	current *phaseStats
+}
+
+type phaseStats struct {
+	name        string
+	classes     int
+	loadTime    time.Duration
+	linkTime    time.Duration
+	resolveTime time.Duration
+	start       time.Time
+	elapsed     time.Duration
+}
+
+func newLoadStats() *loadStats {
+	stats := &loadStats{}
+	stats.begin("bootstrap")
+	return stats
+}
+
+func (self *loadStats) begin(name string) {
+	now := time.Now()
+	if self.current != nil {
+		self.current.elapsed = now.Sub(self.current.start)
+	}
+	self.current = &phaseStats{name: name, start: now}
+	self.phases = append(self.phases, self.current)
+}
+
+// 虚拟机启动的各个阶段 比如bootstrap、vm init、application
+func (self *ClassLoader) BeginPhase(name string) {
+	self.Bootstrap().stats.begin(name)
+}
+
+func (self *ClassLoader) PrintStats() {
+	stats := self.Bootstrap().stats
+	stats.current.elapsed = time.Since(stats.current.start) // 当前阶段到此为止
+
+	fmt.Println("[Class loading statistics]")
+	fmt.Printf("%-12s %8s %12s %12s %12s %12s\n",
+		"phase", "classes", "load", "link", "resolve", "elapsed")
+	for _, phase := range stats.phases {
+		fmt.Printf("%-12s %8d %12v %12v %12v %12v\n", phase.name, phase.classes,
+			phase.loadTime, phase.linkTime, phase.resolveTime, phase.elapsed)
+	}
+}
+
+// 计时并累加到当前阶段
+func (self *ClassLoader) timeLoad(fn func()) {
+	start := time.Now()
+	fn()
+	self.Bootstrap().stats.current.loadTime += time.Since(start)
+}
+
+func (self *ClassLoader) timeLink(fn func()) {
+	start := time.Now()
+	fn()
+	self.Bootstrap().stats.current.linkTime += time.Since(start)
+}
+
+func (self *ClassLoader) timeResolve(fn func()) {
+	start := time.Now()
+	fn()
+	self.Bootstrap().stats.current.resolveTime += time.Since(start)
+}
+
+func (self *ClassLoader) countClass() {
+	self.Bootstrap().stats.current.classes++
+}
@@ -7,10 +7,18 @@ import (
 )
 
 // 类加载器
+// 启动类加载器从classpath读取class文件 用户类加载器对应一个java.lang.ClassLoader实例
+// classMap记录以该加载器为初始加载器的所有类 所以类实际上由(定义加载器, 类名)确定
 type ClassLoader struct {
 	cp       *classpath.Classpath
+	heap     *Heap
 	verboseFlag bool
+	eagerLink bool // -Xlink:eager 链接时就解析常量池里的所有符号引用
 	classMap map[string]*Class
+	bootstrap *ClassLoader // 启动类加载器 对于启动类加载器自身是nil
+	jLoader  *Object // java.lang.ClassLoader实例
+	stats    *loadStats // -verbose:class的统计信息 只有启动类加载器有
+	share    *SharedArchive // -Xshare 只有启动类加载器有
 }
 
 // 1 首先找到 class 文件然后把数据读取到内存中
@@ -18,18 +26,30 @@ type ClassLoader struct {
 // 3 进行链接
 
 // 构造函数
-func NewClassLoader(cp *classpath.Classpath, verboseFlag bool) *ClassLoader {
+func NewClassLoader(cp *classpath.Classpath, heap *Heap, verboseFlag, eagerLink bool,
+	share *SharedArchive) *ClassLoader {
 	loader := &ClassLoader{
 		cp:       cp,
+		heap:     heap,
 		verboseFlag: verboseFlag,
+		eagerLink: eagerLink,
 		classMap: make(map[string]*Class),
+		stats:    newLoadStats(),
+		share:    share,
 	}
 
+	if heap.bootstrap == nil {
+		heap.bootstrap = loader
+	}
 	loader.loadBasicClasses()
 	loader.loadPrimitiveClasses()
 	return loader
 }
 
+func (self *ClassLoader) Heap() *Heap {
+	return self.heap
+}
+
 func (self *ClassLoader) loadBasicClasses() {
 	jlClassClass := self.LoadClass("java/lang/Class")
 	for _, class := range self.classMap {
@@ -52,7 +72,7 @@ func (self *ClassLoader) loadPrimitiveClass(className string) {
 		accessFlags: ACC_PUBLIC,
 		name: className,
 		loader: self,
-		initStarted: true,
+		initState: CLASS_INITIALIZED,
 	}
 	class.jClass = self.classMap["java/lang/Class"].NewObject()
 	class.jClass.extra = class
@@ -65,20 +85,35 @@ func (self *ClassLoader) LoadClass(name string) (class *Class) {
 		return class
 	}
 
+	if self.jLoader != nil {
+		return self.loadClassByDelegation(name)
+	}
+
 	if name[0] == '[' { // 判断是否是数组类
 		class = self.loadArrayClass(name)
 	} else {
 		class = self.loadNonArrayClass(name)
 	}
+	self.createJClass(class)
+	return
+}
 
-	// 任意一个class加载时都会关联java.lang.Class的一个实例
-	// 使之jClass为java.lang.Class的一个实例
-	// 使之jClass.extra 为其自身
-	if jlClassClass, ok := self.classMap["java/lang/Class"]; ok {
-		class.jClass = jlClassClass.NewObject()
-		class.jClass.extra = class
+// 任意一个class加载时都会关联java.lang.Class的一个实例
+// 使之jClass为java.lang.Class的一个实例
+// 使之jClass.extra 为其自身
+func (self *ClassLoader) createJClass(class *Class) {
+	jlClassClass, ok := self.Bootstrap().classMap["java/lang/Class"]
+	if !ok {
+		return
+	}
+	class.jClass = jlClassClass.NewObject()
+	class.jClass.extra = class
+	if self.jLoader != nil {
+		// Class.getClassLoader0()读取这个字段
+		if field := jlClassClass.getField("classLoader", "Ljava/lang/ClassLoader;", false); field != nil {
+			class.jClass.SetRefField(field.slotId, self.jLoader)
+		}
 	}
-	return
 }
 
 func (self *ClassLoader) loadArrayClass(name string) *Class {
@@ -87,27 +122,64 @@ func (self *ClassLoader) loadArrayClass(name string) *Class {
 		accessFlags: ACC_PUBLIC,
 		name: name,
 		loader: self,
-		initStarted: true, // 数组类不需要初始化
+		initState: CLASS_INITIALIZED, // 数组类不需要初始化
 		superClass: self.LoadClass("java/lang/Object"),
 		interfaces: []*Class{ // 实现了 以下两个类
 			self.LoadClass("java/lang/Cloneable"),
 			self.LoadClass("java/io/Serializable"),
 		},
 	}
+	class.vtable = class.superClass.vtable // 数组类没有自己的方法
 	self.classMap[name] = class
 	return class
 }
 
 func (self *ClassLoader) loadNonArrayClass(name string) *Class {
-	data, entry := self.readClass(name) // 读取类信息
-	class := self.defineClass(data) // 解析类信息
-	link(class)
+	var class *Class
+	var entry classpath.Entry
+	self.timeLoad(func() {
+		if class = self.share.lookup(name); class != nil {
+			return // 直接使用归档中解析好的类
+		}
+		var data []byte
+		data, entry = self.readClass(name) // 读取类信息
+		class = parseClass(data) // 解析类信息
+	})
+	self.registerClass(class) // 加载超类和接口
+	self.link(class)
+	if class.shared {
+		if self.verboseFlag {
+			fmt.Printf("[Loaded %s from shared objects file]\n", name)
+		}
+		return class
+	}
+	self.share.record(class, entry)
 	if self.verboseFlag {
 		fmt.Printf("[Loaded %s from %s]\n", name, entry)
 	}
 	return class
 }
 
+// 启动类加载器找不到类时返回nil 而不是抛出异常
+func (self *ClassLoader) FindBootstrapClass(name string) *Class {
+	bootstrap := self.Bootstrap()
+	if class, ok := bootstrap.classMap[name]; ok {
+		return class
+	}
+	if name[0] != '[' {
+		if bootstrap.share.contains(name) {
+			return bootstrap.LoadClass(name)
+		}
+		if _, _, err := bootstrap.cp.ReadClass(name); err != nil {
+			return nil
+		}
+	} else if component := getComponentClassName(name); primitiveTypes[component] == "" &&
+		bootstrap.FindBootstrapClass(component) == nil {
+		return nil
+	}
+	return bootstrap.LoadClass(name)
+}
+
 func (self *ClassLoader) readClass(name string) ([]byte, classpath.Entry) {
 	data, entry, err := self.cp.ReadClass(name)
 	if err != nil {
@@ -116,14 +188,14 @@ func (self *ClassLoader) readClass(name string) ([]byte, classpath.Entry) {
 	return data, entry
 }
 
-func (self *ClassLoader) defineClass(data []byte) *Class {
-	class := parseClass(data)
+func (self *ClassLoader) registerClass(class *Class) {
 	hackClass(class)
 	class.loader = self // 绑定加载器
 	resolveSuperClass(class)
 	resolveInterfaces(class)
+	class.refKind = referenceKind(class)
+	class.finalizable = hasFinalizer(class)
 	self.classMap[class.name] = class // 注册登记
-	return class
 }
 
 func parseClass(data []byte) *Class {
@@ -152,9 +224,15 @@ func resolveInterfaces(class *Class) {
 	}
 }
 
-func link(class *Class) {
-	verify(class)
-	prepare(class)
+func (self *ClassLoader) link(class *Class) {
+	self.countClass()
+	self.timeLink(func() {
+		verify(class)
+		prepare(class)
+	})
+	if self.eagerLink {
+		self.timeResolve(class.constantPool.resolveAll)
+	}
 }
 
 func verify(class *Class) {
@@ -162,27 +240,54 @@ func verify(class *Class) {
 }
 
 func prepare(class *Class) {
-	calcInstanceFieldSlotIds(class)
-	calcStaticFieldSlotIds(class)
+	if !class.shared {
+		calcInstanceFieldSlotIds(class)
+		calcStaticFieldSlotIds(class)
+	}
+	buildMethodTables(class)
 	allocAndInitStaticVars(class)
 }
 
-// calculate how many Instantce vars do we need
+// 计算实例字段在对象里的位置 父类的字段排在前面
+// 引用类型的字段slotId是在Object.refs里的下标
+// 其他字段slotId是在Object.prims里的字节偏移 long和double占8字节并且按8字节对齐 其余都占4字节
+// 先放long和double 如果偏移不是8的倍数 就先放一个4字节的字段补齐 没有的话留4字节空隙
 func calcInstanceFieldSlotIds(class *Class) {
-	slotId := uint(0)
+	primBytes, refCount := uint(0), uint(0)
 	if class.superClass != nil {
-		slotId = class.superClass.instanceSlotCount
+		primBytes = class.superClass.instancePrimBytes
+		refCount = class.superClass.instanceRefCount
 	}
+	var wides, narrows []*Field
 	for _, field := range class.fields {
-		if !field.IsStatic() {
-			field.slotId = slotId
-			slotId++
-			if field.isLongOrDouble() {
-				slotId++
-			}
+		switch {
+		case field.IsStatic():
+		case isRefField(field):
+			field.slotId = refCount
+			refCount++
+		case field.isLongOrDouble():
+			wides = append(wides, field)
+		default:
+			narrows = append(narrows, field)
+		}
+	}
+	if len(wides) > 0 && primBytes%8 != 0 {
+		if len(narrows) > 0 {
+			narrows[0].slotId = primBytes
+			narrows = narrows[1:]
 		}
+		primBytes += 4
 	}
-	class.instanceSlotCount = slotId // how many slot we need
+	for _, field := range wides {
+		field.slotId = primBytes
+		primBytes += 8
+	}
+	for _, field := range narrows {
+		field.slotId = primBytes
+		primBytes += 4
+	}
+	class.instancePrimBytes = primBytes
+	class.instanceRefCount = refCount
 }
 
 // calculate how many STATIC vars do we need
@@ -231,7 +336,7 @@ func initStaticFinalVar(class *Class, field *Field) {
 			vars.SetDouble(slotId, val)
 		case "Ljava/lang/String;":
 			goStr := cp.GetConstant(cpIndex).(string)
-			jStr := JString(class.loader, goStr)
+			jStr := InternedJString(class.loader, goStr)
 			vars.SetRef(slotId, jStr)
 		}
 	}
@@ -244,4 +349,11 @@ func hackClass(class *Class) {
 			"(Ljava/lang/Class;Ljava/lang/String;Z)V")
 		loadLibrary.code = []byte{0xb1} // return void
 	}
+	if class.name == "java/lang/ref/Reference" {
+		referent := class.getField("referent", "Ljava/lang/Object;", false)
+		referent.referent = true
+	}
+	if class.name == "java/lang/String" {
+		class.stringFields = newStringFields(class)
+	}
 }
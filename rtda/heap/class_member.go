@@ -79,10 +79,10 @@ func (self *ClassMember) isAccessibleTo(d *Class) bool {
 	c := self.class
 	if self.IsProtected() {
 		return d == c || d.IsSubClassOf(c) ||
-			c.GetPackageName() == d.GetPackageName()
+			c.isSameRuntimePackage(d)
 	}
 	if !self.IsPrivate() {
-		return c.GetPackageName() == d.GetPackageName()
+		return c.isSameRuntimePackage(d)
 	}
 	return d == c
 }
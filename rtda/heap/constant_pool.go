@@ -66,3 +66,19 @@ func (self *ConstantPool) GetConstant(index uint) Constant {
 	}
 	panic(fmt.Sprintf("No constants at index %d", index))
 }
+
+// -Xlink:eager 链接时解析所有的类、字段和方法符号引用 尽早暴露链接错误
+func (self *ConstantPool) resolveAll() {
+	for _, c := range self.consts {
+		switch ref := c.(type) {
+		case *ClassRef:
+			ref.ResolvedClass()
+		case *FieldRef:
+			ref.ResolvedField()
+		case *MethodRef:
+			ref.ResolvedMethod()
+		case *InterfaceMethodRef:
+			ref.ResolvedInterfaceMethod()
+		}
+	}
+}
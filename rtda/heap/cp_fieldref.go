@@ -60,3 +60,8 @@ func lookupField(c *Class, name, descriptor string) *Field {
 	}
 	return nil
 }
+
+// 已经解析好的字段 还没有解析时返回nil 不会触发解析
+func (self *FieldRef) Field() *Field {
+	return self.field
+}
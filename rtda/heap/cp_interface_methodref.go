@@ -40,12 +40,20 @@ func (self *InterfaceMethodRef) resolveInterfaceMethodRef() {
 	self.method = method
 }
 
-// TODO
+// 接口自己声明的方法 然后是Object的公有实例方法 最后是超接口中的方法
 func lookupInterfaceMethod(iface *Class, name, descriptor string) *Method {
 	for _, method := range iface.methods {
 		if method.name == name && method.descriptor == descriptor {
 			return method
 		}
 	}
-	return lookupMethodInInterfaces(iface.interfaces, name, descriptor)
+	if object := iface.superClass; object != nil {
+		for _, method := range object.methods {
+			if method.name == name && method.descriptor == descriptor &&
+				method.IsPublic() && !method.IsStatic() {
+				return method
+			}
+		}
+	}
+	return lookupMethodInInterfaces(iface, name, descriptor)
 }
@@ -6,6 +6,7 @@ type Field struct {
 	ClassMember
 	constValueIndex uint
 	slotId uint
+	referent bool // Reference.referent 由虚拟机弱引用
 }
 
 //  classfile.MemberInfo 转换为 Fileds
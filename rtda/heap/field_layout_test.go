@@ -0,0 +1,113 @@
+package heap
+
+import (
+	"fmt"
+	"testing"
+)
+
+// 按描述符给类加上实例字段并计算布局 名字是类名加下标
+func newLayoutTestClass(name string, super *Class, descriptors ...string) *Class {
+	class := &Class{name: name, superClass: super}
+	for i, descriptor := range descriptors {
+		field := &Field{}
+		field.class = class
+		field.name = fmt.Sprintf("%s%d", name, i)
+		field.descriptor = descriptor
+		class.fields = append(class.fields, field)
+	}
+	calcInstanceFieldSlotIds(class)
+	return class
+}
+
+// 所有实例字段(包括超类的)都在对象里 互不重叠 long和double按8字节对齐
+func checkFieldLayout(t *testing.T, class *Class) {
+	prims := make([]*Field, class.instancePrimBytes)
+	refs := make([]*Field, class.instanceRefCount)
+	for c := class; c != nil; c = c.superClass {
+		for _, field := range c.fields {
+			if isRefField(field) {
+				if field.slotId >= uint(len(refs)) || refs[field.slotId] != nil {
+					t.Errorf("%s: ref field %s at %d overlaps or is out of range", class.name, field.name, field.slotId)
+					continue
+				}
+				refs[field.slotId] = field
+				continue
+			}
+			size := uint(4)
+			if field.isLongOrDouble() {
+				size = 8
+				if field.slotId%8 != 0 {
+					t.Errorf("%s: %s %s at offset %d is not 8-aligned", class.name, field.descriptor, field.name, field.slotId)
+				}
+			}
+			if field.slotId+size > uint(len(prims)) {
+				t.Errorf("%s: %s at %d+%d is beyond %d bytes", class.name, field.name, field.slotId, size, len(prims))
+				continue
+			}
+			for i := field.slotId; i < field.slotId+size; i++ {
+				if prims[i] != nil {
+					t.Errorf("%s: %s at %d overlaps %s", class.name, field.name, field.slotId, prims[i].name)
+					break
+				}
+				prims[i] = field
+			}
+		}
+	}
+}
+
+func fieldOffsets(class *Class) map[string]uint {
+	offsets := map[string]uint{}
+	for _, field := range class.fields {
+		offsets[field.name] = field.slotId
+	}
+	return offsets
+}
+
+func TestFieldLayout(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		super, sub   []string
+		wantPrims    uint
+		wantFirstPad bool // 子类的第一个4字节字段补在超类字段后面的空隙里
+	}{
+		{"odd ints then longs", []string{"I", "I", "I"}, []string{"J", "I", "D", "Z", "Ljava/lang/Object;"}, 36, true},
+		{"odd ints then only longs", []string{"I"}, []string{"J", "D"}, 24, false},
+		{"even ints then longs", []string{"I", "C"}, []string{"J", "B"}, 20, false},
+		{"longs then odd ints", []string{"J", "I"}, []string{"D", "S", "[I"}, 24, true},
+		{"no super fields", nil, []string{"Z", "J", "F"}, 16, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			super := newLayoutTestClass("Super", &Class{name: "java/lang/Object"}, c.super...)
+			superOffsets := fieldOffsets(super)
+			superPrims := super.instancePrimBytes
+			sub := newLayoutTestClass("Sub", super, c.sub...)
+			leaf := newLayoutTestClass("Leaf", sub, "J", "I", "Ljava/lang/String;")
+
+			for _, class := range []*Class{super, sub, leaf} {
+				checkFieldLayout(t, class)
+			}
+			// 子类不会移动超类字段 超类的对象和子类的对象按同样的偏移访问这些字段
+			for name, offset := range fieldOffsets(super) {
+				if offset != superOffsets[name] {
+					t.Errorf("super field %s moved from %d to %d", name, superOffsets[name], offset)
+				}
+			}
+			if super.instancePrimBytes != superPrims || sub.instancePrimBytes < superPrims {
+				t.Errorf("super %d bytes, sub %d bytes", super.instancePrimBytes, sub.instancePrimBytes)
+			}
+			if sub.instancePrimBytes != c.wantPrims {
+				t.Errorf("sub has %d bytes of primitive fields, want %d", sub.instancePrimBytes, c.wantPrims)
+			}
+			if c.wantFirstPad {
+				for _, field := range sub.fields {
+					if !field.isLongOrDouble() && !isRefField(field) {
+						if field.slotId != superPrims {
+							t.Errorf("first narrow field %s at %d, want it in the gap at %d", field.name, field.slotId, superPrims)
+						}
+						break
+					}
+				}
+			}
+		})
+	}
+}
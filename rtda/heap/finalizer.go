@@ -0,0 +1,113 @@
+package heap
+
+import "sync"
+
+// 重写了finalize()的对象在分配时登记到finalizable里 虚拟机一直强引用它们
+// collect()从GC根遍历堆 遍历不到的登记对象移到pending(对象因此复活)
+// 再由虚拟机的Finalizer线程执行Java的finalize()方法 之后就和普通对象一样由Go回收
+// 可达性由虚拟机自己判断 所以循环引用的对象(比如FileInputStream和它的FileDescriptor)也会被finalize
+type finalizerQueue struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	finalizable []*Object
+	pending     []*Object
+	running     int // 已经取出但是finalize()还没有执行完的对象
+}
+
+func newFinalizerQueue() *finalizerQueue {
+	queue := &finalizerQueue{}
+	queue.cond = sync.NewCond(&queue.mu)
+	return queue
+}
+
+// 空的finalize()和Object.finalize()一样 不需要执行
+func hasFinalizer(class *Class) bool {
+	method := LookupMethodInClass(class, "finalize", "()V")
+	if method == nil || method.class.isJlObject() {
+		return false
+	}
+	return !(len(method.code) == 1 && method.code[0] == 0xb1) // return
+}
+
+func (self *finalizerQueue) register(obj *Object) {
+	self.mu.Lock()
+	self.finalizable = append(self.finalizable, obj)
+	self.mu.Unlock()
+}
+
+func (self *finalizerQueue) registered() int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return len(self.finalizable)
+}
+
+// 还没有执行finalize()的对象 它们是GC根
+func (self *finalizerQueue) queued() []*Object {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return append([]*Object{}, self.pending...)
+}
+
+// 把walker遍历不到的登记对象移到pending 再从它们出发继续遍历
+// 复活的对象引用的对象也要算作存活
+func (self *finalizerQueue) enqueueUnreachable(walker *heapWalker) int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	reachable := self.finalizable[:0]
+	var unreachable []*Object
+	for _, obj := range self.finalizable {
+		if walker.visited[obj] {
+			reachable = append(reachable, obj)
+		} else {
+			unreachable = append(unreachable, obj)
+		}
+	}
+	clear(self.finalizable[len(reachable):])
+	self.finalizable = reachable
+	if len(unreachable) == 0 {
+		return 0
+	}
+	for _, obj := range unreachable {
+		walker.mark(obj)
+	}
+	walker.drain()
+	self.pending = append(self.pending, unreachable...)
+	self.cond.Broadcast()
+	return len(unreachable)
+}
+
+// 阻塞直到有对象需要finalize 不需要持有全局解释器锁
+func (self *Heap) WaitFinalizable() *Object {
+	queue := self.finalizers
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	for len(queue.pending) == 0 {
+		queue.cond.Wait()
+	}
+	obj := queue.pending[0]
+	queue.pending[0] = nil
+	queue.pending = queue.pending[1:]
+	queue.running++
+	return obj
+}
+
+// WaitFinalizable()返回的对象finalize()执行完毕(包括抛出异常)
+func (self *Heap) Finalized() {
+	queue := self.finalizers
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	queue.running--
+	queue.cond.Broadcast()
+}
+
+// Runtime.runFinalization()
+// 调用者先持有全局解释器锁调用GC() 把不可达的对象放进队列 再释放锁调用这里等待队列清空
+// 否则Finalizer线程无法执行
+func (self *Heap) RunFinalization() {
+	queue := self.finalizers
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	for len(queue.pending) > 0 || queue.running > 0 {
+		queue.cond.Wait()
+	}
+}
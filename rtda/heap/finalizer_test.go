@@ -0,0 +1,95 @@
+package heap
+
+import "testing"
+
+// 不经过类加载器 搭一个重写了finalize()的类Node 它有一个字段next
+func newFinalizerTestHeap() (*Heap, *Class) {
+	heap := NewHeap(0)
+	loader := &ClassLoader{heap: heap, classMap: map[string]*Class{}}
+	heap.bootstrap = loader
+	object := &Class{name: "java/lang/Object", loader: loader}
+	node := &Class{name: "Node", superClass: object, loader: loader, finalizable: true}
+	next := &Field{}
+	next.class = node
+	next.name = "next"
+	next.descriptor = "LNode;"
+	node.fields = []*Field{next}
+	calcInstanceFieldSlotIds(node)
+	return heap, node
+}
+
+// 线程栈上只有一个引用ref
+func testThreadRoots(class *Class, ref *Object) func() []ThreadRoots {
+	method := &Method{}
+	method.class = class
+	return func() []ThreadRoots {
+		return []ThreadRoots{{Frames: []FrameRoots{{Method: method, Refs: []*Object{ref}}}}}
+	}
+}
+
+func TestFinalizeSelfReference(t *testing.T) {
+	heap, node := newFinalizerTestHeap()
+	live := node.NewObject()
+	live.SetRefField(0, live)
+	heap.SetThreadRoots(testThreadRoots(node, live))
+	func() {
+		cyclic := node.NewObject()
+		cyclic.SetRefField(0, cyclic)
+	}()
+
+	heap.GC()
+	queued := heap.finalizers.queued()
+	if len(queued) != 1 || queued[0] == live || queued[0].GetRefField(0) != queued[0] {
+		t.Fatalf("queued %v, want only the unreachable self-referencing object", queued)
+	}
+	if n := heap.finalizers.registered(); n != 1 {
+		t.Fatalf("%d objects still registered, want 1", n)
+	}
+	if obj := heap.WaitFinalizable(); obj != queued[0] {
+		t.Fatalf("WaitFinalizable returned %p, want %p", obj, queued[0])
+	}
+	heap.Finalized()
+
+	// finalize()只执行一次 复活的对象再次不可达时不会重新入队
+	heap.GC()
+	if queued := heap.finalizers.queued(); len(queued) != 0 {
+		t.Fatalf("queued %d objects after the second GC, want 0", len(queued))
+	}
+	heap.RunFinalization()
+}
+
+// 两个互相引用的对象 和FileInputStream与它的FileDescriptor一样
+// 等待finalize的对象是GC根 它引用的对象还算存活
+func TestFinalizeCycle(t *testing.T) {
+	heap, node := newFinalizerTestHeap()
+	heap.SetThreadRoots(testThreadRoots(node, nil))
+	func() {
+		a, b := node.NewObject(), node.NewObject()
+		a.SetRefField(0, b)
+		b.SetRefField(0, a)
+	}()
+
+	heap.GC()
+	if queued := heap.finalizers.queued(); len(queued) != 2 {
+		t.Fatalf("queued %d objects, want both objects of the cycle", len(queued))
+	}
+	if heap.liveBytes != 2*instanceSize(node) {
+		t.Fatalf("%d live bytes, want the two queued objects (%d)", heap.liveBytes, 2*instanceSize(node))
+	}
+}
+
+// 没有调用System.gc() 分配足够多以后也会找出不可达的对象
+func TestFinalizeAfterAllocation(t *testing.T) {
+	heap, node := newFinalizerTestHeap()
+	heap.SetThreadRoots(testThreadRoots(node, nil))
+	node.NewObject()
+
+	heap.maybeCollect(collectBytes / 2)
+	if queued := heap.finalizers.queued(); len(queued) != 0 {
+		t.Fatalf("collected after %d bytes", collectBytes/2)
+	}
+	heap.maybeCollect(collectBytes)
+	if queued := heap.finalizers.queued(); len(queued) != 1 {
+		t.Fatalf("queued %d objects after %d bytes, want 1", len(queued), collectBytes*3/2)
+	}
+}
@@ -0,0 +1,185 @@
+package heap
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// 对象本身仍然由Go分配和回收 这里只是近似地记录Java堆用了多少字节
+// 和分代GC一样只在回收时才知道哪些对象死了: 已用的字节数是上次collect()时可达对象的大小加上之后分配的
+// 不给每个对象注册cleanup 那样每个对象要多分配三次、多用48字节 分配慢五倍左右(见BenchmarkNewObject)
+// 超出-Xmx时先collect() 仍然不够就抛出OutOfMemoryError
+type Heap struct {
+	maxBytes    int64        // -Xmx
+	usedBytes   atomic.Int64 // 所有存活对象加上上次collect()之后分配的对象的近似大小
+	reserved    bool         // 正在构造OutOfMemoryError 可以临时透支
+	gcCount     atomic.Int64
+	refs        *referenceQueue
+	finalizers  *finalizerQueue
+	strings     *stringTable // 字符串池 见string_pool.go
+	outOfMemory func()       // 第一次抛出OutOfMemoryError之前调用 见-XX:+HeapDumpOnOutOfMemoryError
+
+	bootstrap    *ClassLoader         // 启动类加载器 遍历堆的起点
+	threadRoots  func() []ThreadRoots // 所有线程栈上的GC根 由虚拟机设置
+	sinceCollect atomic.Int64         // 上次collect()之后分配的字节数
+	liveBytes    int64                // 上次collect()时可达对象的大小
+}
+
+const (
+	DefaultMaxHeap = 1 << 30  // 没有指定-Xmx时最多使用1G
+	reservedBytes  = 1 << 20  // 抛出OutOfMemoryError时可以透支的字节数
+	collectBytes   = 16 << 20 // 至少分配这么多字节之后才再次collect()
+
+	objectHeaderSize = int64(unsafe.Sizeof(Object{}))
+	refSize          = int64(unsafe.Sizeof(uintptr(0)))
+)
+
+func NewHeap(maxBytes int64) *Heap {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxHeap
+	}
+	return &Heap{
+		maxBytes:   maxBytes,
+		refs:       newReferenceQueue(),
+		finalizers: newFinalizerQueue(),
+		strings:    newStringTable(),
+	}
+}
+
+// 没有设置时只有对象的记账 不会finalize对象
+func (self *Heap) SetThreadRoots(roots func() []ThreadRoots) {
+	self.threadRoots = roots
+}
+
+func (self *Heap) OnOutOfMemory(hook func()) {
+	self.outOfMemory = hook
+}
+
+func (self *Heap) MaxMemory() int64 {
+	return self.maxBytes
+}
+
+func (self *Heap) UsedMemory() int64 {
+	return self.usedBytes.Load()
+}
+
+// 已经向操作系统申请的内存 至少和已用内存一样多
+func (self *Heap) TotalMemory() int64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	total := int64(stats.HeapSys)
+	if used := self.UsedMemory(); total < used {
+		total = used
+	}
+	if total > self.maxBytes {
+		total = self.maxBytes
+	}
+	return total
+}
+
+func (self *Heap) FreeMemory() int64 {
+	free := self.TotalMemory() - self.UsedMemory()
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// System.gc() / Runtime.gc() 调用者持有全局解释器锁
+// 先让Go回收 被清除的软引用和弱引用的referent就不会再被遍历到
+func (self *Heap) GC() {
+	runtime.GC()
+	self.collect()
+	self.gcCount.Add(1)
+}
+
+// 从GC根遍历堆 把不可达的需要finalize的对象交给Finalizer线程 并重新计算已用的字节数
+// 只被Go代码的局部变量引用的对象(比如本地方法正在填充的数组)遍历不到 会少算
+// 调用者持有全局解释器锁 其他线程不会同时修改对象
+func (self *Heap) collect() {
+	self.sinceCollect.Store(0)
+	if self.bootstrap == nil || self.threadRoots == nil {
+		return
+	}
+	walker := walkHeap(self.bootstrap, self.threadRoots())
+	self.finalizers.enqueueUnreachable(walker)
+	live := int64(0)
+	for _, obj := range walker.objects {
+		live += obj.size()
+	}
+	self.liveBytes = live
+	self.usedBytes.Store(live)
+}
+
+// 有需要finalize的对象时 分配的字节数超过上次存活的大小(至少collectBytes)就collect()一次
+// 和分代GC的新生代满了就回收差不多 否则不可达的对象要等到内存不足才会被finalize
+func (self *Heap) maybeCollect(size int64) {
+	if self.sinceCollect.Add(size) > max(self.liveBytes, collectBytes) && self.finalizers.registered() > 0 {
+		self.collect()
+	}
+}
+
+// 分配size字节之前先记账
+// 超出-Xmx时先GC()重新计算已用的字节数 再清除软引用GC()一次 仍然不够就抛出OutOfMemoryError
+func (self *Heap) charge(size int64) {
+	if self.tryCharge(size, self.maxBytes) {
+		self.reserved = false
+		self.maybeCollect(size)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			self.refs.clearSofts() // 软引用的referent只在内存不足时回收
+		}
+		self.GC()
+		if self.tryCharge(size, self.maxBytes) {
+			self.reserved = false
+			return
+		}
+	}
+
+	if !self.reserved {
+		if hook := self.outOfMemory; hook != nil {
+			self.outOfMemory = nil
+			hook()
+		}
+		self.reserved = true
+		panic(NewJavaException("java/lang/OutOfMemoryError", "Java heap space"))
+	}
+	if !self.tryCharge(size, self.maxBytes+reservedBytes) {
+		// 连构造OutOfMemoryError的内存都没有了
+		panic("java.lang.OutOfMemoryError: Java heap space")
+	}
+}
+
+func (self *Heap) tryCharge(size, limit int64) bool {
+	for {
+		used := self.usedBytes.Load()
+		if size < 0 || used+size > limit || used+size < used {
+			return false
+		}
+		if self.usedBytes.CompareAndSwap(used, used+size) {
+			return true
+		}
+	}
+}
+
+// 分配对象 调用者已经记过账
+// 类重写了finalize()时同时注册finalizer
+func (self *Heap) newObject(class *Class, prims []byte, refs []*Object) *Object {
+	obj := &Object{class: class, prims: prims, refs: refs}
+	if class.finalizable {
+		self.finalizers.register(obj)
+	}
+	return obj
+}
+
+func instanceSize(class *Class) int64 {
+	return objectHeaderSize + int64(class.instancePrimBytes) + int64(class.instanceRefCount)*refSize
+}
+
+// 数组对象的大小 count个元素 每个元素elementSize字节
+func arraySize(count uint, elementSize int64) int64 {
+	return objectHeaderSize + int64(count)*elementSize
+}
@@ -0,0 +1,352 @@
+package heap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+	"unsafe"
+)
+
+// HPROF的记录和子记录 见HotSpot的heapDumper.cpp
+const (
+	hprofUtf8            = 0x01
+	hprofLoadClass       = 0x02
+	hprofFrame           = 0x04
+	hprofTrace           = 0x05
+	hprofHeapDumpSegment = 0x1c
+	hprofHeapDumpEnd     = 0x2c
+
+	hprofRootUnknown     = 0xff
+	hprofRootJavaFrame   = 0x03
+	hprofRootStickyClass = 0x05
+	hprofRootMonitorUsed = 0x07
+	hprofRootThreadObj   = 0x08
+	hprofClassDump       = 0x20
+	hprofInstanceDump    = 0x21
+	hprofObjArrayDump    = 0x22
+	hprofPrimArrayDump   = 0x23
+)
+
+// 字段和数组元素的类型
+var hprofTypes = map[byte]byte{
+	'L': 2, '[': 2, 'Z': 4, 'C': 5, 'F': 6, 'D': 7, 'B': 8, 'S': 9, 'I': 10, 'J': 11,
+}
+
+var hprofTypeSizes = map[byte]int{
+	'L': 8, '[': 8, 'Z': 1, 'C': 2, 'F': 4, 'D': 8, 'B': 1, 'S': 2, 'I': 4, 'J': 8,
+}
+
+const (
+	hprofIDSize       = 8
+	hprofDummyTrace   = 1       // 对象分配的位置未知 都使用这个空的调用栈
+	hprofSegmentLimit = 1 << 24 // 子记录攒够这么多字节就写出一个HEAP DUMP SEGMENT
+)
+
+// 所有可达的对象以HPROF格式写出
+// 调用者必须持有全局解释器锁 其他线程都停在安全的位置
+func (self *ClassLoader) DumpHeap(w io.Writer, threads []ThreadRoots) error {
+	dumper := &heapDumper{
+		heapWalker: walkHeap(self.Bootstrap(), threads),
+		out:        bufio.NewWriter(w),
+		start:      time.Now(),
+		names:      map[string]uint64{},
+		serials:    map[*Class]uint32{},
+	}
+	dumper.writeHeader()
+	dumper.writeClasses()
+	dumper.writeTraces(threads)
+	dumper.writeRoots(self.Bootstrap(), threads)
+	for _, obj := range dumper.objects {
+		dumper.writeObject(obj)
+	}
+	dumper.flushSegment()
+	dumper.writeRecord(hprofHeapDumpEnd, nil)
+	if dumper.err != nil {
+		return dumper.err
+	}
+	return dumper.out.Flush()
+}
+
+type heapDumper struct {
+	*heapWalker
+	out     *bufio.Writer
+	err     error
+	start   time.Time
+	names   map[string]uint64 // UTF8记录的ID
+	serials map[*Class]uint32 // LOAD CLASS记录的序号
+	nextID  uint64
+	segment bytes.Buffer
+}
+
+func objectID(obj *Object) uint64 {
+	return uint64(uintptr(unsafe.Pointer(obj)))
+}
+
+// "JAVA PROFILE 1.0.2" ID的字节数 开始的时间
+func (self *heapDumper) writeHeader() {
+	self.write([]byte("JAVA PROFILE 1.0.2\x00"))
+	self.write(u4(hprofIDSize))
+	self.write(u8(uint64(self.start.UnixMilli())))
+}
+
+func (self *heapDumper) writeRecord(tag byte, body []byte) {
+	self.write([]byte{tag})
+	self.write(u4(uint32(time.Since(self.start).Microseconds())))
+	self.write(u4(uint32(len(body))))
+	self.write(body)
+}
+
+func (self *heapDumper) write(data []byte) {
+	if self.err == nil {
+		_, self.err = self.out.Write(data)
+	}
+}
+
+// 字符串第一次使用时写出UTF8记录
+func (self *heapDumper) name(s string) uint64 {
+	if id, ok := self.names[s]; ok {
+		return id
+	}
+	self.nextID++
+	id := self.nextID
+	self.names[s] = id
+	self.writeRecord(hprofUtf8, append(u8(id), s...))
+	return id
+}
+
+// 每个类一条LOAD CLASS 以及字段名的UTF8记录
+func (self *heapDumper) writeClasses() {
+	for i, class := range self.classes {
+		serial := uint32(i + 1)
+		self.serials[class] = serial
+		body := append(u4(serial), u8(objectID(class.jClass))...)
+		body = append(body, u4(hprofDummyTrace)...)
+		body = append(body, u8(self.name(class.name))...)
+		self.writeRecord(hprofLoadClass, body)
+		for _, field := range class.fields {
+			self.name(field.name)
+		}
+	}
+}
+
+// 每个线程一个调用栈 序号从2开始
+func (self *heapDumper) writeTraces(threads []ThreadRoots) {
+	self.writeRecord(hprofTrace, append(append(u4(hprofDummyTrace), u4(0)...), u4(0)...))
+	for i, thread := range threads {
+		var frameIDs []byte
+		for _, frame := range thread.Frames {
+			method := frame.Method
+			line := method.GetLineNumber(frame.PC)
+			if line == -2 {
+				line = -3 // 本地方法
+			}
+			self.nextID++
+			frameIDs = append(frameIDs, u8(self.nextID)...)
+			body := u8(self.nextID)
+			body = append(body, u8(self.name(method.name))...)
+			body = append(body, u8(self.name(method.descriptor))...)
+			body = append(body, u8(self.name(method.class.sourceFile))...)
+			body = append(body, u4(self.serials[method.class])...)
+			body = append(body, u4(uint32(int32(line)))...)
+			self.writeRecord(hprofFrame, body)
+		}
+		body := append(u4(uint32(i+2)), u4(uint32(i+1))...)
+		body = append(body, u4(uint32(len(thread.Frames)))...)
+		self.writeRecord(hprofTrace, append(body, frameIDs...))
+	}
+}
+
+func (self *heapDumper) writeRoots(bootstrap *ClassLoader, threads []ThreadRoots) {
+	for _, class := range self.classes {
+		if class.loader == bootstrap {
+			self.subRecord(hprofRootStickyClass, u8(objectID(class.jClass)))
+		}
+	}
+	for i, thread := range threads {
+		serial := u4(uint32(i + 1))
+		if thread.JThread != nil {
+			self.subRecord(hprofRootThreadObj, u8(objectID(thread.JThread)), serial, u4(uint32(i+2)))
+		}
+		for depth, frame := range thread.Frames {
+			for _, ref := range frame.Refs {
+				self.subRecord(hprofRootJavaFrame, u8(objectID(ref)), serial, u4(uint32(depth)))
+			}
+			for _, obj := range frame.Locked {
+				self.subRecord(hprofRootMonitorUsed, u8(objectID(obj)))
+			}
+		}
+	}
+	for _, str := range self.interns {
+		self.subRecord(hprofRootUnknown, u8(objectID(str)))
+	}
+	for _, obj := range self.finalizing {
+		self.subRecord(hprofRootUnknown, u8(objectID(obj)))
+	}
+}
+
+func (self *heapDumper) writeObject(obj *Object) {
+	if class := mirroredClass(obj); class != nil {
+		self.writeClassDump(class)
+		return
+	}
+	id := u8(objectID(obj))
+	trace := u4(hprofDummyTrace)
+	switch {
+	case obj.class.isRefArray():
+		elements := make([]byte, 0, len(obj.refs)*hprofIDSize)
+		for _, ref := range obj.refs {
+			elements = append(elements, u8(objectID(ref))...)
+		}
+		self.subRecord(hprofObjArrayDump, id, trace, u4(uint32(len(obj.refs))), u8(objectID(obj.class.jClass)), elements)
+	case obj.class.IsArray():
+		elementType := obj.class.name[1]
+		elements := new(bytes.Buffer)
+		binary.Write(elements, binary.BigEndian, primArrayElements(obj, elementType))
+		self.subRecord(hprofPrimArrayDump, id, trace, u4(uint32(obj.ArrayLength())),
+			[]byte{hprofTypes[elementType]}, elements.Bytes())
+	default:
+		values := new(bytes.Buffer)
+		for class := obj.class; class != nil; class = class.superClass {
+			for _, field := range class.fields {
+				if !field.IsStatic() {
+					self.writeInstanceValue(values, obj, field)
+				}
+			}
+		}
+		self.subRecord(hprofInstanceDump, id, trace, u8(objectID(obj.class.jClass)),
+			u4(uint32(values.Len())), values.Bytes())
+	}
+}
+
+// 基本类型数组的元素 binary.Write按大端序写出
+func primArrayElements(arr *Object, elementType byte) interface{} {
+	switch elementType {
+	case 'Z', 'B':
+		return arr.Bytes()
+	case 'C':
+		return arr.Chars()
+	case 'S':
+		return arr.Shorts()
+	case 'I':
+		return arr.Ints()
+	case 'J':
+		return arr.Longs()
+	case 'F':
+		return arr.Floats()
+	default:
+		return arr.Doubles()
+	}
+}
+
+// 实例字段的值
+func (self *heapDumper) writeInstanceValue(buf *bytes.Buffer, obj *Object, field *Field) {
+	switch field.descriptor[0] {
+	case 'Z', 'B':
+		buf.WriteByte(byte(obj.GetIntField(field.slotId)))
+	case 'C', 'S':
+		buf.Write(u2(uint16(obj.GetIntField(field.slotId))))
+	case 'I', 'F':
+		buf.Write(u4(uint32(obj.GetIntField(field.slotId))))
+	case 'J', 'D':
+		buf.Write(u8(uint64(obj.GetLongField(field.slotId))))
+	default:
+		ref := obj.GetRefField(field.slotId)
+		if field.referent {
+			ref = obj.Referent(field)
+		}
+		buf.Write(u8(objectID(ref)))
+	}
+}
+
+// 静态变量的值
+func (self *heapDumper) writeStaticValue(buf *bytes.Buffer, field *Field, slots Slots) {
+	if slots == nil {
+		buf.Write(make([]byte, hprofTypeSizes[field.descriptor[0]]))
+		return
+	}
+	switch field.descriptor[0] {
+	case 'Z', 'B':
+		buf.WriteByte(byte(slots.GetInt(field.slotId)))
+	case 'C', 'S':
+		buf.Write(u2(uint16(slots.GetInt(field.slotId))))
+	case 'I', 'F':
+		buf.Write(u4(uint32(slots.GetInt(field.slotId))))
+	case 'J', 'D':
+		buf.Write(u8(uint64(slots.GetLong(field.slotId))))
+	default:
+		buf.Write(u8(objectID(slots.GetRef(field.slotId))))
+	}
+}
+
+func (self *heapDumper) writeClassDump(class *Class) {
+	var superID, loaderID uint64
+	if class.superClass != nil {
+		superID = objectID(class.superClass.jClass)
+	}
+	loaderID = objectID(class.loader.jLoader)
+
+	var size uint32
+	if class.name[0] != '[' {
+		size = uint32(instanceSize(class))
+	}
+	var statics, instanceFields []*Field
+	for _, field := range class.fields {
+		if field.IsStatic() {
+			statics = append(statics, field)
+		} else {
+			instanceFields = append(instanceFields, field)
+		}
+	}
+
+	body := new(bytes.Buffer)
+	body.Write(u8(objectID(class.jClass)))
+	body.Write(u4(hprofDummyTrace))
+	body.Write(u8(superID))
+	body.Write(u8(loaderID))
+	body.Write(make([]byte, 4*hprofIDSize)) // signers protection domain 两个保留字段
+	body.Write(u4(size))
+	body.Write(u2(0)) // 常量池
+	body.Write(u2(uint16(len(statics))))
+	for _, field := range statics {
+		body.Write(u8(self.names[field.name]))
+		body.WriteByte(hprofTypes[field.descriptor[0]])
+		self.writeStaticValue(body, field, class.staticVars)
+	}
+	body.Write(u2(uint16(len(instanceFields))))
+	for _, field := range instanceFields {
+		body.Write(u8(self.names[field.name]))
+		body.WriteByte(hprofTypes[field.descriptor[0]])
+	}
+	self.subRecord(hprofClassDump, body.Bytes())
+}
+
+func (self *heapDumper) subRecord(tag byte, parts ...[]byte) {
+	self.segment.WriteByte(tag)
+	for _, part := range parts {
+		self.segment.Write(part)
+	}
+	if self.segment.Len() >= hprofSegmentLimit {
+		self.flushSegment()
+	}
+}
+
+func (self *heapDumper) flushSegment() {
+	if self.segment.Len() > 0 {
+		self.writeRecord(hprofHeapDumpSegment, self.segment.Bytes())
+		self.segment.Reset()
+	}
+}
+
+func u2(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+func u4(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func u8(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
@@ -0,0 +1,316 @@
+package heap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"runtime"
+	"testing"
+)
+
+// 按HPROF的类型编号 每种值的字节数
+var hprofValueSizes = map[byte]int{2: 8, 4: 1, 5: 2, 6: 4, 7: 8, 8: 1, 9: 2, 10: 4, 11: 8}
+
+// 转储文件里读出的一个CLASS DUMP
+type dumpedClass struct {
+	super, size  uint64
+	staticNames  []string
+	staticValues []uint64
+	fieldNames   []string
+	fieldTypes   []byte
+}
+
+// 转储文件里读出的一个INSTANCE DUMP
+type dumpedInstance struct {
+	class  uint64
+	values []byte
+}
+
+type hprofReader struct {
+	t         *testing.T
+	buf       *bytes.Reader
+	idSize    uint32
+	names     map[uint64]string
+	classIDs  map[string]uint64
+	classes   map[uint64]*dumpedClass
+	instances map[uint64]*dumpedInstance
+	roots     map[byte][]uint64
+}
+
+func (self *hprofReader) read(n int) []byte {
+	data := make([]byte, n)
+	if _, err := self.buf.Read(data); err != nil && n > 0 {
+		self.t.Fatalf("truncated dump: %v", err)
+	}
+	return data
+}
+
+func (self *hprofReader) u1() byte   { return self.read(1)[0] }
+func (self *hprofReader) u2() uint16 { return binary.BigEndian.Uint16(self.read(2)) }
+func (self *hprofReader) u4() uint32 { return binary.BigEndian.Uint32(self.read(4)) }
+func (self *hprofReader) u8() uint64 { return binary.BigEndian.Uint64(self.read(8)) }
+
+// 按类型的字节数读出一个值
+func (self *hprofReader) value(typ byte) uint64 {
+	data := self.read(hprofValueSizes[typ])
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// 按HotSpot的格式解析DumpHeap的输出 不认识的记录和子记录让测试失败
+func parseHprof(t *testing.T, data []byte) *hprofReader {
+	self := &hprofReader{
+		t:         t,
+		buf:       bytes.NewReader(data),
+		names:     map[uint64]string{},
+		classIDs:  map[string]uint64{},
+		classes:   map[uint64]*dumpedClass{},
+		instances: map[uint64]*dumpedInstance{},
+		roots:     map[byte][]uint64{},
+	}
+	if header := string(self.read(len("JAVA PROFILE 1.0.2\x00"))); header != "JAVA PROFILE 1.0.2\x00" {
+		t.Fatalf("header = %q", header)
+	}
+	if self.idSize = self.u4(); self.idSize != 8 {
+		t.Fatalf("ID size = %d, want 8", self.idSize)
+	}
+	self.u8() // 时间戳
+	for ended := false; !ended; {
+		tag := self.u1()
+		self.u4()
+		body := self.read(int(self.u4()))
+		switch tag {
+		case hprofUtf8:
+			self.names[binary.BigEndian.Uint64(body)] = string(body[8:])
+		case hprofLoadClass:
+			name := self.names[binary.BigEndian.Uint64(body[16:])]
+			self.classIDs[name] = binary.BigEndian.Uint64(body[4:])
+		case hprofFrame, hprofTrace:
+		case hprofHeapDumpSegment:
+			self.parseSegment(body)
+		case hprofHeapDumpEnd:
+			ended = true
+		default:
+			t.Fatalf("unknown record 0x%x", tag)
+		}
+	}
+	if self.buf.Len() != 0 {
+		t.Fatalf("%d bytes after HEAP DUMP END", self.buf.Len())
+	}
+	return self
+}
+
+func (self *hprofReader) parseSegment(segment []byte) {
+	outer := self.buf
+	self.buf = bytes.NewReader(segment)
+	defer func() { self.buf = outer }()
+	for self.buf.Len() > 0 {
+		tag := self.u1()
+		switch tag {
+		case hprofRootUnknown, hprofRootStickyClass, hprofRootMonitorUsed:
+			self.roots[tag] = append(self.roots[tag], self.u8())
+		case hprofRootJavaFrame, hprofRootThreadObj:
+			self.roots[tag] = append(self.roots[tag], self.u8())
+			self.u4()
+			self.u4()
+		case hprofClassDump:
+			self.parseClassDump()
+		case hprofInstanceDump:
+			id := self.u8()
+			self.u4()
+			class := self.u8()
+			self.instances[id] = &dumpedInstance{class: class, values: self.read(int(self.u4()))}
+		case hprofObjArrayDump:
+			self.u8()
+			self.u4()
+			n := self.u4()
+			self.u8()
+			self.read(int(n) * 8)
+		case hprofPrimArrayDump:
+			self.u8()
+			self.u4()
+			n := self.u4()
+			self.read(int(n) * hprofValueSizes[self.u1()])
+		default:
+			self.t.Fatalf("unknown sub-record 0x%x", tag)
+		}
+	}
+}
+
+func (self *hprofReader) parseClassDump() {
+	id := self.u8()
+	self.u4()
+	class := &dumpedClass{super: self.u8()}
+	self.read(5 * 8) // 类加载器 signers protection domain 两个保留字段
+	class.size = uint64(self.u4())
+	if n := self.u2(); n != 0 {
+		self.t.Fatalf("%d constant pool entries", n)
+	}
+	for n := self.u2(); n > 0; n-- {
+		class.staticNames = append(class.staticNames, self.names[self.u8()])
+		class.staticValues = append(class.staticValues, self.value(self.u1()))
+	}
+	for n := self.u2(); n > 0; n-- {
+		class.fieldNames = append(class.fieldNames, self.names[self.u8()])
+		class.fieldTypes = append(class.fieldTypes, self.u1())
+	}
+	self.classes[id] = class
+}
+
+// 按CLASS DUMP里的实例字段 从子类到超类读出INSTANCE DUMP的值
+func (self *hprofReader) instanceValues(id uint64) map[string]uint64 {
+	instance := self.instances[id]
+	if instance == nil {
+		self.t.Fatalf("no INSTANCE DUMP for 0x%x", id)
+	}
+	values := map[string]uint64{}
+	outer := self.buf
+	self.buf = bytes.NewReader(instance.values)
+	defer func() { self.buf = outer }()
+	for classID := instance.class; classID != 0; classID = self.classes[classID].super {
+		class := self.classes[classID]
+		for i, name := range class.fieldNames {
+			values[name] = self.value(class.fieldTypes[i])
+		}
+	}
+	if self.buf.Len() != 0 {
+		self.t.Fatalf("INSTANCE DUMP 0x%x has %d bytes more than its fields", id, self.buf.Len())
+	}
+	return values
+}
+
+// 不经过类加载器 搭一个有各种字段的类Point和一个WeakReference
+type dumpTestHeap struct {
+	loader                       *ClassLoader
+	object, point, weakReference *Class
+}
+
+func (self *dumpTestHeap) newClass(name string, super *Class, fields ...[2]string) *Class {
+	class := &Class{name: name, superClass: super, loader: self.loader}
+	for _, nd := range fields {
+		field := &Field{}
+		field.class = class
+		field.name = nd[0]
+		field.descriptor = nd[1]
+		if field.name == "total" {
+			field.accessFlags = ACC_STATIC
+		}
+		class.fields = append(class.fields, field)
+	}
+	class.refKind = referenceKind(class)
+	calcInstanceFieldSlotIds(class)
+	calcStaticFieldSlotIds(class)
+	class.staticVars = newSlots(class.staticSlotCount)
+	self.loader.classMap[name] = class
+	return class
+}
+
+func newDumpTestHeap() *dumpTestHeap {
+	heap := NewHeap(0)
+	self := &dumpTestHeap{loader: &ClassLoader{heap: heap, classMap: map[string]*Class{}}}
+	heap.bootstrap = self.loader
+	self.object = self.newClass("java/lang/Object", nil)
+	jlClass := self.newClass("java/lang/Class", self.object)
+	reference := self.newClass("java/lang/ref/Reference", self.object,
+		[2]string{"referent", "Ljava/lang/Object;"}, [2]string{"queue", "Ljava/lang/Object;"})
+	reference.getField("referent", "Ljava/lang/Object;", false).referent = true
+	self.weakReference = self.newClass("java/lang/ref/WeakReference", reference)
+	shape := self.newClass("Shape", self.object, [2]string{"id", "I"}, [2]string{"flag", "Z"})
+	self.point = self.newClass("Point", shape,
+		[2]string{"b", "B"}, [2]string{"ch", "C"}, [2]string{"s", "S"}, [2]string{"total", "I"},
+		[2]string{"f", "F"}, [2]string{"count", "J"}, [2]string{"d", "D"}, [2]string{"next", "LPoint;"})
+	for _, class := range self.loader.classMap {
+		class.jClass = jlClass.NewObject()
+		class.jClass.extra = class
+	}
+	return self
+}
+
+func TestDumpHeapRoundTrip(t *testing.T) {
+	h := newDumpTestHeap()
+	point := h.point
+	p, q := point.NewObject(), point.NewObject()
+	set := func(name, descriptor string, fn func(slotId uint)) {
+		fn(point.getField(name, descriptor, false).slotId)
+	}
+	set("id", "I", func(i uint) { p.SetIntField(i, -2) })
+	set("flag", "Z", func(i uint) { p.SetIntField(i, 1) })
+	set("b", "B", func(i uint) { p.SetIntField(i, -3) })
+	set("ch", "C", func(i uint) { p.SetIntField(i, 0x4e2d) })
+	set("s", "S", func(i uint) { p.SetIntField(i, -4) })
+	set("f", "F", func(i uint) { p.SetFloatField(i, 1.5) })
+	set("count", "J", func(i uint) { p.SetLongField(i, -5) })
+	set("d", "D", func(i uint) { p.SetDoubleField(i, 2.25) })
+	set("next", "LPoint;", func(i uint) { p.SetRefField(i, q) })
+	point.staticVars.SetInt(point.getField("total", "I", true).slotId, 42)
+
+	weak := h.weakReference.NewObject()
+	weak.SetReferent(h.weakReference.getField("referent", "Ljava/lang/Object;", false), p)
+
+	method := &Method{}
+	method.class = point
+	threads := []ThreadRoots{{Frames: []FrameRoots{{Method: method, Refs: []*Object{p, weak}}}}}
+	var out bytes.Buffer
+	if err := h.loader.DumpHeap(&out, threads); err != nil {
+		t.Fatal(err)
+	}
+	runtime.KeepAlive(p)
+	dump := parseHprof(t, out.Bytes())
+
+	if roots := dump.roots[hprofRootJavaFrame]; len(roots) != 2 || roots[0] != objectID(p) || roots[1] != objectID(weak) {
+		t.Fatalf("java frame roots = %x", roots)
+	}
+	if n := len(dump.roots[hprofRootStickyClass]); n != len(h.loader.classMap) {
+		t.Fatalf("%d sticky classes, want %d", n, len(h.loader.classMap))
+	}
+
+	// CLASS DUMP里的实例字段和class.fields的顺序一样 不包括静态字段
+	pointDump := dump.classes[dump.classIDs["Point"]]
+	if pointDump == nil {
+		t.Fatal("no CLASS DUMP for Point")
+	}
+	wantNames := []string{"b", "ch", "s", "f", "count", "d", "next"}
+	wantTypes := []byte{8, 5, 9, 6, 11, 7, 2}
+	if !bytes.Equal(pointDump.fieldTypes, wantTypes) || len(pointDump.fieldNames) != len(wantNames) {
+		t.Fatalf("Point fields %v %v, want %v %v", pointDump.fieldNames, pointDump.fieldTypes, wantNames, wantTypes)
+	}
+	for i, name := range wantNames {
+		if pointDump.fieldNames[i] != name {
+			t.Fatalf("Point fields %v, want %v", pointDump.fieldNames, wantNames)
+		}
+	}
+	if pointDump.size != uint64(instanceSize(point)) {
+		t.Errorf("instance size = %d, want %d", pointDump.size, instanceSize(point))
+	}
+	if pointDump.super != objectID(point.superClass.jClass) {
+		t.Errorf("super = 0x%x, want Shape", pointDump.super)
+	}
+	if len(pointDump.staticNames) != 1 || pointDump.staticNames[0] != "total" || pointDump.staticValues[0] != 42 {
+		t.Errorf("statics %v = %v, want total = 42", pointDump.staticNames, pointDump.staticValues)
+	}
+
+	// INSTANCE DUMP的长度和值与writeInstanceValue一致
+	values := dump.instanceValues(objectID(p))
+	want := map[string]uint64{
+		"id": uint64(uint32(0xfffffffe)), "flag": 1, "b": 0xfd, "ch": 0x4e2d, "s": 0xfffc,
+		"f": uint64(math.Float32bits(1.5)), "count": uint64(0xfffffffffffffffb),
+		"d": math.Float64bits(2.25), "next": objectID(q),
+	}
+	for name, v := range want {
+		if values[name] != v {
+			t.Errorf("p.%s = 0x%x, want 0x%x", name, values[name], v)
+		}
+	}
+	if values := dump.instanceValues(objectID(q)); values["next"] != 0 || values["count"] != 0 {
+		t.Errorf("q = %v, want zero fields", values)
+	}
+
+	// Reference.referent不在字段槽里 转储的是弱引用指向的对象
+	if values := dump.instanceValues(objectID(weak)); values["referent"] != objectID(p) || values["queue"] != 0 {
+		t.Errorf("weak reference = %v, want referent 0x%x", values, objectID(p))
+	}
+	runtime.KeepAlive(weak)
+}
@@ -0,0 +1,37 @@
+package heap
+
+import "sort"
+
+// jmap -histo的一行 字节数和-Xmx记账用的一样是近似值
+type HistogramEntry struct {
+	Class     *Class
+	Instances int64
+	Bytes     int64
+}
+
+// 按类统计所有可达对象的个数和大小 按字节数从大到小排列
+// 调用者必须持有全局解释器锁
+func (self *ClassLoader) Histogram(threads []ThreadRoots) []*HistogramEntry {
+	entries := map[*Class]*HistogramEntry{}
+	for _, obj := range walkHeap(self.Bootstrap(), threads).objects {
+		entry := entries[obj.class]
+		if entry == nil {
+			entry = &HistogramEntry{Class: obj.class}
+			entries[obj.class] = entry
+		}
+		entry.Instances++
+		entry.Bytes += obj.size()
+	}
+
+	histogram := make([]*HistogramEntry, 0, len(entries))
+	for _, entry := range entries {
+		histogram = append(histogram, entry)
+	}
+	sort.Slice(histogram, func(i, j int) bool {
+		if histogram[i].Bytes != histogram[j].Bytes {
+			return histogram[i].Bytes > histogram[j].Bytes
+		}
+		return histogram[i].Class.name < histogram[j].Class.name
+	})
+	return histogram
+}
@@ -0,0 +1,52 @@
+package heap
+
+import "testing"
+
+// 线程栈上的对象都是可达的 其余的在collect()之后不再记账
+func newAccountingTestHeap(objects int64) (*Heap, *Class, *[]*Object) {
+	point := newPointClass()
+	heap := point.loader.heap
+	heap.maxBytes = objects * instanceSize(point)
+	var roots []*Object
+	heap.SetThreadRoots(func() []ThreadRoots {
+		method := &Method{}
+		method.class = point
+		return []ThreadRoots{{Frames: []FrameRoots{{Method: method, Refs: roots}}}}
+	})
+	return heap, point, &roots
+}
+
+func TestHeapAccounting(t *testing.T) {
+	heap, point, roots := newAccountingTestHeap(10)
+	size := instanceSize(point)
+	for i := 0; i < 5; i++ {
+		*roots = append(*roots, point.NewObject())
+	}
+	for i := 0; i < 100; i++ {
+		point.NewObject() // 垃圾 内存不足时collect()
+	}
+	heap.GC()
+	if used := heap.UsedMemory(); used != 5*size {
+		t.Fatalf("used %d bytes after GC, want the 5 reachable objects (%d)", used, 5*size)
+	}
+	point.NewObject()
+	if used := heap.UsedMemory(); used != 6*size {
+		t.Fatalf("used %d bytes after allocating, want %d", used, 6*size)
+	}
+}
+
+func TestHeapOutOfMemory(t *testing.T) {
+	_, point, roots := newAccountingTestHeap(10)
+	defer func() {
+		ex, ok := recover().(*JavaException)
+		if !ok || ex.className != "java/lang/OutOfMemoryError" {
+			t.Fatalf("recovered %v, want OutOfMemoryError", ex)
+		}
+		if len(*roots) != 10 {
+			t.Fatalf("OutOfMemoryError after %d reachable objects, want 10", len(*roots))
+		}
+	}()
+	for {
+		*roots = append(*roots, point.NewObject())
+	}
+}
@@ -0,0 +1,131 @@
+package heap
+
+// 对象本身由Go的GC管理 虚拟机自己没有对象列表
+// 需要枚举存活的对象时(堆转储、直方图、找出需要finalize的对象) 从GC根出发遍历
+// 根包括启动类加载器加载的类(它们的静态变量)、线程栈、字符串池和等待finalize的对象
+// 用户类加载器加载的类通过java.lang.ClassLoader实例可达
+
+// 线程栈上的GC根 由rtda收集 栈顶的帧在前
+type ThreadRoots struct {
+	JThread *Object
+	Frames  []FrameRoots
+}
+
+type FrameRoots struct {
+	Method *Method
+	PC     int
+	Refs   []*Object // 局部变量表和操作数栈里的引用
+	Locked []*Object // 这个栈帧进入的monitor
+}
+
+type heapWalker struct {
+	objects    []*Object // 按遍历的顺序
+	classes    []*Class  // 遍历到的类 不包括基本类型
+	visited    map[*Object]bool
+	interns    []*Object
+	finalizing []*Object
+	scanned    int // objects里前scanned个对象的引用已经标记过
+}
+
+// java.lang.Class实例对应的类 基本类型的Class实例按普通对象处理
+func mirroredClass(obj *Object) *Class {
+	if class, ok := obj.extra.(*Class); ok && class.jClass == obj && !class.IsPrimitive() {
+		return class
+	}
+	return nil
+}
+
+// 广度优先遍历
+func walkHeap(bootstrap *ClassLoader, threads []ThreadRoots) *heapWalker {
+	self := &heapWalker{visited: map[*Object]bool{}}
+	self.markRoots(bootstrap, threads)
+	self.drain()
+	return self
+}
+
+func (self *heapWalker) markRoots(bootstrap *ClassLoader, threads []ThreadRoots) {
+	for _, class := range bootstrap.classMap {
+		self.mark(class.jClass)
+	}
+	for _, thread := range threads {
+		self.mark(thread.JThread)
+		for _, frame := range thread.Frames {
+			self.mark(frame.Method.class.jClass)
+			for _, ref := range frame.Refs {
+				self.mark(ref)
+			}
+		}
+	}
+	self.interns = bootstrap.heap.strings.objects()
+	for _, str := range self.interns {
+		self.mark(str)
+	}
+	// 等待执行finalize()的对象由Finalizer线程引用
+	self.finalizing = bootstrap.heap.finalizers.queued()
+	for _, obj := range self.finalizing {
+		self.mark(obj)
+	}
+}
+
+// 遍历已经标记的对象引用的对象 直到没有新的对象
+func (self *heapWalker) drain() {
+	for ; self.scanned < len(self.objects); self.scanned++ {
+		obj := self.objects[self.scanned]
+		self.mark(obj.class.jClass)
+		if class := mirroredClass(obj); class != nil {
+			self.classes = append(self.classes, class)
+			self.markClass(class)
+		}
+		if loader, ok := obj.extra.(*ClassLoader); ok {
+			for _, class := range loader.classMap {
+				self.mark(class.jClass)
+			}
+		}
+		if obj.class.IsArray() {
+			for _, ref := range obj.refs {
+				self.mark(ref)
+			}
+		} else {
+			self.eachField(obj, func(field *Field, ref *Object) { self.mark(ref) })
+		}
+	}
+}
+
+func (self *heapWalker) mark(obj *Object) {
+	if obj != nil && !self.visited[obj] {
+		self.visited[obj] = true
+		self.objects = append(self.objects, obj)
+	}
+}
+
+func (self *heapWalker) markClass(class *Class) {
+	if class.superClass != nil {
+		self.mark(class.superClass.jClass)
+	}
+	self.mark(class.loader.jLoader)
+	for _, field := range class.fields {
+		if field.IsStatic() && isRefField(field) && class.staticVars != nil {
+			self.mark(class.staticVars.GetRef(field.slotId))
+		}
+	}
+}
+
+func isRefField(field *Field) bool {
+	return field.descriptor[0] == 'L' || field.descriptor[0] == '['
+}
+
+// 对象的引用类型字段 包括Reference.referent
+func (self *heapWalker) eachField(obj *Object, fn func(field *Field, ref *Object)) {
+	for class := obj.class; class != nil; class = class.superClass {
+		for _, field := range class.fields {
+			if field.IsStatic() || !isRefField(field) {
+				continue
+			}
+			if field.referent {
+				fn(field, obj.Referent(field))
+			} else {
+				fn(field, obj.refs[field.slotId])
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package heap
+
+import "strings"
+
+// Go代码里发生的错误 需要以Java异常的形式抛给正在执行的Java代码
+// 用panic抛出 由解释器捕获后转换成真正的异常对象
+type JavaException struct {
+	className string // 内部形式的类名 比如java/lang/OutOfMemoryError
+	message   string
+	thrown    *Object // Java代码已经抛出的异常对象 原样重新抛出
+}
+
+func NewJavaException(className, message string) *JavaException {
+	return &JavaException{className: className, message: message}
+}
+
+// 比如用户类加载器的loadClass()抛出的异常
+func NewThrownException(thrown *Object) *JavaException {
+	return &JavaException{className: thrown.class.name, thrown: thrown}
+}
+
+func (self *JavaException) Thrown() *Object {
+	return self.thrown
+}
+
+func (self *JavaException) ClassName() string {
+	return self.className
+}
+
+func (self *JavaException) Message() string {
+	return self.message
+}
+
+func (self *JavaException) Error() string {
+	name := strings.Replace(self.className, "/", ".", -1)
+	if self.message == "" {
+		return name
+	}
+	return name + ": " + self.message
+}
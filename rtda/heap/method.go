@@ -8,13 +8,20 @@ type Method struct {
 	maxLocals uint
 	code []byte
 	exceptionTable ExceptionTable
-	lineNumberTable *classfile.LineNumberTableAttribute
-	exceptions *classfile.ExceptionsAttribute
+	lineNumberTable []lineNumberEntry // 按startPc升序
+	exceptionIndexTable []uint16 // Exceptions属性 声明抛出的异常在常量池中的索引
 	// Runtime Visible Parameter Annotations Attribute
 	parameterAnnotationData []byte
 	annotationDefaultData []byte
 	parsedDescriptor *MethodDescriptor
 	argSlotCount uint
+	vtableIndex int // 在vtable中的下标 静态方法、私有方法和构造函数是-1
+	itableIndex int // 接口方法在itable中的下标
+	conflicts bool // 多个默认方法冲突时vtable里放的标记
+	decoded interface{} // 解释器按pc缓存的已解码指令 由instructions包管理 code本身保持不变
+	invocationCount uint32 // 解释执行的调用次数 达到阈值时JIT编译
+	backedgeCount uint32 // 解释执行时向回跳转的次数
+	compiled interface{} // JIT编译好的代码 由main包管理
 }
 
 //  classfile.MemberInfo 转换为 Methods
@@ -45,17 +52,36 @@ func (self *Method) copyAttributes(cfMethod *classfile.MemberInfo) {
 		self.maxStack = codeAttr.MaxStack()
 		self.maxLocals = codeAttr.MaxLocals()
 		self.code = codeAttr.Code()
-		self.lineNumberTable = codeAttr.LineNumberTableAttribute()
+		if lntAttr := codeAttr.LineNumberTableAttribute(); lntAttr != nil {
+			self.lineNumberTable = newLineNumberTable(lntAttr)
+		}
 		self.exceptionTable = newExcetionTable(codeAttr.ExceptionTable(),
 			self.class.constantPool)
 	}
-	self.exceptions = cfMethod.ExceptionsAttribute()
+	if exAttr := cfMethod.ExceptionsAttribute(); exAttr != nil {
+		self.exceptionIndexTable = exAttr.ExceptionIndexTable()
+	}
 	self.annotationData = cfMethod.RuntimeVisibleAnnotationsAttributeData()
 	self.parameterAnnotationData =
 		cfMethod.RuntimeVisibleParameterAnnotationsAttributeData()
 	self.annotationDefaultData = cfMethod.AnnotationDefaultAttributeData()
 }
 
+// 行号表不再引用classfile的结构 方便-Xshare:dump序列化
+type lineNumberEntry struct {
+	startPc    uint16
+	lineNumber uint16
+}
+
+func newLineNumberTable(lntAttr *classfile.LineNumberTableAttribute) []lineNumberEntry {
+	entries := lntAttr.Entries()
+	table := make([]lineNumberEntry, len(entries))
+	for i, entry := range entries {
+		table[i] = lineNumberEntry{entry.StartPc(), entry.LineNumber()}
+	}
+	return table
+}
+
 func (self *Method) calcArgSlotCount(paramTypes []string) {
 	for _, paramType := range paramTypes {
 		self.argSlotCount++
@@ -119,6 +145,42 @@ func (self *Method) Code() []byte {
 	return self.code
 }
 
+// 类型由instructions包决定 heap包不依赖指令的实现
+func (self *Method) DecodedCode() interface{} {
+	return self.decoded
+}
+
+func (self *Method) SetDecodedCode(decoded interface{}) {
+	self.decoded = decoded
+}
+
+func (self *Method) IncInvocationCount() uint32 {
+	self.invocationCount++
+	return self.invocationCount
+}
+
+func (self *Method) IncBackedgeCount() uint32 {
+	self.backedgeCount++
+	return self.backedgeCount
+}
+
+func (self *Method) CompiledCode() interface{} {
+	return self.compiled
+}
+
+func (self *Method) SetCompiledCode(compiled interface{}) {
+	self.compiled = compiled
+}
+
+// 异常处理器的入口 JIT据此划分基本块
+func (self *Method) ExceptionHandlerPCs() []int {
+	pcs := make([]int, len(self.exceptionTable))
+	for i, handler := range self.exceptionTable {
+		pcs[i] = handler.handlerPc
+	}
+	return pcs
+}
+
 func (self *Method) ParameterAnnotationData() []byte {
 	return self.parameterAnnotationData
 }
@@ -147,10 +209,13 @@ func (self *Method) GetLineNumber(pc int) int {
 	if self.IsNative() {
 		return -2
 	}
-	if self.lineNumberTable == nil {
-		return -1
+	for i := len(self.lineNumberTable) - 1; i >= 0; i-- {
+		entry := self.lineNumberTable[i]
+		if pc >= int(entry.startPc) {
+			return int(entry.lineNumber)
+		}
 	}
-	return self.lineNumberTable.GetLineNumber(pc)
+	return -1
 }
 
 // <init> 是对象构造函数
@@ -187,11 +252,11 @@ func (self *Method) ReturnType() *Class {
 }
 
 func (self *Method) ExceptionTypes() []*Class {
-	if self.exceptions == nil {
+	if self.exceptionIndexTable == nil {
 		return nil
 	}
 
-	exIndexTable := self.exceptions.ExceptionIndexTable()
+	exIndexTable := self.exceptionIndexTable
 	exClasses := make([]*Class, len(exIndexTable))
 	cp := self.class.constantPool
 
@@ -11,17 +11,111 @@ func LookupMethodInClass(class *Class, name, descriptor string) *Method {
 	return nil
 }
 
-func lookupMethodInInterfaces(ifaces []*Class, name, descriptor string) *Method {
-	for _, iface := range ifaces {
+// jvms8 5.4.6 invokevirtual和invokeinterface的方法选择
+// 先在类及其超类中查找 找不到时从最具体的超接口方法中选择唯一的默认方法
+// 有多个默认方法可选时抛出IncompatibleClassChangeError
+// 返回nil或者抽象方法时 由调用者抛出AbstractMethodError
+func SelectMethod(class *Class, name, descriptor string) *Method {
+	if method := LookupMethodInClass(class, name, descriptor); method != nil {
+		return method
+	}
+	candidates := maximallySpecificMethods(class, name, descriptor)
+	method, conflicts := selectDefaultMethod(candidates)
+	if conflicts {
+		panic(NewJavaException("java/lang/IncompatibleClassChangeError",
+			"Conflicting default methods: "+describeMethods(candidates)))
+	}
+	return method
+}
+
+// jvms8 5.4.3.3和5.4.3.4的最后两步 解析时有多个默认方法不报错 任选一个
+func lookupMethodInInterfaces(class *Class, name, descriptor string) *Method {
+	candidates := maximallySpecificMethods(class, name, descriptor)
+	if method, conflicts := selectDefaultMethod(candidates); method != nil && !conflicts {
+		return method
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// 恰好有一个非抽象方法时返回它 多于一个时conflicts为true
+// 全部是抽象方法时返回第一个抽象方法
+func selectDefaultMethod(candidates []*Method) (method *Method, conflicts bool) {
+	for _, candidate := range candidates {
+		if !candidate.IsAbstract() {
+			if method != nil && !method.IsAbstract() {
+				return nil, true
+			}
+			method = candidate
+		} else if method == nil {
+			method = candidate
+		}
+	}
+	return method, false
+}
+
+// jvms8 5.4.3.3 maximally-specific superinterface methods
+// class的所有超接口(包括超类实现的接口)中声明的同名同描述符的非私有实例方法
+// 如果另一个声明了这个方法的接口是它的子接口 它就被覆盖了 不算在内
+func maximallySpecificMethods(class *Class, name, descriptor string) []*Method {
+	var declared []*Method
+	for _, iface := range superInterfaces(class) {
 		for _, method := range iface.methods {
-			if method.name == name && method.descriptor == descriptor {
-				return method
+			if method.name == name && method.descriptor == descriptor &&
+				!method.IsStatic() && !method.IsPrivate() {
+				declared = append(declared, method)
 			}
 		}
-		method := lookupMethodInInterfaces(iface.interfaces, name, descriptor)
-		if method != nil {
-			return method
+	}
+
+	var candidates []*Method
+	for _, method := range declared {
+		overridden := false
+		for _, other := range declared {
+			if other != method && other.class.isSubInterfaceOf(method.class) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			candidates = append(candidates, method)
 		}
 	}
-	return nil
+	return candidates
+}
+
+// 按声明顺序深度优先 每个接口只出现一次
+func superInterfaces(class *Class) []*Class {
+	var ifaces []*Class
+	seen := map[*Class]bool{}
+	var visit func(iface *Class)
+	visit = func(iface *Class) {
+		if seen[iface] {
+			return
+		}
+		seen[iface] = true
+		ifaces = append(ifaces, iface)
+		for _, superInterface := range iface.interfaces {
+			visit(superInterface)
+		}
+	}
+	for c := class; c != nil; c = c.superClass {
+		for _, iface := range c.interfaces {
+			visit(iface)
+		}
+	}
+	return ifaces
+}
+
+func describeMethods(methods []*Method) string {
+	desc := ""
+	for i, method := range methods {
+		if i > 0 {
+			desc += " "
+		}
+		desc += method.class.JavaName() + "." + method.name
+	}
+	return desc
 }
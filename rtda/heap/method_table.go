@@ -0,0 +1,131 @@
+package heap
+
+// 虚方法表和接口方法表 在prepare阶段构造
+// vtable: 超类的vtable在前 子类覆盖的方法替换同一下标 新方法追加在后面
+//         还没有实现的超接口方法(默认方法或者抽象方法)也追加进来
+// itable: 每个超接口一个数组 下标是接口方法的itableIndex 元素是vtable里对应的实现
+// invokevirtual和invokeinterface用下标直接取到要调用的方法 不用再按名字逐级查找
+
+func buildMethodTables(class *Class) {
+	if class.IsInterface() {
+		calcItableIndexes(class)
+		return
+	}
+
+	var vtable []*Method
+	if class.superClass != nil {
+		vtable = append(vtable, class.superClass.vtable...)
+	}
+	index := make(map[string]int, len(vtable)+len(class.methods)) // 名字+描述符 -> vtable下标
+	for i, method := range vtable {
+		index[method.name+method.descriptor] = i
+		if method.class.IsInterface() || method.conflicts {
+			// 从超类继承来的默认方法 这个类的超接口里可能有更具体的
+			vtable[i] = selectInheritedMethod(class, method.name, method.descriptor)
+		}
+	}
+
+	for _, method := range class.methods {
+		method.vtableIndex, method.itableIndex = -1, -1
+		if method.IsStatic() || method.IsPrivate() || method.name == "<init>" {
+			continue
+		}
+		key := method.name + method.descriptor
+		if i, ok := index[key]; ok {
+			vtable[i] = method
+			method.vtableIndex = i
+		} else {
+			method.vtableIndex = len(vtable)
+			index[key] = len(vtable)
+			vtable = append(vtable, method)
+		}
+	}
+
+	ifaces := superInterfaces(class)
+	for _, iface := range ifaces {
+		for _, method := range iface.methods {
+			if method.itableIndex < 0 {
+				continue
+			}
+			key := method.name + method.descriptor
+			if _, ok := index[key]; !ok {
+				index[key] = len(vtable)
+				vtable = append(vtable, selectInheritedMethod(class, method.name, method.descriptor))
+			}
+		}
+	}
+	class.vtable = vtable
+
+	class.itable = make(map[*Class][]*Method, len(ifaces))
+	for _, iface := range ifaces {
+		impls := make([]*Method, iface.itableSize)
+		for _, method := range iface.methods {
+			if method.itableIndex >= 0 {
+				impls[method.itableIndex] = vtable[index[method.name+method.descriptor]]
+			}
+		}
+		class.itable[iface] = impls
+	}
+}
+
+// 接口的非静态、非私有方法按声明顺序编号
+func calcItableIndexes(iface *Class) {
+	iface.itableSize = 0
+	for _, method := range iface.methods {
+		method.vtableIndex = -1
+		method.itableIndex = -1
+		if !method.IsStatic() && !method.IsPrivate() {
+			method.itableIndex = iface.itableSize
+			iface.itableSize++
+		}
+	}
+}
+
+// 类及其超类都没有声明这个方法 从最具体的超接口方法中选择
+// 有多个默认方法可选时 放一个conflicts标记 调用时抛出IncompatibleClassChangeError
+func selectInheritedMethod(class *Class, name, descriptor string) *Method {
+	candidates := maximallySpecificMethods(class, name, descriptor)
+	method, conflicts := selectDefaultMethod(candidates)
+	if conflicts {
+		marker := &Method{conflicts: true, vtableIndex: -1, itableIndex: -1}
+		marker.class = class
+		marker.name = name
+		marker.descriptor = descriptor
+		marker.accessFlags = ACC_PUBLIC | ACC_ABSTRACT
+		return marker
+	}
+	return method
+}
+
+// invokevirtual的方法选择 见SelectMethod
+func (self *Class) VirtualMethod(resolved *Method) *Method {
+	if resolved.IsPrivate() {
+		return resolved
+	}
+	if resolved.class.IsInterface() {
+		return self.InterfaceMethod(resolved)
+	}
+	if resolved.vtableIndex < 0 || resolved.vtableIndex >= len(self.vtable) {
+		return SelectMethod(self, resolved.name, resolved.descriptor)
+	}
+	return self.checkConflicts(self.vtable[resolved.vtableIndex])
+}
+
+// invokeinterface的方法选择 接口引用上调用的toString()等方法解析到Object里 走vtable
+func (self *Class) InterfaceMethod(resolved *Method) *Method {
+	if !resolved.class.IsInterface() {
+		return self.VirtualMethod(resolved)
+	}
+	impls := self.itable[resolved.class]
+	if resolved.itableIndex < 0 || resolved.itableIndex >= len(impls) {
+		return SelectMethod(self, resolved.name, resolved.descriptor)
+	}
+	return self.checkConflicts(impls[resolved.itableIndex])
+}
+
+func (self *Class) checkConflicts(method *Method) *Method {
+	if method != nil && method.conflicts {
+		return SelectMethod(self, method.name, method.descriptor) // 抛出IncompatibleClassChangeError
+	}
+	return method
+}
@@ -0,0 +1,105 @@
+package heap
+
+import (
+	"fmt"
+	"testing"
+)
+
+// 不经过类加载器 直接搭一个类层次:
+// Object <- Base <- Mid <- Leaf implements Greeter
+// 每个类声明methodsPerClass个方法 Greeter有一个默认方法greet()
+const methodsPerClass = 10
+
+type testHierarchy struct {
+	object, base, mid, leaf, greeter *Class
+}
+
+func newTestClass(name string, flags uint16, super *Class, ifaces []*Class, methodNames ...string) *Class {
+	class := &Class{name: name, accessFlags: flags, superClass: super, interfaces: ifaces}
+	for _, methodName := range methodNames {
+		method := &Method{}
+		method.class = class
+		method.name = methodName
+		method.descriptor = "()V"
+		method.accessFlags = ACC_PUBLIC
+		class.methods = append(class.methods, method)
+	}
+	buildMethodTables(class)
+	return class
+}
+
+func methodNames(prefix string) []string {
+	names := make([]string, methodsPerClass)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return names
+}
+
+func newTestHierarchy() *testHierarchy {
+	h := &testHierarchy{}
+	h.object = newTestClass("java/lang/Object", ACC_PUBLIC, nil, nil, methodNames("o")...)
+	h.base = newTestClass("Base", ACC_PUBLIC, h.object, nil, methodNames("b")...)
+	h.mid = newTestClass("Mid", ACC_PUBLIC, h.base, nil, append(methodNames("m"), "b0")...)
+	h.greeter = newTestClass("Greeter", ACC_PUBLIC|ACC_INTERFACE|ACC_ABSTRACT, nil, nil, "greet")
+	h.leaf = newTestClass("Leaf", ACC_PUBLIC, h.mid, []*Class{h.greeter}, methodNames("l")...)
+	return h
+}
+
+// vtable和itable选出的方法必须和按名字逐级查找的结果一样
+func TestMethodTablesMatchSelectMethod(t *testing.T) {
+	h := newTestHierarchy()
+	for _, class := range []*Class{h.object, h.base, h.mid, h.greeter} {
+		for _, resolved := range class.methods {
+			for _, receiver := range []*Class{h.object, h.base, h.mid, h.leaf} {
+				if !receiver.IsImplements(class) && !receiver.IsSubClassOf(class) && receiver != class {
+					continue
+				}
+				want := SelectMethod(receiver, resolved.name, resolved.descriptor)
+				got := receiver.VirtualMethod(resolved)
+				if class.IsInterface() {
+					got = receiver.InterfaceMethod(resolved)
+				}
+				if got != want {
+					t.Errorf("%s.%s on %s: table selected %s.%s, want %s.%s",
+						class.name, resolved.name, receiver.name,
+						got.class.name, got.name, want.class.name, want.name)
+				}
+			}
+		}
+	}
+}
+
+// Object里的方法要逐级查到最上面 是SelectMethod最慢的情况
+func BenchmarkVirtualMethod(b *testing.B) {
+	h := newTestHierarchy()
+	resolved := h.object.methods[methodsPerClass-1]
+	for i := 0; i < b.N; i++ {
+		h.leaf.VirtualMethod(resolved)
+	}
+}
+
+func BenchmarkSelectMethod(b *testing.B) {
+	h := newTestHierarchy()
+	resolved := h.object.methods[methodsPerClass-1]
+	for i := 0; i < b.N; i++ {
+		SelectMethod(h.leaf, resolved.name, resolved.descriptor)
+	}
+}
+
+// 默认方法在类层次里找不到 SelectMethod还要遍历所有超接口
+func BenchmarkInterfaceMethod(b *testing.B) {
+	h := newTestHierarchy()
+	resolved := h.greeter.methods[0]
+	for i := 0; i < b.N; i++ {
+		h.leaf.InterfaceMethod(resolved)
+	}
+}
+
+func BenchmarkSelectDefaultMethod(b *testing.B) {
+	h := newTestHierarchy()
+	resolved := h.greeter.methods[0]
+	for i := 0; i < b.N; i++ {
+		SelectMethod(h.leaf, resolved.name, resolved.descriptor)
+	}
+}
@@ -0,0 +1,143 @@
+package heap
+
+import (
+	"sync"
+	"time"
+)
+
+// 每个对象都可以作为monitor使用(synchronized、wait、notify)
+// owner是持有monitor的线程(*rtda.Thread) 同一线程可以重入
+// Enter和Wait可能会阻塞 调用者必须先释放全局解释器锁
+type Monitor struct {
+	mu         sync.Mutex
+	cond       *sync.Cond // 等待进入monitor
+	owner      interface{}
+	entryCount int
+	waiters    []chan struct{} // 调用了wait()的线程
+}
+
+func newMonitor() *Monitor {
+	monitor := &Monitor{}
+	monitor.cond = sync.NewCond(&monitor.mu)
+	return monitor
+}
+
+func (self *Monitor) Owner() interface{} {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.owner
+}
+
+func (self *Monitor) IsOwner(thread interface{}) bool {
+	return self.Owner() == thread
+}
+
+// 不阻塞 monitor被其他线程持有时返回false
+func (self *Monitor) TryEnter(thread interface{}) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.owner == nil || self.owner == thread {
+		self.owner = thread
+		self.entryCount++
+		return true
+	}
+	return false
+}
+
+func (self *Monitor) Enter(thread interface{}) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.enter(thread, 1)
+}
+
+func (self *Monitor) enter(thread interface{}, count int) {
+	for self.owner != nil && self.owner != thread {
+		self.cond.Wait()
+	}
+	self.owner = thread
+	self.entryCount += count
+}
+
+// 当前线程不持有monitor时返回false
+func (self *Monitor) Exit(thread interface{}) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.owner != thread {
+		return false
+	}
+	self.entryCount--
+	if self.entryCount == 0 {
+		self.owner = nil
+		self.cond.Signal()
+	}
+	return true
+}
+
+// 释放monitor并等待notify()或者超时 然后重新进入monitor
+// timeout为0表示一直等待 当前线程不持有monitor时返回false
+func (self *Monitor) Wait(thread interface{}, timeout time.Duration) bool {
+	self.mu.Lock()
+	if self.owner != thread {
+		self.mu.Unlock()
+		return false
+	}
+	ch := make(chan struct{}, 1)
+	self.waiters = append(self.waiters, ch)
+	count := self.entryCount
+	self.owner = nil
+	self.entryCount = 0
+	self.cond.Signal()
+	self.mu.Unlock()
+
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		select {
+		case <-ch:
+		case <-timer.C:
+		}
+		timer.Stop()
+	} else {
+		<-ch
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.removeWaiter(ch)
+	self.enter(thread, count)
+	return true
+}
+
+func (self *Monitor) removeWaiter(ch chan struct{}) {
+	for i, waiter := range self.waiters {
+		if waiter == ch {
+			self.waiters = append(self.waiters[:i], self.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (self *Monitor) Notify(thread interface{}) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.owner != thread {
+		return false
+	}
+	if len(self.waiters) > 0 {
+		self.waiters[0] <- struct{}{}
+		self.waiters = self.waiters[1:]
+	}
+	return true
+}
+
+func (self *Monitor) NotifyAll(thread interface{}) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.owner != thread {
+		return false
+	}
+	for _, waiter := range self.waiters {
+		waiter <- struct{}{}
+	}
+	self.waiters = nil
+	return true
+}
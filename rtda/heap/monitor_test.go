@@ -0,0 +1,133 @@
+package heap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonitorReentrant(t *testing.T) {
+	m := newMonitor()
+	a, b := new(int), new(int)
+	if !m.TryEnter(a) || !m.TryEnter(a) {
+		t.Fatal("owner cannot re-enter")
+	}
+	if m.TryEnter(b) {
+		t.Fatal("second thread entered an owned monitor")
+	}
+	if m.Exit(b) {
+		t.Fatal("non-owner exited the monitor")
+	}
+	m.Exit(a)
+	if !m.IsOwner(a) {
+		t.Fatal("monitor released before the last exit")
+	}
+	m.Exit(a)
+	if m.Owner() != nil || !m.TryEnter(b) {
+		t.Fatal("monitor not released after the last exit")
+	}
+}
+
+func TestMonitorEnterBlocks(t *testing.T) {
+	m := newMonitor()
+	a, b := new(int), new(int)
+	m.Enter(a)
+	entered := make(chan struct{})
+	go func() {
+		m.Enter(b)
+		close(entered)
+	}()
+	select {
+	case <-entered:
+		t.Fatal("entered a monitor held by another thread")
+	case <-time.After(20 * time.Millisecond):
+	}
+	m.Exit(a)
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Fatal("not woken after the owner exited")
+	}
+}
+
+func TestMonitorWaitNotify(t *testing.T) {
+	m := newMonitor()
+	a, b := new(int), new(int)
+	if m.Wait(a, 0) || m.Notify(a) || m.NotifyAll(a) {
+		t.Fatal("wait/notify allowed without owning the monitor")
+	}
+
+	m.Enter(a)
+	m.Enter(a)
+	done := make(chan struct{})
+	go func() {
+		m.Wait(a, 0) // 释放两层重入 唤醒后恢复
+		if !m.IsOwner(a) {
+			t.Error("waiter does not own the monitor after waking")
+		}
+		m.Exit(a)
+		if !m.IsOwner(a) {
+			t.Error("entry count not restored after wait")
+		}
+		m.Exit(a)
+		close(done)
+	}()
+	for !m.TryEnter(b) {
+		time.Sleep(time.Millisecond)
+	}
+	m.Notify(b)
+	m.Exit(b)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken by notify")
+	}
+}
+
+func TestMonitorWaitTimeout(t *testing.T) {
+	m := newMonitor()
+	a := new(int)
+	m.Enter(a)
+	start := time.Now()
+	if !m.Wait(a, 20*time.Millisecond) {
+		t.Fatal("owner cannot wait")
+	}
+	if time.Since(start) < 20*time.Millisecond || !m.IsOwner(a) {
+		t.Fatal("timed wait returned early or without the monitor")
+	}
+}
+
+func TestMonitorNotifyAll(t *testing.T) {
+	m := newMonitor()
+	owner := new(int)
+	const n = 4
+	woken := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			self := new(int)
+			m.Enter(self)
+			m.Wait(self, 0)
+			m.Exit(self)
+			woken <- struct{}{}
+		}()
+	}
+	for {
+		m.Enter(owner)
+		m.mu.Lock()
+		waiting := len(m.waiters)
+		m.mu.Unlock()
+		if waiting == n {
+			break
+		}
+		m.Exit(owner)
+		time.Sleep(time.Millisecond)
+	}
+	m.NotifyAll(owner)
+	m.Exit(owner)
+	for i := 0; i < n; i++ {
+		select {
+		case <-woken:
+		case <-time.After(time.Second):
+			t.Fatal("not all waiters woken by notifyAll")
+		}
+	}
+}
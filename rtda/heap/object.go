@@ -1,28 +1,46 @@
 package heap
 
+import (
+	"math"
+	"unsafe"
+)
+
+// 基本类型的实例字段按Field.slotId给出的字节偏移放在prims里 按本机字节序
+// 引用类型的实例字段按slotId给出的下标放在refs里 布局见calcInstanceFieldSlotIds
+// 数组也一样 基本类型数组的元素放在prims里 引用类型数组的元素放在refs里
 type Object struct {
-	class *Class
-	data interface{}
-	extra interface{} // 记录Object结构体实例的额外信息
+	class   *Class
+	prims   []byte
+	refs    []*Object
+	extra   interface{} // 记录Object结构体实例的额外信息
+	monitor *Monitor    // 第一次使用时才创建
 }
 
 func newObject(class *Class) *Object {
-	return &Object{
-		class: class,
-		data: newSlots(class.instanceSlotCount), // 分配类实例内存
-	}
+	heap := class.loader.heap
+	size := instanceSize(class)
+	heap.charge(size)
+	return heap.newObject(class, newPrims(class.instancePrimBytes), newRefs(class.instanceRefCount)) // 分配类实例内存
 }
 
-func (self *Object) Class() *Class {
-	return self.class
+// 按8字节对齐分配 这样long、double字段和数组元素都是对齐的
+func newPrims(bytes uint) []byte {
+	if bytes == 0 {
+		return nil
+	}
+	words := make([]uint64, (bytes+7)/8)
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(words))), bytes)
 }
 
-func (self *Object) Data() interface{} {
-	return self.data
+func newRefs(count uint) []*Object {
+	if count == 0 {
+		return nil
+	}
+	return make([]*Object, count)
 }
 
-func (self *Object) Fields() Slots {
-	return self.data.(Slots)
+func (self *Object) Class() *Class {
+	return self.class
 }
 
 func (self *Object) Extra() interface{} {
@@ -33,31 +51,92 @@ func (self *Object) SetExtra(extra interface{}) {
 	self.extra = extra
 }
 
+// 调用者持有全局解释器锁 所以这里不需要额外同步
+func (self *Object) Monitor() *Monitor {
+	if self.monitor == nil {
+		self.monitor = newMonitor()
+	}
+	return self.monitor
+}
+
 func (self *Object) IsInstanceOf(class *Class) bool {
 	return class.IsAssignableFrom(self.class)
 }
 
+// 实例字段 offset是Field.SlotId()
+// boolean、byte、char、short和int一样占4个字节 由解释器统一按int读写
+// 字段都是对齐的 直接按本机字节序读写 先检查最后一个字节没有越界
+func (self *Object) GetIntField(offset uint) int32 {
+	_ = self.prims[offset+3]
+	return *(*int32)(unsafe.Pointer(&self.prims[offset]))
+}
+
+func (self *Object) SetIntField(offset uint, val int32) {
+	_ = self.prims[offset+3]
+	*(*int32)(unsafe.Pointer(&self.prims[offset])) = val
+}
+
+func (self *Object) GetFloatField(offset uint) float32 {
+	return math.Float32frombits(uint32(self.GetIntField(offset)))
+}
+
+func (self *Object) SetFloatField(offset uint, val float32) {
+	self.SetIntField(offset, int32(math.Float32bits(val)))
+}
+
+func (self *Object) GetLongField(offset uint) int64 {
+	_ = self.prims[offset+7]
+	return *(*int64)(unsafe.Pointer(&self.prims[offset]))
+}
+
+func (self *Object) SetLongField(offset uint, val int64) {
+	_ = self.prims[offset+7]
+	*(*int64)(unsafe.Pointer(&self.prims[offset])) = val
+}
+
+func (self *Object) GetDoubleField(offset uint) float64 {
+	return math.Float64frombits(uint64(self.GetLongField(offset)))
+}
+
+func (self *Object) SetDoubleField(offset uint, val float64) {
+	self.SetLongField(offset, int64(math.Float64bits(val)))
+}
+
+func (self *Object) GetRefField(index uint) *Object {
+	return self.refs[index]
+}
+
+func (self *Object) SetRefField(index uint, ref *Object) {
+	self.refs[index] = ref
+}
+
 // reflection
 func (self *Object) GetRefVar(name, descriptor string) *Object {
 	field := self.class.getField(name, descriptor, false)
-	slots := self.data.(Slots)
-	return slots.GetRef(field.slotId)
+	return self.GetRefField(field.slotId)
 }
 
 func (self *Object) SetRefVar(name, descriptor string, ref *Object) {
 	field := self.class.getField(name, descriptor, false)
-	slots := self.data.(Slots)
-	slots.SetRef(field.slotId, ref)
+	self.SetRefField(field.slotId, ref)
 }
 
 func (self *Object) SetIntVar(name, descriptor string, val int32) {
 	field := self.class.getField(name, descriptor, false)
-	slots := self.data.(Slots)
-	slots.SetInt(field.slotId, val)
+	self.SetIntField(field.slotId, val)
+}
+
+func (self *Object) SetLongVar(name, descriptor string, val int64) {
+	field := self.class.getField(name, descriptor, false)
+	self.SetLongField(field.slotId, val)
 }
 
 func (self *Object) GetIntVar(name, descriptor string) int32 {
 	field := self.class.getField(name, descriptor, false)
-	slots := self.data.(Slots)
-	return slots.GetInt(field.slotId)
+	return self.GetIntField(field.slotId)
+}
+
+func (self *Object) GetLongVar(name, descriptor string) int64 {
+	field := self.class.getField(name, descriptor, false)
+	return self.GetLongField(field.slotId)
 }
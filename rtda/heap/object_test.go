@@ -0,0 +1,102 @@
+package heap
+
+import (
+	"runtime"
+	"testing"
+)
+
+// 和example里的FieldLayoutTest.Point一样的字段
+// int a, b, c, d, e, f, g, h; boolean flag; char ch; long count; Object next;
+// 线程栈上没有引用 collect()之后已用的字节数归零
+func newPointClass() *Class {
+	loader := &ClassLoader{heap: NewHeap(0), classMap: map[string]*Class{}}
+	loader.heap.bootstrap = loader
+	loader.heap.SetThreadRoots(func() []ThreadRoots { return nil })
+	object := &Class{name: "java/lang/Object", loader: loader}
+	point := &Class{name: "Point", superClass: object, loader: loader}
+	for _, nd := range [][2]string{
+		{"a", "I"}, {"b", "I"}, {"c", "I"}, {"d", "I"},
+		{"e", "I"}, {"f", "I"}, {"g", "I"}, {"h", "I"},
+		{"flag", "Z"}, {"ch", "C"}, {"count", "J"}, {"next", "Ljava/lang/Object;"},
+	} {
+		field := &Field{}
+		field.class = point
+		field.name = nd[0]
+		field.descriptor = nd[1]
+		point.fields = append(point.fields, field)
+	}
+	calcInstanceFieldSlotIds(point)
+	return point
+}
+
+func pointFieldOffsets(point *Class) (a, b, count, next uint) {
+	return point.getField("a", "I", false).slotId,
+		point.getField("b", "I", false).slotId,
+		point.getField("count", "J", false).slotId,
+		point.getField("next", "Ljava/lang/Object;", false).slotId
+}
+
+// 对象由Go分配 实际占用的内存包括size class的取整 和Heap记账用的instanceSize不一样
+// 用MemStats量出每个对象实际分配了多少字节 估计只是下限
+// 分配次数包括Object、prims和refs
+func TestObjectMemory(t *testing.T) {
+	point := newPointClass()
+	allocs := testing.AllocsPerRun(100, func() { point.NewObject() })
+
+	const n = 10000
+	objects := make([]*Object, n)
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for i := range objects {
+		objects[i] = point.NewObject()
+	}
+	runtime.ReadMemStats(&after)
+	measured := int64(after.TotalAlloc-before.TotalAlloc) / n
+	estimated := instanceSize(point)
+	t.Logf("bytes/object: measured %d, estimated %d, %v allocations", measured, estimated, allocs)
+	if measured < estimated {
+		t.Errorf("measured %d bytes/object, less than the estimated %d", measured, estimated)
+	}
+	runtime.KeepAlive(objects)
+}
+
+// p.a + p.b; p.count; p.next
+func BenchmarkGetField(b *testing.B) {
+	point := newPointClass()
+	a, bb, count, next := pointFieldOffsets(point)
+	p := point.NewObject()
+	p.SetRefField(next, p)
+	b.ReportAllocs()
+	b.ResetTimer()
+	var sum int64
+	for i := 0; i < b.N; i++ {
+		sum += int64(p.GetIntField(a)+p.GetIntField(bb)) + p.GetLongField(count)
+		p = p.GetRefField(next)
+	}
+	runtime.KeepAlive(sum)
+}
+
+// p.a = i; p.count = i; p.next = p
+func BenchmarkPutField(b *testing.B) {
+	point := newPointClass()
+	a, _, count, next := pointFieldOffsets(point)
+	p := point.NewObject()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.SetIntField(a, int32(i))
+		p.SetLongField(count, int64(i))
+		p.SetRefField(next, p)
+	}
+}
+
+// B/op是Go实际分配的字节数 estimated-B/op是Heap记账的大小
+func BenchmarkNewObject(b *testing.B) {
+	point := newPointClass()
+	b.ReportAllocs()
+	b.ReportMetric(float64(instanceSize(point)), "estimated-B/op")
+	for i := 0; i < b.N; i++ {
+		point.NewObject()
+	}
+}
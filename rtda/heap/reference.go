@@ -0,0 +1,169 @@
+package heap
+
+import (
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// java.lang.ref.Reference的子类型
+const (
+	REF_NONE = iota
+	REF_SOFT
+	REF_WEAK
+	REF_PHANTOM
+	REF_FINAL
+)
+
+var referenceKinds = map[string]uint8{
+	"java/lang/ref/SoftReference":    REF_SOFT,
+	"java/lang/ref/WeakReference":    REF_WEAK,
+	"java/lang/ref/PhantomReference": REF_PHANTOM,
+	"java/lang/ref/FinalReference":   REF_FINAL,
+}
+
+// Reference.referent不放在字段槽里 而是从Go这边弱引用
+// 软引用额外保留一个强引用 内存不足时才清除
+type referentHolder struct {
+	referent weak.Pointer[Object]
+	strong   *Object
+}
+
+// referent被Go的GC回收之后 等待加入ReferenceQueue的引用对象
+type PendingReference struct {
+	ref    weak.Pointer[Object]
+	holder weak.Pointer[referentHolder]
+}
+
+// 引用对象本身还活着 并且referent没有被重新设置或者clear()过时返回引用对象
+// 调用者必须持有全局解释器锁
+func (self PendingReference) Reference() *Object {
+	ref := self.ref.Value()
+	if ref == nil {
+		return nil
+	}
+	if holder := self.holder.Value(); holder == nil || ref.extra != holder {
+		return nil
+	}
+	ref.extra = nil
+	return ref
+}
+
+type referenceQueue struct {
+	mu      sync.Mutex
+	pending []PendingReference
+	signal  chan struct{}
+	softs   []weak.Pointer[referentHolder] // 所有软引用 内存不足时清除
+}
+
+func newReferenceQueue() *referenceQueue {
+	return &referenceQueue{signal: make(chan struct{}, 1)}
+}
+
+func referenceKind(class *Class) uint8 {
+	if kind, ok := referenceKinds[class.name]; ok {
+		return kind
+	}
+	if class.superClass != nil {
+		return class.superClass.refKind
+	}
+	return REF_NONE
+}
+
+func (self *Class) IsReference() bool {
+	return self.refKind != REF_NONE
+}
+
+func (self *Field) IsReferent() bool {
+	return self.referent
+}
+
+// Reference.get()
+func (self *Object) Referent(field *Field) *Object {
+	if self.class.refKind == REF_FINAL {
+		return self.GetRefField(field.slotId)
+	}
+	if holder, ok := self.extra.(*referentHolder); ok {
+		return holder.referent.Value()
+	}
+	return nil
+}
+
+// Reference构造函数和Reference.clear()
+// FinalReference由虚拟机管理 仍然是强引用
+func (self *Object) SetReferent(field *Field, referent *Object) {
+	if self.class.refKind == REF_FINAL {
+		self.SetRefField(field.slotId, referent)
+		return
+	}
+	if referent == nil {
+		self.extra = nil
+		return
+	}
+
+	holder := &referentHolder{referent: weak.Make(referent)}
+	self.extra = holder
+	heap := self.class.loader.heap
+	if self.class.refKind == REF_SOFT {
+		holder.strong = referent
+		heap.refs.addSoft(holder)
+	}
+	runtime.AddCleanup(referent, heap.refs.enqueue, PendingReference{
+		ref:    weak.Make(self),
+		holder: weak.Make(holder),
+	})
+}
+
+// 在cleanup goroutine中执行 不能访问Java对象
+func (self *referenceQueue) enqueue(pending PendingReference) {
+	self.mu.Lock()
+	self.pending = append(self.pending, pending)
+	self.mu.Unlock()
+	select {
+	case self.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (self *referenceQueue) addSoft(holder *referentHolder) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if len(self.softs) == cap(self.softs) {
+		// 扩容之前先去掉已经回收的
+		alive := self.softs[:0]
+		for _, soft := range self.softs {
+			if soft.Value() != nil {
+				alive = append(alive, soft)
+			}
+		}
+		self.softs = alive
+	}
+	self.softs = append(self.softs, weak.Make(holder))
+}
+
+// 抛出OutOfMemoryError之前清除所有软引用
+func (self *referenceQueue) clearSofts() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for _, soft := range self.softs {
+		if holder := soft.Value(); holder != nil {
+			holder.strong = nil
+		}
+	}
+	self.softs = nil
+}
+
+// 阻塞直到有referent被回收 不需要持有全局解释器锁
+func (self *Heap) WaitPendingReferences() []PendingReference {
+	refs := self.refs
+	for {
+		<-refs.signal
+		refs.mu.Lock()
+		pending := refs.pending
+		refs.pending = nil
+		refs.mu.Unlock()
+		if len(pending) > 0 {
+			return pending
+		}
+	}
+}
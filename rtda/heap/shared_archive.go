@@ -0,0 +1,461 @@
+package heap
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"jvm/classpath"
+	"os"
+	"path/filepath"
+)
+
+// 类数据共享(-Xshare)
+// -Xshare:dump 把initVM期间启动类加载器从jar包加载的类的解析和链接结果写入归档文件
+// -Xshare:auto 启动时读回归档 这些类不再读jar包和解析class文件
+// 归档里记录了启动类路径下每个jar包的大小和修改时间 任何一个变化了归档就作废
+
+const sharedArchiveVersion = 2
+
+type SharedArchive struct {
+	path    string
+	dumping bool
+	classes map[string]*sharedClass // -Xshare:auto 归档中的类
+	loaded  []*Class                // -Xshare:dump 按加载顺序记录的类
+}
+
+type sharedHeader struct {
+	Version int
+	Jars    []sharedJar
+}
+
+type sharedJar struct {
+	Path    string
+	Size    int64
+	ModTime int64
+}
+
+// gob只能序列化导出的字段 所以运行时的结构另外对应一套镜像
+type sharedClass struct {
+	AccessFlags       uint16
+	Name              string
+	SuperClassName    string
+	InterfaceNames    []string
+	Constants         []sharedConstant
+	Fields            []sharedField
+	Methods           []sharedMethod
+	SourceFile        string
+	InstancePrimBytes uint
+	InstanceRefCount  uint
+	StaticSlotCount   uint
+}
+
+const (
+	sharedNone = iota
+	sharedInt
+	sharedFloat
+	sharedLong
+	sharedDouble
+	sharedString
+	sharedClassRef
+	sharedFieldRef
+	sharedMethodRef
+	sharedInterfaceMethodRef
+)
+
+type sharedConstant struct {
+	Tag        uint8
+	Int        int32
+	Float      float32
+	Long       int64
+	Double     float64
+	String     string
+	ClassName  string
+	Name       string
+	Descriptor string
+}
+
+type sharedMember struct {
+	AccessFlags    uint16
+	Name           string
+	Descriptor     string
+	Signature      string
+	AnnotationData []byte
+}
+
+type sharedField struct {
+	Member          sharedMember
+	ConstValueIndex uint
+	SlotId          uint
+}
+
+type sharedMethod struct {
+	Member                  sharedMember
+	MaxStack                uint
+	MaxLocals               uint
+	Code                    []byte
+	ExceptionTable          []sharedHandler
+	LineNumberTable         []uint16 // startPc和lineNumber交替存放
+	ExceptionIndexTable     []uint16
+	ParameterAnnotationData []byte
+	AnnotationDefaultData   []byte
+}
+
+type sharedHandler struct {
+	StartPc   int
+	EndPc     int
+	HandlerPc int
+	CatchType uint // 0表示catch-all
+}
+
+// -Xshare:dump 创建一个空的归档 类加载之后调用DumpSharedArchive写入
+func NewSharedArchive(path string) *SharedArchive {
+	return &SharedArchive{path: path, dumping: true}
+}
+
+// -Xshare:auto 读取归档 文件不存在时返回nil
+// 启动类路径下的jar包和归档里记录的不一致时返回错误
+func OpenSharedArchive(path string, cp *classpath.Classpath) (*SharedArchive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+	header := sharedHeader{}
+	if err := decoder.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Version != sharedArchiveVersion {
+		return nil, fmt.Errorf("unsupported version %d", header.Version)
+	}
+	jars, err := statJars(cp.BootJars())
+	if err != nil {
+		return nil, err
+	}
+	if len(jars) != len(header.Jars) {
+		return nil, errors.New("boot classpath has changed")
+	}
+	for i, jar := range jars {
+		if jar != header.Jars[i] {
+			return nil, errors.New(jar.Path + " has changed")
+		}
+	}
+
+	var classes []*sharedClass
+	if err := decoder.Decode(&classes); err != nil {
+		return nil, err
+	}
+	archive := &SharedArchive{path: path, classes: make(map[string]*sharedClass, len(classes))}
+	for _, class := range classes {
+		archive.classes[class.Name] = class
+	}
+	return archive, nil
+}
+
+func statJars(paths []string) ([]sharedJar, error) {
+	jars := make([]sharedJar, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		jars[i] = sharedJar{Path: path, Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+	}
+	return jars, nil
+}
+
+func (self *ClassLoader) SharedArchivePath() string {
+	if share := self.Bootstrap().share; share != nil {
+		return share.path
+	}
+	return ""
+}
+
+// 从归档中取出类 得到的类和parseClass的结果一样 还没有注册和链接
+func (self *SharedArchive) lookup(name string) *Class {
+	if self == nil || self.classes == nil {
+		return nil
+	}
+	sc, ok := self.classes[name]
+	if !ok {
+		return nil
+	}
+	delete(self.classes, name) // 每个类只会被启动类加载器加载一次
+	return sc.restore()
+}
+
+func (self *SharedArchive) contains(name string) bool {
+	if self == nil || self.classes == nil {
+		return false
+	}
+	_, ok := self.classes[name]
+	return ok
+}
+
+// 只记录从启动类路径下的jar包加载的类 其他类无法校验是否过期
+func (self *SharedArchive) record(class *Class, entry classpath.Entry) {
+	if self == nil || !self.dumping {
+		return
+	}
+	for _, jar := range class.loader.cp.BootJars() {
+		if entry.String() == jar {
+			self.loaded = append(self.loaded, class)
+			return
+		}
+	}
+}
+
+// 把启动类加载器到目前为止加载的类写入归档 返回写入的类的个数
+func (self *ClassLoader) DumpSharedArchive() (int, error) {
+	loader := self.Bootstrap()
+	archive := loader.share
+	if archive == nil || !archive.dumping {
+		return 0, errors.New("-Xshare:dump is not enabled")
+	}
+	jars, err := statJars(loader.cp.BootJars())
+	if err != nil {
+		return 0, err
+	}
+	classes := make([]*sharedClass, len(archive.loaded))
+	for i, class := range archive.loaded {
+		classes[i] = class.dump()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(archive.path), 0755); err != nil {
+		return 0, err
+	}
+	// 先写临时文件再改名 避免别的虚拟机读到写了一半的归档
+	tmp := archive.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	encoder := gob.NewEncoder(file)
+	err = encoder.Encode(sharedHeader{Version: sharedArchiveVersion, Jars: jars})
+	if err == nil {
+		err = encoder.Encode(classes)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, archive.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return len(classes), nil
+}
+
+func (self *Class) dump() *sharedClass {
+	sc := &sharedClass{
+		AccessFlags:       self.accessFlags,
+		Name:              self.name,
+		SuperClassName:    self.superClassName,
+		InterfaceNames:    self.interfaceNames,
+		Constants:         self.constantPool.dump(),
+		Fields:            make([]sharedField, len(self.fields)),
+		Methods:           make([]sharedMethod, len(self.methods)),
+		SourceFile:        self.sourceFile,
+		InstancePrimBytes: self.instancePrimBytes,
+		InstanceRefCount:  self.instanceRefCount,
+		StaticSlotCount:   self.staticSlotCount,
+	}
+	for i, field := range self.fields {
+		sc.Fields[i] = sharedField{
+			Member:          field.ClassMember.dump(),
+			ConstValueIndex: field.constValueIndex,
+			SlotId:          field.slotId,
+		}
+	}
+	for i, method := range self.methods {
+		sc.Methods[i] = method.dump()
+	}
+	return sc
+}
+
+func (self *sharedClass) restore() *Class {
+	class := &Class{
+		accessFlags:       self.AccessFlags,
+		name:              self.Name,
+		superClassName:    self.SuperClassName,
+		interfaceNames:    self.InterfaceNames,
+		sourceFile:        self.SourceFile,
+		instancePrimBytes: self.InstancePrimBytes,
+		instanceRefCount:  self.InstanceRefCount,
+		staticSlotCount:   self.StaticSlotCount,
+		shared:            true,
+	}
+	class.constantPool = restoreConstantPool(class, self.Constants)
+	class.fields = make([]*Field, len(self.Fields))
+	for i, sf := range self.Fields {
+		field := &Field{constValueIndex: sf.ConstValueIndex, slotId: sf.SlotId}
+		field.ClassMember = sf.Member.restore(class)
+		class.fields[i] = field
+	}
+	class.methods = make([]*Method, len(self.Methods))
+	for i := range self.Methods {
+		class.methods[i] = self.Methods[i].restore(class)
+	}
+	return class
+}
+
+func (self *ClassMember) dump() sharedMember {
+	return sharedMember{
+		AccessFlags:    self.accessFlags,
+		Name:           self.name,
+		Descriptor:     self.descriptor,
+		Signature:      self.signature,
+		AnnotationData: self.annotationData,
+	}
+}
+
+func (self *sharedMember) restore(class *Class) ClassMember {
+	return ClassMember{
+		accessFlags:    self.AccessFlags,
+		name:           self.Name,
+		descriptor:     self.Descriptor,
+		signature:      self.Signature,
+		annotationData: self.AnnotationData,
+		class:          class,
+	}
+}
+
+func (self *Method) dump() sharedMethod {
+	sm := sharedMethod{
+		Member:                  self.ClassMember.dump(),
+		MaxStack:                self.maxStack,
+		MaxLocals:               self.maxLocals,
+		Code:                    self.code,
+		ExceptionTable:          make([]sharedHandler, len(self.exceptionTable)),
+		ExceptionIndexTable:     self.exceptionIndexTable,
+		ParameterAnnotationData: self.parameterAnnotationData,
+		AnnotationDefaultData:   self.annotationDefaultData,
+	}
+	for i, handler := range self.exceptionTable {
+		sm.ExceptionTable[i] = sharedHandler{
+			StartPc:   handler.startPc,
+			EndPc:     handler.endPC,
+			HandlerPc: handler.handlerPc,
+			CatchType: self.class.constantPool.indexOf(handler.catchType),
+		}
+	}
+	for _, entry := range self.lineNumberTable {
+		sm.LineNumberTable = append(sm.LineNumberTable, entry.startPc, entry.lineNumber)
+	}
+	return sm
+}
+
+// 本地方法注入的字节码已经在Code里了 不需要再注入
+func (self *sharedMethod) restore(class *Class) *Method {
+	method := &Method{
+		maxStack:                self.MaxStack,
+		maxLocals:               self.MaxLocals,
+		code:                    self.Code,
+		exceptionIndexTable:     self.ExceptionIndexTable,
+		parameterAnnotationData: self.ParameterAnnotationData,
+		annotationDefaultData:   self.AnnotationDefaultData,
+	}
+	method.ClassMember = self.Member.restore(class)
+	method.exceptionTable = make(ExceptionTable, len(self.ExceptionTable))
+	for i, sh := range self.ExceptionTable {
+		method.exceptionTable[i] = &ExceptionHandler{
+			startPc:   sh.StartPc,
+			endPC:     sh.EndPc,
+			handlerPc: sh.HandlerPc,
+			catchType: getCatchType(sh.CatchType, class.constantPool),
+		}
+	}
+	for i := 0; i+1 < len(self.LineNumberTable); i += 2 {
+		method.lineNumberTable = append(method.lineNumberTable,
+			lineNumberEntry{self.LineNumberTable[i], self.LineNumberTable[i+1]})
+	}
+	md := parseMethodDescriptor(method.descriptor)
+	method.parsedDescriptor = md
+	method.calcArgSlotCount(md.parameterTypes)
+	return method
+}
+
+func (self *ConstantPool) dump() []sharedConstant {
+	consts := make([]sharedConstant, len(self.consts))
+	for i, c := range self.consts {
+		switch c := c.(type) {
+		case int32:
+			consts[i] = sharedConstant{Tag: sharedInt, Int: c}
+		case float32:
+			consts[i] = sharedConstant{Tag: sharedFloat, Float: c}
+		case int64:
+			consts[i] = sharedConstant{Tag: sharedLong, Long: c}
+		case float64:
+			consts[i] = sharedConstant{Tag: sharedDouble, Double: c}
+		case string:
+			consts[i] = sharedConstant{Tag: sharedString, String: c}
+		case *ClassRef:
+			consts[i] = sharedConstant{Tag: sharedClassRef, ClassName: c.className}
+		case *FieldRef:
+			consts[i] = c.MemberRef.dump(sharedFieldRef)
+		case *MethodRef:
+			consts[i] = c.MemberRef.dump(sharedMethodRef)
+		case *InterfaceMethodRef:
+			consts[i] = c.MemberRef.dump(sharedInterfaceMethodRef)
+		}
+	}
+	return consts
+}
+
+func (self *MemberRef) dump(tag uint8) sharedConstant {
+	return sharedConstant{
+		Tag:        tag,
+		ClassName:  self.className,
+		Name:       self.name,
+		Descriptor: self.descriptor,
+	}
+}
+
+func restoreConstantPool(class *Class, sharedConsts []sharedConstant) *ConstantPool {
+	consts := make([]Constant, len(sharedConsts))
+	cp := &ConstantPool{class, consts}
+	for i, sc := range sharedConsts {
+		symRef := SymRef{cp: cp, className: sc.ClassName}
+		memberRef := MemberRef{SymRef: symRef, name: sc.Name, descriptor: sc.Descriptor}
+		switch sc.Tag {
+		case sharedInt:
+			consts[i] = sc.Int
+		case sharedFloat:
+			consts[i] = sc.Float
+		case sharedLong:
+			consts[i] = sc.Long
+		case sharedDouble:
+			consts[i] = sc.Double
+		case sharedString:
+			consts[i] = sc.String
+		case sharedClassRef:
+			consts[i] = &ClassRef{symRef}
+		case sharedFieldRef:
+			consts[i] = &FieldRef{MemberRef: memberRef}
+		case sharedMethodRef:
+			consts[i] = &MethodRef{MemberRef: memberRef}
+		case sharedInterfaceMethodRef:
+			consts[i] = &InterfaceMethodRef{MemberRef: memberRef}
+		}
+	}
+	return cp
+}
+
+// 异常处理表只保存了ClassRef的指针 序列化时找回它在常量池中的索引
+func (self *ConstantPool) indexOf(ref *ClassRef) uint {
+	if ref == nil {
+		return 0
+	}
+	for i, c := range self.consts {
+		if c == Constant(ref) {
+			return uint(i)
+		}
+	}
+	panic("class ref not in constant pool: " + ref.className)
+}
@@ -13,6 +13,15 @@ var (
 		},
 		code: _returnCode,
 	}
+	// Go代码同步调用Java方法时的栈底 异常在这里停止栈展开
+	_catchMethod = &Method{
+		ClassMember: ClassMember{
+			accessFlags: ACC_STATIC,
+			name:        "<catch>",
+			class:       _shimClass,
+		},
+		code: _returnCode,
+	}
 	_athrowMethod = &Method{
 		ClassMember: ClassMember{
 			accessFlags: ACC_STATIC,
@@ -26,3 +35,11 @@ var (
 func ShimReturnMethod() *Method {
 	return _returnMethod
 }
+
+func ShimAthrowMethod() *Method {
+	return _athrowMethod
+}
+
+func ShimCatchMethod() *Method {
+	return _catchMethod
+}
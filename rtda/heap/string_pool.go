@@ -1,53 +1,172 @@
 package heap
 
-import "unicode/utf16"
+import (
+	"runtime"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+	"weak"
+)
 
-var internedStrings = map[string]*Object{}
+// 字符串池 每个虚拟机一个 只有字符串字面量和String.intern()返回的字符串进池
+// 池只弱引用字符串对象 没有其他地方引用时可以被回收
+// 否则卸载的类的字符串常量会一直留在池里
+// 回收之后由cleanup goroutine删除对应的项 所以需要加锁
+type stringTable struct {
+	lock    sync.RWMutex
+	strings map[string]weak.Pointer[Object]
+}
 
-// go string -> java.lang.String
-func JString(loader *ClassLoader, goStr string) *Object {
-	if internedStr, ok := internedStrings[goStr];ok {
-		return internedStr
+func newStringTable() *stringTable {
+	return &stringTable{strings: map[string]weak.Pointer[Object]{}}
+}
+
+func (self *stringTable) lookup(goStr string) *Object {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.strings[goStr].Value()
+}
+
+// 池里已经有活着的字符串就返回它 否则放入jStr
+// 查找和放入之间可能有别的线程放入了同样的字符串 所以放入时要再查一次
+func (self *stringTable) intern(goStr string, jStr *Object) *Object {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if interned := self.strings[goStr].Value(); interned != nil {
+		return interned
 	}
+	self.strings[goStr] = weak.Make(jStr)
+	runtime.AddCleanup(jStr, self.remove, goStr)
+	return jStr
+}
 
-	chars := stringToUtf16(goStr)
-	jChars := &Object{
-		loader.LoadClass("[C"),
-		chars,
-		nil,
+func (self *stringTable) remove(goStr string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.strings[goStr].Value() == nil { // 可能已经换成了新的字符串对象
+		delete(self.strings, goStr)
 	}
+}
 
-	jStr := loader.LoadClass("java/lang/String").NewObject()
-	jStr.SetRefVar("value", "[C", jChars)
+// 池里还活着的字符串 供堆转储作为根
+func (self *stringTable) objects() []*Object {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	strs := make([]*Object, 0, len(self.strings))
+	for _, ptr := range self.strings {
+		if str := ptr.Value(); str != nil {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
 
-	internedStrings[goStr] = jStr
-	return jStr
+// java.lang.String的字段 加载String时查找一次 GoString和JString不用每次按名字找
+// 老版本的String还有offset和count字段 新版本没有 对应的指针为nil
+type stringFields struct {
+	value  *Field
+	offset *Field
+	count  *Field
 }
 
-func GoString(jStr *Object) string {
-	charArr := jStr.GetRefVar("value", "[C")
-	return utf16ToString(charArr.Chars())
+func newStringFields(class *Class) *stringFields {
+	return &stringFields{
+		value:  class.getField("value", "[C", false),
+		offset: class.getField("offset", "I", false),
+		count:  class.getField("count", "I", false),
+	}
 }
 
-// utf8  -> utf16
-func stringToUtf16(s string) []uint16 {
-	runes := []rune(s)
-	return utf16.Encode(runes)
+// go string -> java.lang.String
+// 每次都创建新的字符串对象 不进池
+// java.lang.String总是由启动类加载器加载
+func JString(loader *ClassLoader, goStr string) *Object {
+	loader = loader.Bootstrap()
+	jChars := newJChars(loader, goStr)
+
+	jStr := loader.LoadClass("java/lang/String").NewObject()
+	fields := jStr.class.stringFields
+	jStr.SetRefField(fields.value.slotId, jChars)
+	if fields.count != nil {
+		jStr.SetIntField(fields.count.slotId, jChars.ArrayLength())
+	}
+	return jStr
 }
 
-// utf16 -> utf8
-func utf16ToString(s []uint16) string {
-	runes := utf16.Decode(s)
-	return string(runes)
+// 字符串字面量 ldc和static final字段的ConstantValue
+// 反射得到的类名、字段名和方法名也和HotSpot一样进池 Class.searchFields()用==比较名字
+func InternedJString(loader *ClassLoader, goStr string) *Object {
+	table := loader.heap.strings
+	if internedStr := table.lookup(goStr); internedStr != nil {
+		return internedStr
+	}
+	return table.intern(goStr, JString(loader, goStr))
 }
 
-// TODO
+// String.intern()
 func InternString(jStr *Object) *Object {
+	table := jStr.class.loader.heap.strings
 	goStr := GoString(jStr)
-	if internedStr, ok := internedStrings[goStr]; ok {
+	if internedStr := table.lookup(goStr); internedStr != nil {
 		return internedStr
 	}
+	return table.intern(goStr, jStr)
+}
 
-	internedStrings[goStr] = jStr
-	return jStr
+// java.lang.String -> go string
+// null返回空串 老版本的String只用value里从offset开始的count个字符
+func GoString(jStr *Object) string {
+	if jStr == nil {
+		return ""
+	}
+	fields := jStr.class.stringFields
+	charArr := jStr.GetRefField(fields.value.slotId)
+	if charArr == nil {
+		return ""
+	}
+	chars := charArr.Chars()
+	if fields.count != nil {
+		offset := int32(0)
+		if fields.offset != nil {
+			offset = jStr.GetIntField(fields.offset.slotId)
+		}
+		n := jStr.GetIntField(fields.count.slotId)
+		if offset < 0 || n < 0 || int64(offset)+int64(n) > int64(len(chars)) { // 32位平台上int会溢出
+			return ""
+		}
+		chars = chars[offset : offset+n]
+	}
+	return utf16ToString(chars)
+}
+
+// utf8 -> utf16
+// 全是ASCII字符时直接逐字节写进char数组 不经过[]rune
+func newJChars(loader *ClassLoader, s string) *Object {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return NewCharArray(loader, utf16.Encode([]rune(s)))
+		}
+	}
+	jChars := loader.LoadClass("[C").NewArray(uint(len(s)))
+	chars := jChars.Chars()
+	for i := 0; i < len(s); i++ {
+		chars[i] = uint16(s[i])
+	}
+	return jChars
+}
+
+// utf16 -> utf8
+// 全是ASCII字符时逐字节转换 不经过[]rune
+func utf16ToString(s []uint16) string {
+	for _, c := range s {
+		if c >= utf8.RuneSelf {
+			return string(utf16.Decode(s))
+		}
+	}
+	buf := make([]byte, len(s))
+	for i, c := range s {
+		buf[i] = byte(c)
+	}
+	return unsafe.String(unsafe.SliceData(buf), len(buf)) // buf不再修改 不用再复制一次
 }
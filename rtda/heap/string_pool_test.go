@@ -0,0 +1,111 @@
+package heap
+
+import (
+	"runtime"
+	"testing"
+)
+
+// 不经过类加载器 搭一个java.lang.String
+// 老版本的String还有offset和count字段
+func newStringTestLoader(oldLayout bool) *ClassLoader {
+	loader := &ClassLoader{heap: NewHeap(0), classMap: map[string]*Class{}}
+	object := &Class{name: "java/lang/Object", loader: loader}
+	str := &Class{name: "java/lang/String", superClass: object, loader: loader}
+	fields := [][2]string{{"value", "[C"}, {"hash", "I"}}
+	if oldLayout {
+		fields = append(fields, [2]string{"offset", "I"}, [2]string{"count", "I"})
+	}
+	for _, nd := range fields {
+		field := &Field{}
+		field.class = str
+		field.name = nd[0]
+		field.descriptor = nd[1]
+		str.fields = append(str.fields, field)
+	}
+	calcInstanceFieldSlotIds(str)
+	hackClass(str)
+	loader.classMap[object.name] = object
+	loader.classMap[str.name] = str
+	loader.classMap["[C"] = &Class{name: "[C", superClass: object, loader: loader}
+	return loader
+}
+
+func TestGoStringNull(t *testing.T) {
+	loader := newStringTestLoader(false)
+	if s := GoString(nil); s != "" {
+		t.Errorf("GoString(null) = %q", s)
+	}
+	jStr := loader.classMap["java/lang/String"].NewObject()
+	if s := GoString(jStr); s != "" {
+		t.Errorf("GoString with null value = %q", s)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, oldLayout := range []bool{false, true} {
+		loader := newStringTestLoader(oldLayout)
+		for _, s := range []string{"", "hello", "中文", "café", "\U0001F600 emoji", "a\x00b"} {
+			jStr := JString(loader, s)
+			if got := GoString(jStr); got != s {
+				t.Errorf("oldLayout=%v: GoString(JString(%q)) = %q", oldLayout, s, got)
+			}
+		}
+		// 代理对占两个char
+		if n := JString(loader, "\U0001F600").GetRefVar("value", "[C").ArrayLength(); n != 2 {
+			t.Errorf("oldLayout=%v: U+1F600 takes %d chars, want 2", oldLayout, n)
+		}
+	}
+}
+
+// 老版本的String只用value里从offset开始的count个字符 越界时返回空串而不是让Go panic
+func TestGoStringOffsetCount(t *testing.T) {
+	loader := newStringTestLoader(true)
+	jStr := JString(loader, "hello world")
+	str := jStr.class
+	offset := str.getField("offset", "I", false).slotId
+	count := str.getField("count", "I", false).slotId
+	for _, c := range []struct {
+		offset, count int32
+		want          string
+	}{
+		{0, 11, "hello world"},
+		{6, 5, "world"},
+		{2, 0, ""},
+		{-1, 3, ""},
+		{0, -1, ""},
+		{6, 6, ""},
+		{12, 0, ""},
+		{0x7fffffff, 1, ""},
+	} {
+		jStr.SetIntField(offset, c.offset)
+		jStr.SetIntField(count, c.count)
+		if got := GoString(jStr); got != c.want {
+			t.Errorf("offset=%d count=%d: GoString = %q, want %q", c.offset, c.count, got, c.want)
+		}
+	}
+}
+
+// 字面量和intern()对同样的内容返回同一个对象 JString每次都创建新的对象
+func TestInternIdentity(t *testing.T) {
+	loader := newStringTestLoader(false)
+	literal := InternedJString(loader, "中文")
+	if again := InternedJString(loader, "中文"); again != literal {
+		t.Fatal("InternedJString returned a different object for the same string")
+	}
+	fresh := JString(loader, "中文")
+	if fresh == literal {
+		t.Fatal("JString returned the interned object")
+	}
+	if interned := InternString(fresh); interned != literal {
+		t.Fatal("intern() of an equal string did not return the literal")
+	}
+
+	other := JString(loader, "other")
+	if interned := InternString(other); interned != other {
+		t.Fatal("intern() of a new string did not return the string itself")
+	}
+	if InternedJString(loader, "other") != other {
+		t.Fatal("literal did not find the string put in the table by intern()")
+	}
+	runtime.KeepAlive(literal)
+}
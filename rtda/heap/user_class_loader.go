@@ -0,0 +1,119 @@
+package heap
+
+import (
+	"fmt"
+	"jvm/classfile"
+	"runtime"
+	"strings"
+)
+
+// 用户类加载器加载类时要执行Java代码的loadClass() 由base包注入
+var loadClassByJava func(loader *ClassLoader, name string) *Class
+
+func SetUserClassLoading(fn func(loader *ClassLoader, name string) *Class) {
+	loadClassByJava = fn
+}
+
+func (self *ClassLoader) Bootstrap() *ClassLoader {
+	if self.bootstrap == nil {
+		return self
+	}
+	return self.bootstrap
+}
+
+func (self *ClassLoader) IsBootstrap() bool {
+	return self.jLoader == nil
+}
+
+// 对应的java.lang.ClassLoader实例 启动类加载器返回nil
+func (self *ClassLoader) JLoader() *Object {
+	return self.jLoader
+}
+
+// java.lang.ClassLoader实例对应的类加载器 第一次使用时创建
+// jLoader为nil时返回启动类加载器
+func (self *ClassLoader) UserLoader(jLoader *Object) *ClassLoader {
+	bootstrap := self.Bootstrap()
+	if jLoader == nil {
+		return bootstrap
+	}
+	if loader, ok := jLoader.extra.(*ClassLoader); ok {
+		return loader
+	}
+	loader := &ClassLoader{
+		heap:        bootstrap.heap,
+		verboseFlag: bootstrap.verboseFlag,
+		eagerLink:   bootstrap.eagerLink,
+		classMap:    make(map[string]*Class),
+		bootstrap:   bootstrap,
+		jLoader:     jLoader,
+	}
+	jLoader.extra = loader
+	return loader
+}
+
+// ClassLoader.findLoadedClass()
+func (self *ClassLoader) FindLoadedClass(name string) *Class {
+	return self.classMap[name]
+}
+
+// 数组类由元素类型的定义加载器定义 基本类型数组属于启动类加载器
+// 其他类交给Java代码的loadClass() 由它完成双亲委派
+// 无论谁定义了这个类 都记录当前加载器为它的初始加载器
+func (self *ClassLoader) loadClassByDelegation(name string) *Class {
+	var class *Class
+	if name[0] == '[' {
+		componentName := getComponentClassName(name)
+		if _, ok := primitiveTypes[componentName]; ok {
+			class = self.Bootstrap().LoadClass(name)
+		} else if component := self.LoadClass(componentName); component.loader != self {
+			class = component.loader.LoadClass(name)
+		} else {
+			class = self.loadArrayClass(name)
+			self.createJClass(class)
+		}
+	} else {
+		class = loadClassByJava(self, name)
+	}
+	self.classMap[name] = class
+	return class
+}
+
+// ClassLoader.defineClass1() name为nil时使用class文件里的类名
+func (self *ClassLoader) DefineClass(name string, data []byte, source string) *Class {
+	var class *Class
+	self.timeLoad(func() {
+		cf, err := classfile.Parse(data)
+		if err != nil {
+			panic(NewJavaException("java/lang/ClassFormatError", fmt.Sprint(err)))
+		}
+		class = newClass(cf)
+	})
+	if name != "" && class.name != name {
+		panic(NewJavaException("java/lang/NoClassDefFoundError",
+			fmt.Sprintf("%s (wrong name: %s)", name, class.name)))
+	}
+	if strings.HasPrefix(class.name, "java/") {
+		panic(NewJavaException("java/lang/SecurityException",
+			"Prohibited package name: "+class.GetPackageName()))
+	}
+	if _, ok := self.classMap[class.name]; ok {
+		panic(NewJavaException("java/lang/LinkageError",
+			"attempted duplicate class definition for name: \""+class.name+"\""))
+	}
+
+	self.registerClass(class)
+	self.link(class)
+	self.createJClass(class)
+	if self.verboseFlag {
+		fmt.Printf("[Loaded %s from %s]\n", class.name, source)
+		runtime.AddCleanup(class, printUnloading, class.name)
+	}
+	return class
+}
+
+// 用户类加载器不可达之后 它定义的类、类对象、静态变量和运行时常量池
+// 都只被加载器自己引用 Go的GC会把它们一起回收
+func printUnloading(name string) {
+	fmt.Printf("[Unloading class %s]\n", name)
+}
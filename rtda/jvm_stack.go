@@ -1,27 +1,32 @@
 package rtda
 
+// 栈满之后额外保留的栈帧数 用来构造并抛出StackOverflowError
+const reservedFrames = 64
+
 type Stack struct {
-	maxSize uint
+	maxSize uint  // 最多存放的栈帧数
+	maxBytes uint // 最多占用的字节数 0表示不限制
 	size uint
+	bytes uint
+	reserved bool // 是否正在使用保留区
 	_top *Frame
 }
 
-func newStack(maxSize uint) *Stack {
+func newStack(maxSize, maxBytes uint) *Stack {
 	return &Stack{
 		maxSize: maxSize,
+		maxBytes: maxBytes,
 	}
 }
 
+// 调用者先用overflow检查 athrow过渡帧这种马上就会弹出的栈帧可以直接压入
 func (self *Stack) push(frame *Frame) {
-	if self.size >= self.maxSize {
-		panic("java.lang.StackOverflowError")
-	}
-
 	if self._top != nil {
 		frame.lower =self._top;
 	}
 	self._top = frame
 	self.size++
+	self.bytes += frame.size()
 }
 
 func (self *Stack) pop() *Frame {
@@ -33,6 +38,10 @@ func (self *Stack) pop() *Frame {
 	self._top = top.lower
 	top.lower = nil
 	self.size--
+	self.bytes -= top.size()
+	if self.reserved && !self.isFull(0) {
+		self.reserved = false // 栈已经回退 重新启用保留区
+	}
 
 	return top
 }
@@ -44,6 +53,32 @@ func (self *Stack) top() *Frame {
 	return self._top
 }
 
+// 压入frame之后是否超出-Xss限制
+func (self *Stack) isFull(frameSize uint) bool {
+	if self.size >= self.maxSize {
+		return true
+	}
+	return self.maxBytes > 0 && self.bytes+frameSize > self.maxBytes
+}
+
+// 压入frame会导致栈溢出时返回true 同时打开保留区
+// 保留区也用完以后总是返回true
+func (self *Stack) overflow(frame *Frame) bool {
+	if self.reserved {
+		return self.exhausted()
+	}
+	if !self.isFull(frame.size()) {
+		return false
+	}
+	self.reserved = true
+	return true
+}
+
+// 保留区是否已经用完 这时已经不能再执行StackOverflowError的构造函数了
+func (self *Stack) exhausted() bool {
+	return self.reserved && self.size >= self.maxSize+reservedFrames
+}
+
 func (self *Stack) getFrames() []*Frame {
 	frames := make([]*Frame, 0, self.size)
 	for frame := self._top; frame != nil; frame = frame.lower {
@@ -55,9 +90,3 @@ func (self *Stack) getFrames() []*Frame {
 func (self *Stack) isEmpty() bool {
 	return self._top == nil
 }
-
-func (self *Stack) clear() {
-	for !self.isEmpty() {
-		self.pop()
-	}
-}
@@ -0,0 +1,77 @@
+package rtda
+
+import (
+	"jvm/rtda/heap"
+	"testing"
+)
+
+// 压入栈帧直到StackOverflow 返回压入的个数
+func stackDepth(frames, bytes uint) int {
+	savedFrames, savedBytes := maxStackFrames, maxStackBytes
+	defer func() { maxStackFrames, maxStackBytes = savedFrames, savedBytes }()
+	SetStackSize(frames, bytes)
+
+	thread := NewThread()
+	method := &heap.Method{}
+	depth := 0
+	for {
+		frame := thread.NewFrame(method)
+		if thread.StackOverflow(frame) {
+			return depth
+		}
+		thread.PushFrame(frame)
+		depth++
+	}
+}
+
+func TestStackSizeInFrames(t *testing.T) {
+	if depth := stackDepth(0, 0); depth != 1024 {
+		t.Fatalf("default depth = %d", depth)
+	}
+	if depth := stackDepth(4096, 0); depth != 4096 {
+		t.Fatalf("-Xss4096 depth = %d", depth)
+	}
+}
+
+// 按字节给出的-Xss既可以缩小栈 也可以让栈超过默认的1024个栈帧
+func TestStackSizeInBytes(t *testing.T) {
+	if depth := stackDepth(0, 16*frameOverhead); depth != 16 {
+		t.Fatalf("small -Xss depth = %d", depth)
+	}
+	if depth := stackDepth(0, 8<<20); depth != 8<<20/frameOverhead {
+		t.Fatalf("-Xss8m depth = %d", depth)
+	}
+}
+
+// 第一次溢出后可以再压入reservedFrames个栈帧构造StackOverflowError
+// 保留区用完以后每次都报告溢出 栈回退到限制以下后重新启用保留区
+func TestStackReserve(t *testing.T) {
+	thread := NewThread()
+	method := &heap.Method{}
+	for !thread.StackOverflow(thread.NewFrame(method)) {
+		thread.PushFrame(thread.NewFrame(method))
+	}
+	for i := 0; i < reservedFrames; i++ {
+		frame := thread.NewFrame(method)
+		if thread.StackOverflow(frame) || thread.StackExhausted() {
+			t.Fatalf("reserve exhausted after %d frames", i)
+		}
+		thread.PushFrame(frame)
+	}
+	for i := 0; i < 2; i++ {
+		if !thread.StackOverflow(thread.NewFrame(method)) || !thread.StackExhausted() {
+			t.Fatal("no overflow with the reserve exhausted")
+		}
+	}
+
+	for i := 0; i <= reservedFrames; i++ {
+		thread.PopFrame()
+	}
+	if thread.StackExhausted() || thread.StackOverflow(thread.NewFrame(method)) {
+		t.Fatal("overflow after unwinding below the limit")
+	}
+	thread.PushFrame(thread.NewFrame(method))
+	if !thread.StackOverflow(thread.NewFrame(method)) {
+		t.Fatal("reserve not re-enabled after unwinding")
+	}
+}
@@ -114,3 +114,25 @@ func (self *OperandStack) PopBoolean() bool {
 func NewOperandStack(maxStack uint) *OperandStack {
 	return newOperandStack(maxStack)
 }
+
+// 供快速解释器直接操作槽 它把栈顶位置放在局部变量里 只在调用、返回、抛异常前写回
+// 没有操作数栈的方法(max_stack为0)对应nil
+func (self *OperandStack) Slots() []Slot {
+	if self == nil {
+		return nil
+	}
+	return self.slots
+}
+
+func (self *OperandStack) Size() uint {
+	if self == nil {
+		return 0
+	}
+	return self.size
+}
+
+func (self *OperandStack) SetSize(size uint) {
+	if self != nil {
+		self.size = size
+	}
+}
@@ -9,3 +9,21 @@ func NewShimFrame(thread *Thread, ops *OperandStack) *Frame {
 		operandStack: ops,
 	}
 }
+
+// 过渡帧执行athrow指令 把操作数栈顶的异常抛出去
+func NewAthrowShimFrame(thread *Thread, ops *OperandStack) *Frame {
+	return &Frame{
+		thread: thread,
+		method: heap.ShimAthrowMethod(),
+		operandStack: ops,
+	}
+}
+
+// 过渡帧拦下被调方法抛出的异常 见base.InvokeAndWait
+func NewCatchShimFrame(thread *Thread, ops *OperandStack) *Frame {
+	return &Frame{
+		thread: thread,
+		method: heap.ShimCatchMethod(),
+		operandStack: ops,
+	}
+}
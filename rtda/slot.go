@@ -1,8 +1,53 @@
 package rtda
 
-import "jvm/rtda/heap"
+import (
+	"jvm/rtda/heap"
+	"math"
+)
 
 type Slot struct {
 	num int32
 	ref *heap.Object
 }
+
+// 供Go代码构造参数或读取返回值 long和double占两个槽
+func IntSlot(val int32) Slot {
+	return Slot{num: val}
+}
+
+func FloatSlot(val float32) Slot {
+	return Slot{num: int32(math.Float32bits(val))}
+}
+
+func RefSlot(ref *heap.Object) Slot {
+	return Slot{ref: ref}
+}
+
+func LongSlots(val int64) (low, high Slot) {
+	return Slot{num: int32(val)}, Slot{num: int32(val >> 32)}
+}
+
+func DoubleSlots(val float64) (low, high Slot) {
+	return LongSlots(int64(math.Float64bits(val)))
+}
+
+func (self Slot) Int() int32 {
+	return self.num
+}
+
+func (self Slot) Boolean() bool {
+	return self.num == 1
+}
+
+func (self Slot) Float() float32 {
+	return math.Float32frombits(uint32(self.num))
+}
+
+func (self Slot) Ref() *heap.Object {
+	return self.ref
+}
+
+// 和LongSlots相反 由低位和高位两个槽拼出long
+func SlotsLong(low, high Slot) int64 {
+	return int64(uint32(high.num))<<32 | int64(uint32(low.num))
+}
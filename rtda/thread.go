@@ -1,6 +1,9 @@
 package rtda
 
-import "jvm/rtda/heap"
+import (
+	"jvm/rtda/heap"
+	"time"
+)
 
 /*
 JVM
@@ -16,12 +19,63 @@ JVM
 type Thread struct {
 	pc int
 	stack *Stack
+	jThread *heap.Object // 对应的java.lang.Thread实例
+	caught *heap.Object // 被过渡帧拦下的异常 见base.InvokeAndWait
+	daemon bool
+	locks []lockRecord // 持有的monitor 按进入的顺序 线程转储据此打印"- locked"
+	blockedOn *heap.Object // 正在等待进入的monitor
+	waitingOn *heap.Object // 在wait()里等待的monitor
+	timedWaiting bool
+}
+
+// 进入monitor的栈帧 线程还没有栈帧时是nil
+type lockRecord struct {
+	obj *heap.Object
+	frame *Frame
+}
+
+// 虚拟机栈的大小 由-Xss设置 默认最多存放1024个栈帧
+var (
+	maxStackFrames uint = 1024
+	maxStackBytes  uint = 0
+)
+
+// -Xss既可以给出栈帧个数 也可以给出字节数
+// 给出字节数时栈帧个数不再受1024的限制 由字节数决定
+func SetStackSize(frames, bytes uint) {
+	if frames > 0 {
+		maxStackFrames = frames
+	}
+	if bytes > 0 {
+		maxStackFrames = bytes/frameOverhead + 1
+	}
+	maxStackBytes = bytes
 }
 
 func NewThread() *Thread {
-	return &Thread{
-		stack: newStack(1024), // 最多存放1024个栈帧
+	thread := &Thread{
+		stack: newStack(maxStackFrames, maxStackBytes),
 	}
+	registerThread(thread)
+	return thread
+}
+
+func (self *Thread) JThread() *heap.Object {
+	return self.jThread
+}
+
+func (self *Thread) SetJThread(jThread *heap.Object) {
+	self.jThread = jThread
+}
+
+func (self *Thread) SetCaughtException(ex *heap.Object) {
+	self.caught = ex
+}
+
+func (self *Thread) TakeCaughtException() *heap.Object {
+	ex := self.caught
+	self.caught = nil
+	return ex
 }
 
 func (self *Thread) PC() int {
@@ -36,8 +90,23 @@ func (self *Thread) PushFrame(frame *Frame) {
 	self.stack.push(frame)
 }
 
+// 压入frame会超出栈的大小时返回true
+// 此时虚拟机栈会临时启用保留区 以便构造StackOverflowError
+func (self *Thread) StackOverflow(frame *Frame) bool {
+	return self.stack.overflow(frame)
+}
+
+// 保留区也用完了 见Stack.exhausted
+func (self *Thread) StackExhausted() bool {
+	return self.stack.exhausted()
+}
+
 func (self *Thread) PopFrame() *Frame {
-	return self.stack.pop()
+	frame := self.stack.pop()
+	if frame.lockedObj != nil {
+		self.ExitMonitor(frame.lockedObj) // 退出synchronized方法
+	}
+	return frame
 }
 
 func (self *Thread) CurrentFrame() *Frame {
@@ -57,7 +126,107 @@ func (self *Thread) IsStackEmpty() bool {
 }
 
 func (self *Thread) ClearStack() {
-	self.stack.clear()
+	for !self.IsStackEmpty() {
+		self.PopFrame()
+	}
+}
+
+// 进入obj的monitor 被其他线程持有时释放全局解释器锁并阻塞
+func (self *Thread) EnterMonitor(obj *heap.Object) {
+	monitor := obj.Monitor()
+	if !monitor.TryEnter(self) {
+		self.blockedOn = obj
+		self.Blocking(func() {
+			monitor.Enter(self)
+		})
+		self.blockedOn = nil
+	}
+	var frame *Frame
+	if !self.IsStackEmpty() {
+		frame = self.CurrentFrame()
+	}
+	self.locks = append(self.locks, lockRecord{obj, frame})
+}
+
+// 退出obj的monitor 当前线程不持有时返回false
+func (self *Thread) ExitMonitor(obj *heap.Object) bool {
+	if !obj.Monitor().Exit(self) {
+		return false
+	}
+	for i := len(self.locks) - 1; i >= 0; i-- {
+		if self.locks[i].obj == obj {
+			self.locks = append(self.locks[:i], self.locks[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// 在obj上wait() 释放monitor并释放全局解释器锁 直到被notify()或者超时
+// 调用者必须持有obj的monitor
+func (self *Thread) WaitMonitor(obj *heap.Object, timeout time.Duration) {
+	self.waitingOn, self.timedWaiting = obj, timeout > 0
+	self.Blocking(func() {
+		obj.Monitor().Wait(self, timeout)
+	})
+	self.waitingOn = nil
+}
+
+// frame进入的monitor 重入多次的只返回一次
+func (self *Thread) LockedObjects(frame *Frame) []*heap.Object {
+	var objs []*heap.Object
+	for i := len(self.locks) - 1; i >= 0; i-- {
+		record := self.locks[i]
+		if record.frame != frame {
+			continue
+		}
+		duplicated := false
+		for _, obj := range objs {
+			duplicated = duplicated || obj == record.obj
+		}
+		if !duplicated {
+			objs = append(objs, record.obj)
+		}
+	}
+	return objs
+}
+
+// 堆转储用的GC根 跳过虚拟机内部的过渡帧
+// 快速解释器和编译好的代码不一定及时写回操作数栈的深度 所以扫描整个操作数栈
+func (self *Thread) HeapRoots() heap.ThreadRoots {
+	roots := heap.ThreadRoots{JThread: self.jThread}
+	for _, frame := range self.GetFrames() {
+		if frame.method.Class().Name() == "~shim" {
+			continue
+		}
+		frameRoots := heap.FrameRoots{
+			Method: frame.method,
+			PC:     frame.nextPC - 1,
+			Locked: self.LockedObjects(frame),
+		}
+		for _, slots := range [][]Slot{frame.localVars, frame.operandStack.Slots()} {
+			for _, slot := range slots {
+				if slot.ref != nil {
+					frameRoots.Refs = append(frameRoots.Refs, slot.ref)
+				}
+			}
+		}
+		roots.Frames = append(roots.Frames, frameRoots)
+	}
+	return roots
+}
+
+func (self *Thread) BlockedOn() *heap.Object {
+	return self.blockedOn
+}
+
+// 第二个返回值表示wait()有没有超时时间
+func (self *Thread) WaitingOn() (*heap.Object, bool) {
+	return self.waitingOn, self.timedWaiting
+}
+
+func (self *Thread) IsDaemon() bool {
+	return self.daemon
 }
 
 func (self *Thread) NewFrame(method *heap.Method) *Frame {
@@ -0,0 +1,58 @@
+package rtda
+
+import "sync"
+
+// 所有还没有结束的线程 按创建的顺序 供线程转储使用
+var allThreads struct {
+	sync.Mutex
+	list []*Thread
+}
+
+func registerThread(thread *Thread) {
+	allThreads.Lock()
+	defer allThreads.Unlock()
+	allThreads.list = append(allThreads.list, thread)
+}
+
+func unregisterThread(thread *Thread) {
+	allThreads.Lock()
+	defer allThreads.Unlock()
+	for i, t := range allThreads.list {
+		if t == thread {
+			allThreads.list = append(allThreads.list[:i], allThreads.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// 栈帧只有持有全局解释器锁的线程才会修改 遍历它们之前要先获取这把锁
+func AllThreads() []*Thread {
+	allThreads.Lock()
+	defer allThreads.Unlock()
+	return append([]*Thread{}, allThreads.list...)
+}
+
+// 由Thread.start()启动的非守护线程 虚拟机要等它们都结束才能退出
+var nonDaemonThreads sync.WaitGroup
+
+// 线程开始执行run()之前调用
+func (self *Thread) Started(daemon bool) {
+	self.daemon = daemon
+	if !daemon {
+		nonDaemonThreads.Add(1)
+	}
+}
+
+// run()返回或者抛出异常之后调用
+func (self *Thread) Exited() {
+	unregisterThread(self)
+	if !self.daemon {
+		nonDaemonThreads.Done()
+	}
+}
+
+// 和DestroyJavaVM一样 main方法返回后等待其余的非守护线程
+// 会阻塞 调用者必须先释放全局解释器锁
+func WaitNonDaemonThreads() {
+	nonDaemonThreads.Wait()
+}
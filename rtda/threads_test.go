@@ -0,0 +1,102 @@
+package rtda
+
+import (
+	"testing"
+	"time"
+
+	"jvm/rtda/heap"
+)
+
+func isRegistered(thread *Thread) bool {
+	for _, t := range AllThreads() {
+		if t == thread {
+			return true
+		}
+	}
+	return false
+}
+
+// 和Thread.start0()、runThread()、join()一样: 新线程在自己的goroutine里运行
+// join()在jThread上wait() 直到线程结束时NotifyAll
+// WaitNonDaemonThreads要等到非守护线程Exited()才返回
+func TestThreadStartJoin(t *testing.T) {
+	jThread := &heap.Object{}
+	alive := false // 相当于threadStatus 只在持有全局解释器锁时访问
+
+	main := NewThread()
+	main.Acquire()
+	thread := NewThread()
+	thread.Started(false)
+	alive = true
+	if !isRegistered(thread) {
+		t.Fatal("started thread not in AllThreads")
+	}
+
+	run := make(chan struct{})
+	go func() {
+		thread.Acquire()
+		defer thread.Release()
+		thread.Blocking(func() { <-run })
+		alive = false
+		thread.EnterMonitor(jThread)
+		jThread.Monitor().NotifyAll(thread)
+		thread.ExitMonitor(jThread)
+		thread.Exited()
+	}()
+
+	waited := make(chan struct{})
+	go func() {
+		WaitNonDaemonThreads()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("WaitNonDaemonThreads returned while a non-daemon thread is alive")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	joined := make(chan struct{})
+	go func() {
+		defer close(joined)
+		defer main.Release()
+		main.EnterMonitor(jThread)
+		for alive {
+			main.WaitMonitor(jThread, 0)
+		}
+		main.ExitMonitor(jThread)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(run)
+
+	for _, ch := range []chan struct{}{joined, waited} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("thread not joined")
+		}
+	}
+	if isRegistered(thread) {
+		t.Fatal("exited thread still in AllThreads")
+	}
+}
+
+// 守护线程不阻止虚拟机退出
+func TestDaemonThreadNotWaited(t *testing.T) {
+	thread := NewThread()
+	thread.Started(true)
+	defer thread.Exited()
+	if !thread.IsDaemon() {
+		t.Fatal("IsDaemon() = false")
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		WaitNonDaemonThreads()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("WaitNonDaemonThreads waits for a daemon thread")
+	}
+}
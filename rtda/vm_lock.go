@@ -0,0 +1,50 @@
+package rtda
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// 全局解释器锁
+// 同一时刻只有持有这把锁的线程可以执行字节码、访问堆和方法区
+// 线程在阻塞之前(等待monitor、sleep、I/O等)必须先释放它
+var (
+	vmLock        sync.Mutex
+	vmLockWaiters atomic.Int32
+	current       *Thread // 持有全局解释器锁的线程
+)
+
+// 线程开始执行Java代码之前获取全局解释器锁
+func (self *Thread) Acquire() {
+	vmLockWaiters.Add(1)
+	vmLock.Lock()
+	vmLockWaiters.Add(-1)
+	current = self
+}
+
+// 正在执行Java代码的线程 只能在持有全局解释器锁时调用
+// 供没有栈帧可用的Go代码(比如类加载)回调Java方法
+func CurrentThread() *Thread {
+	return current
+}
+
+func (self *Thread) Release() {
+	vmLock.Unlock()
+}
+
+// 有其他线程在等待时 让出全局解释器锁
+func (self *Thread) Yield() {
+	if vmLockWaiters.Load() > 0 {
+		self.Release()
+		runtime.Gosched()
+		self.Acquire()
+	}
+}
+
+// 释放全局解释器锁执行可能阻塞的Go代码 返回之前重新获取
+func (self *Thread) Blocking(fn func()) {
+	self.Release()
+	defer self.Acquire()
+	fn()
+}
@@ -0,0 +1,63 @@
+package rtda
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// 持有全局解释器锁的线程之间互斥 Yield让等待的线程有机会执行
+func TestVMLockExclusive(t *testing.T) {
+	const threads, rounds = 4, 1000
+	inside, counter := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			thread := NewThread()
+			thread.Acquire()
+			defer thread.Release()
+			for j := 0; j < rounds; j++ {
+				inside++
+				if inside != 1 {
+					t.Error("two threads hold the VM lock")
+				}
+				counter++
+				inside--
+				thread.Yield()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != threads*rounds {
+		t.Fatalf("counter = %d", counter)
+	}
+}
+
+// Blocking期间其他线程可以获得全局解释器锁
+func TestVMLockBlocking(t *testing.T) {
+	blocked, other := NewThread(), NewThread()
+	blocked.Acquire()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		blocked.Blocking(func() { <-release })
+		blocked.Release()
+		close(done)
+	}()
+
+	acquired := make(chan struct{})
+	go func() {
+		other.Acquire()
+		close(acquired)
+		other.Release()
+	}()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("VM lock not released while blocking")
+	}
+	close(release)
+	<-done
+}
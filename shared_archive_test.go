@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"jvm/classpath"
+	"jvm/rtda/heap"
+	"path/filepath"
+	"testing"
+)
+
+// HelloWorld启动时启动类加载器大约加载450个类
+const sharingTestClasses = 450
+
+// 每个类有一个实例字段、一个静态字段和methodsPerClass个互相调用的静态方法
+// 常量池、字段和方法的数量和JDK里的小类差不多
+func newSharingTestClasses(t testing.TB) []*testClass {
+	const methodsPerClass = 20
+	classes := make([]*testClass, sharingTestClasses)
+	for i := range classes {
+		name := fmt.Sprintf("p/C%d", i)
+		superName := "java/lang/Object"
+		if i%10 != 0 {
+			superName = fmt.Sprintf("p/C%d", i-1)
+		}
+		cp := newTestConstantPool()
+		class := &testClass{flags: 0x21, name: name, superName: superName, cp: cp,
+			fields: []testField{{0x2, "x", "I"}, {0xa, "s", "J"}}}
+		for j := 0; j < methodsPerClass; j++ {
+			code := newBytecode().op(op_iload_0, op_iconst_1, op_iadd)
+			if j+1 < methodsPerClass {
+				code.u2(op_invokestatic, cp.methodRef(name, fmt.Sprintf("m%d", j+1), "(I)I"))
+			}
+			code.u2(op_getstatic, cp.fieldRef(name, "s", "J")).op(op_l2i, op_iadd, op_ireturn)
+			class.methods = append(class.methods, testMethod{0x9, fmt.Sprintf("m%d", j), "(I)I", 3, 1, code.bytes(t)})
+		}
+		classes[i] = class
+	}
+	return classes
+}
+
+func loadSharingTestClasses(cp *classpath.Classpath, share *heap.SharedArchive) []*heap.Class {
+	loader := heap.NewClassLoader(cp, heap.NewHeap(0), false, false, share)
+	classes := make([]*heap.Class, sharingTestClasses)
+	for i := range classes {
+		classes[i] = loader.LoadClass(fmt.Sprintf("p/C%d", i))
+	}
+	return classes
+}
+
+// -Xshare:dump 写出归档 返回归档的路径
+func dumpSharingTestArchive(t testing.TB, cp *classpath.Classpath) string {
+	path := filepath.Join(t.TempDir(), "classes.jsa")
+	share := heap.NewSharedArchive(path)
+	loader := heap.NewClassLoader(cp, heap.NewHeap(0), false, false, share)
+	for i := 0; i < sharingTestClasses; i++ {
+		loader.LoadClass(fmt.Sprintf("p/C%d", i))
+	}
+	if _, err := loader.DumpSharedArchive(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openSharingTestArchive(t testing.TB, path string, cp *classpath.Classpath) *heap.SharedArchive {
+	share, err := heap.OpenSharedArchive(path, cp)
+	if err != nil || share == nil {
+		t.Fatalf("OpenSharedArchive: %v, %v", share, err)
+	}
+	return share
+}
+
+// 从归档恢复的类和从jar包解析的一样
+func TestSharedArchiveMatchesJar(t *testing.T) {
+	jre := writeTestJre(t, newSharingTestClasses(t)...)
+	cp := classpath.Parse(jre, jre)
+	path := dumpSharingTestArchive(t, cp)
+
+	want := loadSharingTestClasses(cp, nil)
+	got := loadSharingTestClasses(cp, openSharingTestArchive(t, path, cp))
+	for i := range want {
+		if got[i].Name() != want[i].Name() || got[i].SuperClass().Name() != want[i].SuperClass().Name() ||
+			len(got[i].Fields()) != len(want[i].Fields()) || len(got[i].Methods()) != len(want[i].Methods()) {
+			t.Fatalf("%s: archived class differs", want[i].Name())
+		}
+		for j, method := range want[i].Methods() {
+			if !bytes.Equal(got[i].Methods()[j].Code(), method.Code()) {
+				t.Errorf("%s.%s: archived code differs", want[i].Name(), method.Name())
+			}
+		}
+	}
+}
+
+// 只统计加载和链接类的时间 不包括执行<clinit>
+func BenchmarkLoadClasses(b *testing.B) {
+	jre := writeTestJre(b, newSharingTestClasses(b)...)
+	cp := classpath.Parse(jre, jre)
+	path := dumpSharingTestArchive(b, cp)
+
+	b.Run("Xshare:off", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			loadSharingTestClasses(cp, nil)
+		}
+	})
+	b.Run("Xshare:auto", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			loadSharingTestClasses(cp, openSharingTestArchive(b, path, cp))
+		}
+	})
+}
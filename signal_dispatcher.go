@@ -0,0 +1,38 @@
+package main
+
+import (
+	"jvm/instructions/base"
+	"jvm/native/sun/misc"
+	"jvm/rtda"
+	"os"
+)
+
+// 虚拟机内部的Signal Dispatcher线程
+// 收到注册了Java处理器的信号后调用Signal.dispatch() 它再启动新线程执行SignalHandler
+// Terminator给INT、TERM、HUP注册的处理器会调用Shutdown.exit() 执行关闭钩子之后退出
+// QUIT由虚拟机自己处理 把所有线程的栈打印到标准输出 程序继续运行
+// 有-XX:+PrintClassHistogram时接着打印类直方图
+func (self *JVM) startSignalDispatcher() {
+	thread := rtda.NewThread()
+	thread.SetJThread(self.newJThread("Signal Dispatcher", self.systemGroup, 9, true)) // Thread.MAX_PRIORITY - 1
+	signalClass := self.classLoader.LoadClass("sun/misc/Signal")
+	dispatch := signalClass.GetStaticMethod("dispatch", "(I)V")
+	misc.NotifyQuit()
+	go func() {
+		for {
+			number := misc.WaitSignal()
+			if misc.IsQuit(number) {
+				os.Stdout.Write(threadDump(thread))
+				if self.cmd.printClassHistogramFlag {
+					thread.Acquire()
+					os.Stdout.Write(classHistogram(self.classLoader))
+					thread.Release()
+				}
+				continue
+			}
+			thread.Acquire()
+			base.InvokeAndWait(thread, dispatch, []rtda.Slot{rtda.IntSlot(number)})
+			thread.Release()
+		}
+	}()
+}
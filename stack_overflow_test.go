@@ -0,0 +1,118 @@
+package main
+
+import (
+	"jvm/classpath"
+	"jvm/instructions/base"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"testing"
+)
+
+// 栈溢出时抛出可以捕获的StackOverflowError 不管栈帧是字节码压入的 还是Go代码压入的过渡帧
+
+// Throwable有构造不了异常时要直接设置的cause和stackTrace字段
+// R.recurse()无限递归 R.id()什么也不做
+func newStackOverflowTestClasses(t testing.TB) []*testClass {
+	var classes []*testClass
+	superName := "java/lang/Object"
+	for _, name := range []string{"java/lang/Throwable", "java/lang/Error",
+		"java/lang/VirtualMachineError", "java/lang/StackOverflowError"} {
+		cp := newTestConstantPool()
+		init := newBytecode().op(op_aload_0).u2(op_invokespecial, cp.methodRef(superName, "<init>", "()V")).op(op_return)
+		class := &testClass{flags: 0x21, name: name, superName: superName, cp: cp,
+			methods: []testMethod{{0x1, "<init>", "()V", 1, 1, init.bytes(t)}}}
+		if name == "java/lang/Throwable" {
+			class.fields = []testField{{0x2, "cause", "Ljava/lang/Throwable;"},
+				{0x2, "stackTrace", "[Ljava/lang/StackTraceElement;"}}
+		}
+		classes = append(classes, class)
+		superName = name
+	}
+
+	cp := newTestConstantPool()
+	recurse := newBytecode().u2(op_invokestatic, cp.methodRef("R", "recurse", "()V")).op(op_return)
+	return append(classes,
+		&testClass{flags: 0x31, name: "java/lang/StackTraceElement", superName: "java/lang/Object"},
+		&testClass{flags: 0x21, name: "R", superName: "java/lang/Object", cp: cp,
+			methods: []testMethod{
+				{0x9, "recurse", "()V", 0, 0, recurse.bytes(t)},
+				{0x9, "id", "()V", 0, 0, []byte{op_return}},
+			},
+		})
+}
+
+func newStackOverflowTest(t *testing.T) (*rtda.Thread, *heap.Class) {
+	base.SetInterpreter(func(thread *rtda.Thread, barrier *rtda.Frame) {
+		run(thread, false, barrier)
+	})
+	jre := writeTestJre(t, newStackOverflowTestClasses(t)...)
+	loader := heap.NewClassLoader(classpath.Parse(jre, jre), heap.NewHeap(0), false, false, nil)
+	thread := rtda.NewThread()
+	thread.Acquire()
+	t.Cleanup(thread.Release)
+	return thread, loader.LoadClass("R")
+}
+
+func expectStackOverflowError(t *testing.T, what string, thrown *heap.Object) {
+	t.Helper()
+	if thrown == nil || thrown.Class().Name() != "java/lang/StackOverflowError" {
+		t.Fatalf("%s threw %v, want StackOverflowError", what, thrown)
+	}
+}
+
+// 栈回退以后保留区重新启用 可以再次溢出
+func TestStackOverflowRecursion(t *testing.T) {
+	thread, class := newStackOverflowTest(t)
+	recurse := class.GetStaticMethod("recurse", "()V")
+	for i := 0; i < 2; i++ {
+		_, thrown := base.InvokeAndWait(thread, recurse, nil)
+		expectStackOverflowError(t, "recurse()", thrown)
+		if !thread.IsStackEmpty() {
+			t.Fatalf("%d frames left after the StackOverflowError", len(thread.GetFrames()))
+		}
+	}
+}
+
+// InvokeAndWait的过渡帧本身就压不进去 异常由构造函数在保留区里构造
+func TestStackOverflowInvokeAndWait(t *testing.T) {
+	thread, class := newStackOverflowTest(t)
+	id := class.GetStaticMethod("id", "()V")
+	for {
+		frame := thread.NewFrame(id)
+		if thread.StackOverflow(frame) {
+			break
+		}
+		thread.PushFrame(frame)
+	}
+	thread.PushFrame(thread.PopFrame()) // 弹出一帧让保留区重新启用 栈仍然是满的
+	depth := len(thread.GetFrames())
+
+	_, thrown := base.InvokeAndWait(thread, id, nil)
+	expectStackOverflowError(t, "InvokeAndWait on a full stack", thrown)
+	if thrown.GetRefVar("cause", "Ljava/lang/Throwable;") != nil {
+		t.Error("StackOverflowError was not built by its constructor")
+	}
+	if n := len(thread.GetFrames()); n != depth {
+		t.Fatalf("%d frames after InvokeAndWait, want %d", n, depth)
+	}
+}
+
+// 保留区也用完了 不再执行构造函数 虚拟机不能崩溃
+func TestStackOverflowReserveExhausted(t *testing.T) {
+	thread, class := newStackOverflowTest(t)
+	id := class.GetStaticMethod("id", "()V")
+	for !thread.StackExhausted() {
+		frame := thread.NewFrame(id)
+		thread.StackOverflow(frame) // 第一次溢出时启用保留区
+		thread.PushFrame(frame)
+	}
+
+	_, thrown := base.InvokeAndWait(thread, id, nil)
+	expectStackOverflowError(t, "InvokeAndWait with the reserve exhausted", thrown)
+	if thrown.GetRefVar("cause", "Ljava/lang/Throwable;") != thrown {
+		t.Error("cause of the preallocated StackOverflowError is not itself")
+	}
+	if trace := thrown.GetRefVar("stackTrace", "[Ljava/lang/StackTraceElement;"); trace == nil || trace.ArrayLength() != 0 {
+		t.Errorf("stackTrace = %v, want an empty array", trace)
+	}
+}
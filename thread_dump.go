@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"jvm/rtda"
+	"jvm/rtda/heap"
+	"time"
+	"unicode/utf16"
+)
+
+// 和HotSpot的线程转储格式一样 逐个线程打印栈帧以及持有、等待的monitor
+// 只读取栈帧 不像logFrames那样弹出它们 程序可以继续运行
+// self是执行转储的虚拟机内部线程 遍历栈帧期间持有全局解释器锁 其他线程都停在安全的位置
+func threadDump(self *rtda.Thread) []byte {
+	self.Acquire()
+	defer self.Release()
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s\nFull thread dump jvmgo:\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	for _, thread := range rtda.AllThreads() {
+		dumpThread(buf, thread, thread == self)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func dumpThread(buf *bytes.Buffer, thread *rtda.Thread, dumping bool) {
+	var frames []*rtda.Frame
+	for _, frame := range thread.GetFrames() {
+		if frame.Method().Class().Name() != "~shim" {
+			frames = append(frames, frame) // 虚拟机内部的过渡帧不打印
+		}
+	}
+
+	blockedOn := thread.BlockedOn()
+	waitingOn, timed := thread.WaitingOn()
+	status, state := "runnable", "RUNNABLE"
+	switch {
+	case blockedOn != nil:
+		status, state = "waiting for monitor entry", "BLOCKED (on object monitor)"
+	case waitingOn != nil && timed:
+		status, state = "in Object.wait()", "TIMED_WAITING (on object monitor)"
+	case waitingOn != nil:
+		status, state = "in Object.wait()", "WAITING (on object monitor)"
+	case len(frames) == 0 && !dumping:
+		status = "waiting on condition" // 虚拟机内部的线程在Go代码里等待
+	}
+
+	buf.WriteString(threadHeader(thread))
+	fmt.Fprintf(buf, " %s\n   java.lang.Thread.State: %s\n", status, state)
+	for i, frame := range frames {
+		method := frame.Method()
+		fmt.Fprintf(buf, "\tat %s.%s(%s)\n", method.Class().JavaName(), method.Name(), sourceLocation(frame))
+		if i == 0 && blockedOn != nil {
+			fmt.Fprintf(buf, "\t- waiting to lock %s\n", describeMonitor(blockedOn))
+		}
+		if i == 0 && waitingOn != nil {
+			fmt.Fprintf(buf, "\t- waiting on %s\n", describeMonitor(waitingOn))
+		}
+		for _, obj := range thread.LockedObjects(frame) {
+			fmt.Fprintf(buf, "\t- locked %s\n", describeMonitor(obj))
+		}
+	}
+}
+
+// "main" #1 prio=5
+func threadHeader(thread *rtda.Thread) string {
+	jThread := thread.JThread()
+	if jThread == nil {
+		return `"<unnamed>"`
+	}
+	name := "<unnamed>"
+	if chars := jThread.GetRefVar("name", "[C"); chars != nil {
+		name = string(utf16.Decode(chars.Chars()))
+	}
+	header := fmt.Sprintf("%q", name)
+	if tid := jThread.GetLongVar("tid", "J"); tid != 0 {
+		header += fmt.Sprintf(" #%d", tid)
+	}
+	if jThread.GetIntVar("daemon", "Z") != 0 {
+		header += " daemon"
+	}
+	return header + fmt.Sprintf(" prio=%d", jThread.GetIntVar("priority", "I"))
+}
+
+// 和StackTraceElement.toString()一样
+func sourceLocation(frame *rtda.Frame) string {
+	method := frame.Method()
+	fileName := method.Class().SourceFile()
+	lineNumber := method.GetLineNumber(frame.NextPC() - 1)
+	switch {
+	case lineNumber == -2:
+		return "Native Method"
+	case fileName != "" && lineNumber >= 0:
+		return fmt.Sprintf("%s:%d", fileName, lineNumber)
+	case fileName != "":
+		return fileName
+	}
+	return "Unknown Source"
+}
+
+// <0xc000123456> (a java.lang.Object)
+func describeMonitor(obj *heap.Object) string {
+	return fmt.Sprintf("<%p> (a %s)", obj, obj.Class().JavaName())
+}